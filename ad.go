@@ -0,0 +1,116 @@
+package ldap
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	attrObjectGUID = "objectguid"
+	attrObjectSID  = "objectsid"
+)
+
+var decoders = map[string]func([]byte) (string, error){
+	attrObjectGUID: DecodeGUID,
+	attrObjectSID:  DecodeSID,
+}
+
+func DecodeGUID(b []byte) (string, error) {
+	if len(b) != 16 {
+		return "", fmt.Errorf("guid: invalid length %d (expected 16)", len(b))
+	}
+	var str strings.Builder
+	str.WriteString(fmt.Sprintf("%08x", binary.LittleEndian.Uint32(b[0:])))
+	str.WriteRune(minus)
+	str.WriteString(fmt.Sprintf("%04x", binary.LittleEndian.Uint16(b[4:])))
+	str.WriteRune(minus)
+	str.WriteString(fmt.Sprintf("%04x", binary.LittleEndian.Uint16(b[6:])))
+	str.WriteRune(minus)
+	str.WriteString(hex.EncodeToString(b[8:10]))
+	str.WriteRune(minus)
+	str.WriteString(hex.EncodeToString(b[10:]))
+	return str.String(), nil
+}
+
+func EncodeGUID(guid string) ([]byte, error) {
+	guid = strings.Trim(guid, "{}")
+	parts := strings.Split(guid, string(minus))
+	if len(parts) != 5 || len(parts[0]) != 8 || len(parts[1]) != 4 || len(parts[2]) != 4 || len(parts[3]) != 4 || len(parts[4]) != 12 {
+		return nil, fmt.Errorf("%s: invalid guid", guid)
+	}
+	raw, err := hex.DecodeString(strings.Join(parts, ""))
+	if err != nil {
+		return nil, fmt.Errorf("%s: invalid guid (%w)", guid, err)
+	}
+	b := make([]byte, 16)
+	binary.LittleEndian.PutUint32(b[0:], binary.BigEndian.Uint32(raw[0:]))
+	binary.LittleEndian.PutUint16(b[4:], binary.BigEndian.Uint16(raw[4:]))
+	binary.LittleEndian.PutUint16(b[6:], binary.BigEndian.Uint16(raw[6:]))
+	copy(b[8:], raw[8:])
+	return b, nil
+}
+
+func DecodeSID(b []byte) (string, error) {
+	if len(b) < 8 {
+		return "", fmt.Errorf("sid: invalid length %d (expected at least 8)", len(b))
+	}
+	var (
+		rev   = b[0]
+		count = int(b[1])
+		auth  uint64
+	)
+	if len(b) != 8+count*4 {
+		return "", fmt.Errorf("sid: invalid length %d (expected %d)", len(b), 8+count*4)
+	}
+	for _, x := range b[2:8] {
+		auth = auth<<8 | uint64(x)
+	}
+	var str strings.Builder
+	str.WriteString("S-")
+	str.WriteString(strconv.FormatUint(uint64(rev), 10))
+	str.WriteRune(minus)
+	str.WriteString(strconv.FormatUint(auth, 10))
+	for i := 0; i < count; i++ {
+		sub := binary.LittleEndian.Uint32(b[8+i*4:])
+		str.WriteRune(minus)
+		str.WriteString(strconv.FormatUint(uint64(sub), 10))
+	}
+	return str.String(), nil
+}
+
+func EncodeSID(sid string) ([]byte, error) {
+	parts := strings.Split(sid, string(minus))
+	if len(parts) < 3 || strings.ToUpper(parts[0]) != "S" {
+		return nil, fmt.Errorf("%s: invalid sid", sid)
+	}
+	rev, err := strconv.ParseUint(parts[1], 10, 8)
+	if err != nil {
+		return nil, fmt.Errorf("%s: invalid sid revision (%w)", sid, err)
+	}
+	auth, err := strconv.ParseUint(parts[2], 10, 48)
+	if err != nil {
+		return nil, fmt.Errorf("%s: invalid sid authority (%w)", sid, err)
+	}
+	subs := parts[3:]
+	if len(subs) > 0xFF {
+		return nil, fmt.Errorf("%s: too many sub authorities", sid)
+	}
+	b := make([]byte, 8+len(subs)*4)
+	b[0] = byte(rev)
+	b[1] = byte(len(subs))
+	for i := 7; i >= 2; i-- {
+		b[i] = byte(auth)
+		auth >>= 8
+	}
+	for i, s := range subs {
+		sub, err := strconv.ParseUint(s, 10, 32)
+		if err != nil {
+			return nil, fmt.Errorf("%s: invalid sub authority (%w)", sid, err)
+		}
+		binary.LittleEndian.PutUint32(b[8+i*4:], uint32(sub))
+	}
+	return b, nil
+}
@@ -0,0 +1,22 @@
+package ldap
+
+type ClientAPI interface {
+	Bind(user, passwd string, controls ...Control) ([]ControlValue, error)
+	Unbind(controls ...Control) error
+	GetEntry(dn string, attrs ...string) (Entry, error)
+	Search(base string, options ...SearchOption) ([]Entry, []ControlValue, error)
+	Stream(base string, fn func(Entry) error, options ...SearchOption) ([]ControlValue, error)
+	Compare(dn string, ava AttributeAssertion, controls ...Control) (bool, []ControlValue, error)
+	Add(dn string, attrs []Attribute, controls ...Control) ([]ControlValue, error)
+	Modify(dn string, attrs []PartialAttribute, controls ...Control) ([]ControlValue, error)
+	Delete(dn string, controls ...Control) ([]ControlValue, error)
+	ModDN(dn, rdn, parent string, keep bool, controls ...Control) ([]ControlValue, error)
+	Rename(dn, rdn string, keep bool, controls ...Control) ([]ControlValue, error)
+	Move(dn, parent string, controls ...Control) ([]ControlValue, error)
+	Extended(oid string, value []byte, controls ...Control) (ExtendedResult, error)
+}
+
+var (
+	_ ClientAPI = (*Client)(nil)
+	_ ClientAPI = (*SnapshotClient)(nil)
+)
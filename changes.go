@@ -2,6 +2,7 @@ package ldap
 
 import (
 	"bufio"
+	"bytes"
 	"encoding/base64"
 	"errors"
 	"fmt"
@@ -13,6 +14,7 @@ import (
 	"path/filepath"
 	"sort"
 	"strings"
+	"time"
 )
 
 type ChangeType uint8
@@ -22,6 +24,7 @@ const (
 	ModDelete
 	ModReplace
 	ModIncrement
+	ModRDN
 )
 
 func (c ChangeType) IsValid() bool {
@@ -46,18 +49,104 @@ func createPartialWithValue(name, value string) PartialAttribute {
 type Change struct {
 	Name  string
 	Attrs []PartialAttribute
+
+	NewRDN      string
+	DeleteOld   bool
+	NewSuperior string
+}
+
+type ChangeRecord struct {
+	Type ChangeType
+	Line int
+	Change
+}
+
+type Reader struct {
+	rs      *ldifReader
+	version bool
+}
+
+type ReaderOption func(*Reader)
+
+func WithURLPolicy(policy URLPolicy) ReaderOption {
+	return func(r *Reader) {
+		r.rs.policy = policy
+	}
+}
+
+func DisableURLs() ReaderOption {
+	return func(r *Reader) {
+		r.rs.policy.Disable = true
+	}
+}
+
+func withoutFetch() ReaderOption {
+	return func(r *Reader) {
+		r.rs.nofetch = true
+	}
+}
+
+func NewReader(r io.Reader, options ...ReaderOption) *Reader {
+	lc := lineCounter{Reader: r}
+	rs := Reader{
+		rs: &ldifReader{
+			Reader: bufio.NewReader(&lc),
+			lines:  &lc,
+		},
+	}
+	for _, o := range options {
+		o(&rs)
+	}
+	return &rs
+}
+
+func (r *Reader) Read() (ChangeRecord, error) {
+	var rec ChangeRecord
+	if !r.version {
+		r.version = true
+		if err := readVersion(r.rs.Reader); err != nil {
+			return rec, err
+		}
+	}
+	for {
+		b, err := r.rs.ReadByte()
+		if err != nil {
+			return rec, err
+		}
+		switch b {
+		case sharp:
+			skipComments(r.rs.Reader)
+		case carriage, newline:
+			skipBlanks(b, r.rs.Reader)
+		default:
+			r.rs.UnreadByte()
+			rec.Line = r.rs.line()
+			rec.Type, err = parseChange(r.rs, &rec.Change)
+			if errors.Is(err, io.EOF) {
+				err = io.ErrUnexpectedEOF
+			}
+			if err != nil && !errors.Is(err, eob) {
+				return rec, err
+			}
+			return rec, nil
+		}
+	}
 }
 
-func ReadLDIF(r io.Reader, exec func(ChangeType, Change) error) error {
-	rs := bufio.NewReader(r)
-	return readBlock(rs, func() error {
-		var c Change
-		ct, err := parseChange(rs, &c)
-		if err != nil && !errors.Is(err, eob) {
+func ReadLDIF(r io.Reader, exec func(ChangeType, Change) error, options ...ReaderOption) error {
+	rs := NewReader(r, options...)
+	for {
+		rec, err := rs.Read()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				err = nil
+			}
 			return err
 		}
-		return exec(ct, c)
-	})
+		if err := exec(rec.Type, rec.Change); err != nil {
+			return err
+		}
+	}
 }
 
 const (
@@ -68,9 +157,15 @@ const (
 	ldifMod    = "modify"
 	ldifRep    = "replace"
 	ldifInc    = "increment"
+
+	ldifModRDN      = "modrdn"
+	ldifModDN       = "moddn"
+	ldifNewRDN      = "newrdn"
+	ldifDeleteOld   = "deleteoldrdn"
+	ldifNewSuperior = "newsuperior"
 )
 
-func parseChange(rs *bufio.Reader, cg *Change) (ChangeType, error) {
+func parseChange(rs *ldifReader, cg *Change) (ChangeType, error) {
 	name, value, err := readAttribute(rs)
 	if err != nil {
 		return 0, err
@@ -83,17 +178,19 @@ func parseChange(rs *bufio.Reader, cg *Change) (ChangeType, error) {
 		return 0, err
 	}
 	var (
-		parse  func(*bufio.Reader, *Change) error
+		parse  func(*ldifReader, *Change) error
 		action ChangeType
 	)
 	if name == ldifChange {
-		switch value {
+		switch value = strings.TrimSpace(value); value {
 		case ldifAdd:
 			parse, action = parseAdd, ModAdd
 		case ldifDel:
 			parse, action = parseDelete, ModDelete
 		case ldifMod:
 			parse, action = parseModify, ModReplace
+		case ldifModRDN, ldifModDN:
+			parse, action = parseModDN, ModRDN
 		default:
 			return 0, fmt.Errorf("%s: unsupported value %s", name, value)
 		}
@@ -104,8 +201,8 @@ func parseChange(rs *bufio.Reader, cg *Change) (ChangeType, error) {
 	return action, parse(rs, cg)
 }
 
-func parseModify(rs *bufio.Reader, cg *Change) error {
-	return readBlock(rs, func() error {
+func parseModify(rs *ldifReader, cg *Change) error {
+	return readBlock(rs.Reader, func() error {
 		name, value, err := readAttribute(rs)
 		if err != nil {
 			return err
@@ -123,7 +220,7 @@ func parseModify(rs *bufio.Reader, cg *Change) error {
 		default:
 			return fmt.Errorf("%s: unknown operation", name)
 		}
-		err = readBlock(rs, func() error {
+		err = readBlock(rs.Reader, func() error {
 			name, value, err := readAttribute(rs)
 			if err != nil {
 				return err
@@ -141,8 +238,39 @@ func parseModify(rs *bufio.Reader, cg *Change) error {
 	})
 }
 
-func parseAdd(rs *bufio.Reader, cg *Change) error {
-	return readBlock(rs, func() error {
+func parseModDN(rs *ldifReader, cg *Change) error {
+	err := readBlock(rs.Reader, func() error {
+		name, value, err := readAttribute(rs)
+		if err != nil {
+			return err
+		}
+		switch strings.ToLower(name) {
+		case ldifNewRDN:
+			cg.NewRDN = value
+		case ldifDeleteOld:
+			switch value {
+			case "0":
+				cg.DeleteOld = false
+			case "1":
+				cg.DeleteOld = true
+			default:
+				return fmt.Errorf("%s: invalid value %s", name, value)
+			}
+		case ldifNewSuperior:
+			cg.NewSuperior = value
+		default:
+			return fmt.Errorf("%s: unexpected attribute in %s record", name, ldifModRDN)
+		}
+		return nil
+	})
+	if (err == nil || errors.Is(err, eob)) && cg.NewRDN == "" {
+		return fmt.Errorf("%s: %s not provided", cg.Name, ldifNewRDN)
+	}
+	return err
+}
+
+func parseAdd(rs *ldifReader, cg *Change) error {
+	return readBlock(rs.Reader, func() error {
 		name, value, err := readAttribute(rs)
 		if err != nil {
 			return err
@@ -183,9 +311,11 @@ func readBlock(rs *bufio.Reader, exec func() error) error {
 			skipBlanks(b, rs)
 			return eob
 		case minus:
-			b, _ := rs.ReadByte()
-			if b == carriage || b == newline {
-				rs.ReadString(newline)
+			b, err := rs.ReadByte()
+			if err == nil && b == carriage {
+				b, err = rs.ReadByte()
+			}
+			if errors.Is(err, io.EOF) || (err == nil && b == newline) {
 				return eob
 			}
 			return fmt.Errorf("dash")
@@ -199,7 +329,7 @@ func readBlock(rs *bufio.Reader, exec func() error) error {
 	return nil
 }
 
-func parseDelete(rs *bufio.Reader, cg *Change) error {
+func parseDelete(rs *ldifReader, cg *Change) error {
 	b, err := rs.ReadByte()
 	if err != nil {
 		if errors.Is(err, io.EOF) {
@@ -208,12 +338,12 @@ func parseDelete(rs *bufio.Reader, cg *Change) error {
 		return err
 	}
 	if b == carriage || b == newline {
-		return skipBlanks(b, rs)
+		return skipBlanks(b, rs.Reader)
 	}
 	return fmt.Errorf("delete block should be empty")
 }
 
-func readAttribute(rs *bufio.Reader) (string, string, error) {
+func readAttribute(rs *ldifReader) (string, string, error) {
 	b, err := rs.ReadByte()
 	if err != nil {
 		return "", "", err
@@ -235,15 +365,18 @@ func readAttribute(rs *bufio.Reader) (string, string, error) {
 	return name, value, nil
 }
 
-func readDescriptor(rs *bufio.Reader) (string, error) {
+func readDescriptor(rs *ldifReader) (string, error) {
 	name, err := rs.ReadString(colon)
+	if x := strings.IndexByte(name, newline); x >= 0 {
+		return "", fmt.Errorf("%s: colon not found", trimEOL(name[:x]))
+	}
 	if err != nil {
 		err = fmt.Errorf("%w: colon not found", err)
 	}
 	return strings.TrimSuffix(name, string(colon)), err
 }
 
-func readValue(rs *bufio.Reader) (string, error) {
+func readValue(rs *ldifReader) (string, error) {
 	b, err := rs.ReadByte()
 	if err != nil {
 		return "", err
@@ -251,16 +384,20 @@ func readValue(rs *bufio.Reader) (string, error) {
 	var value string
 	switch b {
 	case colon:
-		lines, err := readLines(rs)
+		lines, err := readLines(rs.Reader)
 		if err != nil {
 			return "", err
 		}
-		value = strings.Join(lines, "")
+		buf, err := base64.StdEncoding.DecodeString(strings.Join(strings.Fields(strings.Join(lines, "")), ""))
+		if err != nil {
+			return "", fmt.Errorf("invalid base64 value (%w)", err)
+		}
+		value = string(buf)
 	case langle:
 		value, err = readFromURL(rs)
 	default:
 		rs.UnreadByte()
-		lines, err := readLines(rs)
+		lines, err := readLines(rs.Reader)
 		if err != nil {
 			return "", err
 		}
@@ -273,56 +410,178 @@ const (
 	schemeFile  = "file"
 	schemeHTTP  = "http"
 	schemeHTTPS = "https"
+
+	defaultFetchTimeout = 30 * time.Second
+	defaultFetchSize    = 16 << 20
+	maxFetchRedirects   = 10
 )
 
-func readFromURL(rs *bufio.Reader) (string, error) {
-	str, err := rs.ReadString(newline)
+var ErrURLNotAllowed = errors.New("url value not allowed")
+
+type URLPolicy struct {
+	Client  *http.Client
+	MaxSize int64
+	Schemes []string
+	Hosts   []string
+	Disable bool
+}
+
+func (p URLPolicy) allow(u *url.URL) error {
+	if p.Disable {
+		return fmt.Errorf("%s: %w (disabled)", u, ErrURLNotAllowed)
+	}
+	scheme := strings.ToLower(u.Scheme)
+	switch {
+	case len(p.Schemes) > 0 && !containsName(p.Schemes, scheme):
+		return fmt.Errorf("%s: %w (scheme %s)", u, ErrURLNotAllowed, scheme)
+	case scheme == schemeFile:
+		if !containsName(p.Schemes, schemeFile) {
+			return fmt.Errorf("%s: %w (scheme %s not enabled)", u, ErrURLNotAllowed, scheme)
+		}
+		return nil
+	case len(p.Hosts) > 0:
+		if !containsName(p.Hosts, u.Hostname()) {
+			return fmt.Errorf("%s: %w (host %s)", u, ErrURLNotAllowed, u.Hostname())
+		}
+		return nil
+	case len(p.Schemes) == 0:
+		return fmt.Errorf("%s: %w (scheme %s not enabled)", u, ErrURLNotAllowed, scheme)
+	default:
+		return nil
+	}
+}
+
+func (p URLPolicy) limit() int64 {
+	if p.MaxSize <= 0 {
+		return defaultFetchSize
+	}
+	return p.MaxSize
+}
+
+func (p URLPolicy) client() *http.Client {
+	client := http.Client{
+		Timeout: defaultFetchTimeout,
+	}
+	if p.Client != nil {
+		client = *p.Client
+	}
+	check := client.CheckRedirect
+	client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if err := p.allow(req.URL); err != nil {
+			return err
+		}
+		if check != nil {
+			return check(req, via)
+		}
+		if len(via) >= maxFetchRedirects {
+			return fmt.Errorf("%s: too many redirects", req.URL)
+		}
+		return nil
+	}
+	return &client
+}
+
+type ldifReader struct {
+	*bufio.Reader
+	policy  URLPolicy
+	lines   *lineCounter
+	nofetch bool
+}
+
+func (rs *ldifReader) line() int {
+	if rs.lines == nil {
+		return 0
+	}
+	buf, _ := rs.Peek(rs.Buffered())
+	return rs.lines.count - bytes.Count(buf, []byte{newline}) + 1
+}
+
+type lineCounter struct {
+	io.Reader
+	count int
+}
+
+func (c *lineCounter) Read(b []byte) (int, error) {
+	n, err := c.Reader.Read(b)
+	c.count += bytes.Count(b[:n], []byte{newline})
+	return n, err
+}
+
+func readFromURL(rs *ldifReader) (string, error) {
+	lines, err := readLines(rs.Reader)
 	if err != nil {
 		return "", err
 	}
-	u, err := url.Parse(strings.TrimSpace(str))
+	u, err := url.Parse(strings.TrimSpace(strings.Join(lines, "")))
 	if err != nil {
 		return "", err
 	}
+	if rs.nofetch {
+		switch strings.ToLower(u.Scheme) {
+		case schemeFile, schemeHTTP, schemeHTTPS:
+			return u.String(), nil
+		default:
+			return "", fmt.Errorf("%s: unsupported scheme", u.Scheme)
+		}
+	}
+	if err := rs.policy.allow(u); err != nil {
+		return "", err
+	}
 	var value string
 	switch strings.ToLower(u.Scheme) {
 	case schemeHTTP, schemeHTTPS:
-		value, err = readFromHTTP(u.String())
+		value, err = readFromHTTP(u.String(), rs.policy)
 	case schemeFile:
-		value, err = readFromFile(u.Path)
+		value, err = readFromFile(u.Path, rs.policy.limit())
 	default:
 		err = fmt.Errorf("%s: unsupported scheme", u.Scheme)
 	}
 	return value, err
 }
 
-func readFromFile(file string) (string, error) {
+func readFromFile(file string, limit int64) (string, error) {
 	var files []string
-	if cwd, err := os.Getwd(); err == nil {
+	if cwd, err := os.Getwd(); err == nil && !filepath.IsAbs(file) {
 		files = append(files, filepath.Join(cwd, file))
 	}
 	files = append(files, file)
 	for _, file := range files {
-		buf, err := ioutil.ReadFile(file)
-		if err == nil {
-			return base64.StdEncoding.EncodeToString(buf), nil
+		r, err := os.Open(file)
+		if err != nil {
+			continue
 		}
+		defer r.Close()
+		return readLimit(file, r, limit)
 	}
 	return "", fmt.Errorf("%s: not found", file)
 }
 
-func readFromHTTP(file string) (string, error) {
-	resp, err := http.Get(file)
+func readFromHTTP(file string, policy URLPolicy) (string, error) {
+	resp, err := policy.client().Get(file)
 	if err != nil {
 		return "", err
 	}
 	defer resp.Body.Close()
 
-	buf, err := ioutil.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("%s: %s", file, resp.Status)
+	}
+	limit := policy.limit()
+	if resp.ContentLength > limit {
+		return "", fmt.Errorf("%s: value exceeds %d bytes", file, limit)
+	}
+	return readLimit(file, resp.Body, limit)
+}
+
+func readLimit(file string, r io.Reader, limit int64) (string, error) {
+	buf, err := ioutil.ReadAll(io.LimitReader(r, limit+1))
 	if err != nil {
 		return "", err
 	}
-	return base64.StdEncoding.EncodeToString(buf), nil
+	if int64(len(buf)) > limit {
+		return "", fmt.Errorf("%s: value exceeds %d bytes", file, limit)
+	}
+	return string(buf), nil
 }
 
 func skipBlanks(b byte, rs *bufio.Reader) error {
@@ -366,7 +625,7 @@ func readLines(rs *bufio.Reader) ([]string, error) {
 		str, _ = rs.ReadString(newline)
 		lines  []string
 	)
-	lines = append(lines, strings.TrimSpace(str))
+	lines = append(lines, trimEOL(strings.TrimLeft(str, string(space))))
 	for {
 		b, err := rs.ReadByte()
 		if err != nil {
@@ -380,7 +639,106 @@ func readLines(rs *bufio.Reader) ([]string, error) {
 			break
 		}
 		str, _ := rs.ReadString(newline)
-		lines = append(lines, strings.TrimSuffix(str, "\r\n"))
+		lines = append(lines, trimEOL(str))
 	}
 	return lines, nil
 }
+
+func trimEOL(str string) string {
+	str = strings.TrimSuffix(str, string(newline))
+	return strings.TrimSuffix(str, string(carriage))
+}
+
+const ldifWidth = 76
+
+func WriteLDIF(w io.Writer, entries ...Entry) error {
+	for _, e := range entries {
+		var str strings.Builder
+		str.WriteRune(sharp)
+		str.WriteRune(space)
+		str.WriteString(e.Name)
+		str.WriteRune(newline)
+		appendEntry(&str, e)
+		if _, err := io.WriteString(w, str.String()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (e Entry) MarshalLDIF() ([]byte, error) {
+	var str strings.Builder
+	appendEntry(&str, e)
+	return []byte(str.String()), nil
+}
+
+func appendEntry(str *strings.Builder, e Entry) {
+	writeLine(str, ldifDN, e.Name)
+	for _, a := range e.Attrs {
+		if a.Truncated {
+			str.WriteRune(sharp)
+			str.WriteRune(space)
+			str.WriteString(a.Name)
+			str.WriteString(": values truncated")
+			str.WriteRune(newline)
+		}
+		appendAttribute(str, a)
+	}
+	str.WriteRune(newline)
+}
+
+func writeLine(str *strings.Builder, name, value string) {
+	writeValue(str, name, value, !isSafeString(value))
+}
+
+func writeValue(str *strings.Builder, name, value string, encode bool) {
+	var line strings.Builder
+	line.WriteString(name)
+	line.WriteRune(colon)
+	if encode {
+		line.WriteRune(colon)
+		line.WriteRune(space)
+		line.WriteString(base64.StdEncoding.EncodeToString([]byte(value)))
+	} else if value != "" {
+		line.WriteRune(space)
+		line.WriteString(value)
+	}
+	foldLine(str, line.String())
+}
+
+func foldLine(str *strings.Builder, line string) {
+	foldLineAt(str, line, ldifWidth)
+}
+
+func foldLineAt(str *strings.Builder, line string, limit int) {
+	width := limit
+	for limit > 1 && len(line) > width {
+		str.WriteString(line[:width])
+		str.WriteRune(newline)
+		str.WriteRune(space)
+		line = line[width:]
+		width = limit - 1
+	}
+	str.WriteString(line)
+	str.WriteRune(newline)
+}
+
+func isSafeString(str string) bool {
+	if str == "" {
+		return true
+	}
+	switch str[0] {
+	case space, colon, langle:
+		return false
+	}
+	if str[len(str)-1] == space {
+		return false
+	}
+	for i := 0; i < len(str); i++ {
+		switch b := str[i]; {
+		case b == null, b == newline, b == carriage, b > 0x7F:
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,80 @@
+package ldap
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestURLPolicy(t *testing.T) {
+	data := []struct {
+		Policy URLPolicy
+		URL    string
+		Want   bool
+	}{
+		{Policy: URLPolicy{}, URL: "file:///etc/shadow", Want: false},
+		{Policy: URLPolicy{Hosts: []string{"example.org"}}, URL: "file:///etc/shadow", Want: false},
+		{Policy: URLPolicy{Schemes: []string{"https"}}, URL: "file:///etc/shadow", Want: false},
+		{Policy: URLPolicy{Schemes: []string{"file"}}, URL: "file:///tmp/photo.jpg", Want: true},
+		{Policy: URLPolicy{}, URL: "https://example.org/photo.jpg", Want: false},
+		{Policy: URLPolicy{}, URL: "http://169.254.169.254/latest/meta-data", Want: false},
+		{Policy: URLPolicy{Schemes: []string{"file"}}, URL: "https://example.org/photo.jpg", Want: false},
+		{Policy: URLPolicy{Schemes: []string{"https"}}, URL: "https://example.org/photo.jpg", Want: true},
+		{Policy: URLPolicy{Hosts: []string{"example.org"}}, URL: "https://example.org/photo.jpg", Want: true},
+		{Policy: URLPolicy{Hosts: []string{"example.org"}}, URL: "https://example.com/photo.jpg", Want: false},
+		{Policy: URLPolicy{Schemes: []string{"https"}, Hosts: []string{"example.org"}}, URL: "https://example.com/photo.jpg", Want: false},
+		{Policy: URLPolicy{Schemes: []string{"https"}}, URL: "http://example.org/photo.jpg", Want: false},
+		{Policy: URLPolicy{Disable: true}, URL: "https://example.org/photo.jpg", Want: false},
+	}
+	for _, d := range data {
+		u, err := url.Parse(d.URL)
+		if err != nil {
+			t.Fatal(err)
+		}
+		err = d.Policy.allow(u)
+		if got := err == nil; got != d.Want {
+			t.Errorf("%s: want allowed %t, got %t (%v)", d.URL, d.Want, got, err)
+		}
+		if err != nil && !errors.Is(err, ErrURLNotAllowed) {
+			t.Errorf("%s: unexpected error %v", d.URL, err)
+		}
+	}
+}
+
+func TestReadFromFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "ldif")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "photo.jpg")
+	if err := ioutil.WriteFile(file, []byte("jpeg"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	shadow := filepath.Join(dir, file)
+	if err := os.MkdirAll(filepath.Dir(shadow), 0o700); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(shadow, []byte("shadow"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	cwd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(cwd)
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	got, err := readFromFile(file, defaultFetchSize)
+	if err != nil {
+		t.Fatalf("%s: unexpected error: %s", file, err)
+	}
+	if got != "jpeg" {
+		t.Errorf("%s: content mismatch: want jpeg, got %s", file, got)
+	}
+}
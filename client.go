@@ -5,7 +5,9 @@ import (
 	"errors"
 	"fmt"
 	"net"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/midbel/ber"
 )
@@ -17,6 +19,7 @@ const (
 	oidCancel       = "1.3.6.1.1.8"
 	oidBeginTx      = "1.3.6.1.1.21.1"
 	oidEndTx        = "1.3.6.1.1.21.3"
+	oidRefresh      = "1.3.6.1.4.1.1466.101.119.1"
 )
 
 var ExtensionNames = map[string]string{
@@ -26,6 +29,7 @@ var ExtensionNames = map[string]string{
 	oidCancel:       "Cancel extension",
 	oidBeginTx:      "Begin Transaction extension",
 	oidEndTx:        "End Transaction",
+	oidRefresh:      "Dynamic entry refresh extension",
 }
 
 const (
@@ -33,52 +37,84 @@ const (
 	noticeAbortedTx  = "1.3.6.1.1.21.4"
 )
 
-var ErrUnsolicited = errors.New("unsolicited notification")
+var (
+	ErrUnsolicited  = errors.New("unsolicited notification")
+	ErrTrailingData = errors.New("unexpected data after response")
+	ErrUnauthBind   = errors.New("unauthenticated bind refused (name without password)")
+)
+
+const attrEntryUUID = "entryUUID"
 
 const (
-	ldapBindRequest      uint64 = 0
-	ldapBindResponse            = 1
-	ldapUnbindRequest           = 2
-	ldapSearchRequest           = 3
-	ldapSearchResEntry          = 4
-	ldapSearchResDone           = 5
-	ldapSearchResRef            = 19
-	ldapModifyRequest           = 6
-	ldapModifyResponse          = 7
-	ldapAddRequest              = 8
-	ldapAddResponse             = 9
-	ldapDelRequest              = 10
-	ldapDelResponse             = 11
-	ldapModDNRequest            = 12
-	ldapModDNResponse           = 13
-	ldapCmpRequest              = 14
-	ldapCmpResponse             = 15
-	ldapAbandonRequest          = 16
-	ldapExtendedRequest         = 23
-	ldapExtendedResponse        = 24
+	ldapBindRequest          uint64 = 0
+	ldapBindResponse                = 1
+	ldapUnbindRequest               = 2
+	ldapSearchRequest               = 3
+	ldapSearchResEntry              = 4
+	ldapSearchResDone               = 5
+	ldapSearchResRef                = 19
+	ldapModifyRequest               = 6
+	ldapModifyResponse              = 7
+	ldapAddRequest                  = 8
+	ldapAddResponse                 = 9
+	ldapDelRequest                  = 10
+	ldapDelResponse                 = 11
+	ldapModDNRequest                = 12
+	ldapModDNResponse               = 13
+	ldapCmpRequest                  = 14
+	ldapCmpResponse                 = 15
+	ldapAbandonRequest              = 16
+	ldapExtendedRequest             = 23
+	ldapExtendedResponse            = 24
+	ldapIntermediateResponse        = 25
 )
 
 type Client struct {
 	conn net.Conn
+	addr string
 
 	mu     sync.Mutex
 	msgid  uint32
 	binded bool
 
 	tx []byte
+
+	schema    *Schema
+	schemaTTL time.Duration
+	lookup    bool
+	bases     sync.Map
+	hooks     hooks
+
+	policy    TLSPolicy
+	tlsConfig *tls.Config
+	strict    bool
+	op        string
+	cipher    *attrCipher
+	wb        *writeBuffer
 }
 
 func Open(addr string) (*Client, error) {
 	c, err := net.Dial("tcp", addr)
 	if err != nil {
-		return nil, err
+		return nil, &NetError{Op: "dial", Addr: addr, Err: err}
 	}
 	client := Client{
 		conn: c,
+		addr: addr,
 	}
 	return &client, nil
 }
 
+func NewClient(conn net.Conn) *Client {
+	client := Client{
+		conn: conn,
+	}
+	if addr := conn.RemoteAddr(); addr != nil {
+		client.addr = addr.String()
+	}
+	return &client
+}
+
 func BindTLS(addr, user, passwd string, cfg *tls.Config) (*Client, error) {
 	c, err := Open(addr)
 	if err != nil {
@@ -104,6 +140,24 @@ func Bind(addr, user, passwd string) (*Client, error) {
 	return c, err
 }
 
+// SetSchema makes Add, Rename, Move and ModDN validate their request
+// against s before sending it. Only the request itself is checked unless
+// LookupClasses is enabled.
+func (c *Client) SetSchema(s *Schema) {
+	c.schema = s
+}
+
+// LookupClasses makes schema validation fetch the object classes of the
+// entry and of its superior with one extra search each, so that DIT
+// structure rules can be enforced on writes.
+func (c *Client) LookupClasses(lookup bool) {
+	c.lookup = lookup
+}
+
+func (c *Client) RefuseUnauthenticated(refuse bool) {
+	c.strict = refuse
+}
+
 func (c *Client) Begin() error {
 	if len(c.tx) > 0 {
 		return fmt.Errorf("transaction already running")
@@ -172,37 +226,77 @@ func (c *Client) Bind(user, passwd string, controls ...Control) ([]ControlValue,
 	if c.binded {
 		return nil, nil
 	}
-	msg := struct {
-		Version int
-		Name    string `ber:"octetstr"`
-		Pass    string `ber:"class:0x2,type:0x0,tag:0x0"`
-	}{
-		Version: RFC4511,
-		Name:    user,
-		Pass:    passwd,
+	if c.strict && user != "" && passwd == "" {
+		return nil, fmt.Errorf("%s: %w", user, ErrUnauthBind)
 	}
-	values, err := c.execute(msg, ldapBindRequest, controls)
+	if err := c.secure(); err != nil {
+		return nil, err
+	}
+	msg := bindRequest{
+		Version:  RFC4511,
+		Name:     user,
+		Password: passwd,
+	}
+	info, err := c.before(OperationInfo{Type: OpBind, DN: user, Controls: controls})
+	if err != nil {
+		return nil, err
+	}
+	values, err := c.executeRequest(msg, info.Controls)
+	c.after(info, err)
 	if err == nil {
 		c.binded = true
 	}
 	return values, err
 }
 
-func (c *Client) Unbind() error {
+func (c *Client) Unbind(controls ...Control) error {
 	defer c.conn.Close()
 	if !c.binded {
 		return nil
 	}
-	_, err := c.execute(struct{}{}, ldapUnbindRequest, nil)
+	info, err := c.before(OperationInfo{Type: OpUnbind, Controls: controls})
+	if err != nil {
+		return err
+	}
+	_, err = c.executeRequest(unbindRequest{}, info.Controls)
+	c.after(info, err)
+	return err
+}
+
+func (c *Client) Abandon(msgid int, controls ...Control) error {
+	info, err := c.before(OperationInfo{Type: OpAbandon, Controls: controls})
+	if err != nil {
+		return err
+	}
+	_, err = c.executeRequest(abandonRequest{ID: msgid}, info.Controls)
+	c.after(info, err)
 	return err
 }
 
 func (c *Client) Search(base string, options ...SearchOption) ([]Entry, []ControlValue, error) {
-	c.mu.Lock()
-	defer c.mu.Unlock()
+	var es []Entry
+	values, err := c.Stream(base, func(e Entry) error {
+		es = append(es, e)
+		return nil
+	}, options...)
+	if err != nil {
+		return nil, nil, err
+	}
+	return es, values, nil
+}
 
-	c.msgid++
+func (c *Client) GetEntry(dn string, attrs ...string) (Entry, error) {
+	es, _, err := c.Search(dn, WithAttributes(attrs))
+	if err != nil {
+		return Entry{}, err
+	}
+	if len(es) == 0 {
+		return Entry{}, Result{Code: NoSuchObject, Name: dn}
+	}
+	return es[0], nil
+}
 
+func (c *Client) Stream(base string, fn func(Entry) error, options ...SearchOption) ([]ControlValue, error) {
 	search := searchRequest{
 		Base:   base,
 		Scope:  ScopeBase,
@@ -211,9 +305,129 @@ func (c *Client) Search(base string, options ...SearchOption) ([]Entry, []Contro
 	}
 	for _, opt := range options {
 		if err := opt(&search); err != nil {
-			return nil, nil, err
+			return nil, err
+		}
+	}
+	if err := search.deadline(); err != nil {
+		return nil, err
+	}
+	info := OperationInfo{
+		Type:     OpSearch,
+		DN:       search.Base,
+		Filter:   search.Filter,
+		Controls: search.controls,
+	}
+	for _, a := range search.Attrs {
+		info.Attrs = append(info.Attrs, string(a))
+	}
+	info, err := c.before(info)
+	if err != nil {
+		return nil, err
+	}
+	search.controls = info.Controls
+	if c.cipher != nil {
+		next := fn
+		fn = func(e Entry) error {
+			e, err := c.cipher.decryptEntry(e)
+			if err != nil {
+				return err
+			}
+			return next(e)
+		}
+	}
+	if limit := search.maxbytes; limit > 0 {
+		next := fn
+		fn = func(e Entry) error {
+			for i := range e.Attrs {
+				e.Attrs[i].truncate(limit)
+			}
+			return next(e)
+		}
+	}
+	values, err := c.stream(search, fn)
+	c.after(info, err)
+	return values, err
+}
+
+func (c *Client) stream(search searchRequest, fn func(Entry) error) ([]ControlValue, error) {
+	if !search.fallback {
+		return c.search(search, fn)
+	}
+	values, err := c.search(search, func(e Entry) error {
+		if strings.EqualFold(e.Name, search.Base) {
+			if uuid := e.GetValues(attrEntryUUID); len(uuid) > 0 {
+				c.bases.Store(strings.ToLower(search.Base), uuid[0])
+			}
 		}
+		return fn(e)
+	})
+	if err == nil {
+		return values, err
+	}
+	next, ok := c.fallbackBase(search.Base, err)
+	if !ok {
+		return nil, err
 	}
+	search.Base = next
+	return c.search(search, fn)
+}
+
+func (c *Client) RememberBase(base string) error {
+	es, _, err := c.Search(base, WithAttributes([]string{attrEntryUUID}))
+	if err != nil {
+		return err
+	}
+	if len(es) == 0 {
+		return fmt.Errorf("%s: entry not found", base)
+	}
+	uuid := es[0].GetValues(attrEntryUUID)
+	if len(uuid) == 0 {
+		return fmt.Errorf("%s: %s not available", base, attrEntryUUID)
+	}
+	c.bases.Store(strings.ToLower(base), uuid[0])
+	return nil
+}
+
+func (c *Client) fallbackBase(base string, err error) (string, bool) {
+	var res Result
+	if !errors.As(err, &res) || res.Code != NoSuchObject {
+		return "", false
+	}
+	if uuid, ok := c.bases.Load(strings.ToLower(base)); ok {
+		var (
+			root    = res.Name
+			options = []SearchOption{
+				WithScope(ScopeWhole),
+				WithFilter(Equal(attrEntryUUID, uuid.(string))),
+				WithAttributes([]string{"1.1"}),
+				WithLimit(1),
+			}
+		)
+		es, _, err := c.Search(root, options...)
+		if err == nil && len(es) > 0 {
+			c.bases.Delete(strings.ToLower(base))
+			c.bases.Store(strings.ToLower(es[0].Name), uuid)
+			return es[0].Name, true
+		}
+	}
+	if res.Name == "" || strings.EqualFold(res.Name, base) {
+		return "", false
+	}
+	return res.Name, true
+}
+
+func (c *Client) search(search searchRequest, fn func(Entry) error) ([]ControlValue, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if search.ctx != nil {
+		if when, ok := search.ctx.Deadline(); ok {
+			c.conn.SetDeadline(when)
+			defer c.conn.SetDeadline(time.Time{})
+		}
+	}
+
+	c.msgid++
 
 	var e ber.Encoder
 	e.EncodeInt(int64(c.msgid))
@@ -223,9 +437,9 @@ func (c *Client) Search(base string, options ...SearchOption) ([]Entry, []Contro
 	}
 	body, err := e.AsSequence()
 	if err != nil {
-		return nil, nil, err
+		return nil, err
 	}
-	return c.executeSearch(body)
+	return c.executeSearch(body, fn)
 }
 
 func (c *Client) Whoami(controls ...Control) (string, []ControlValue, error) {
@@ -238,17 +452,61 @@ func (c *Client) Whoami(controls ...Control) (string, []ControlValue, error) {
 }
 
 func (c *Client) Modify(dn string, attrs []PartialAttribute, controls ...Control) ([]ControlValue, error) {
-	msg := struct {
-		Name  string `ber:"octetstr"`
-		Attrs []PartialAttribute
-	}{
-		Name:  dn,
-		Attrs: attrs,
+	if c.wb == nil || len(controls) > 0 {
+		return c.modify(dn, attrs, controls)
+	}
+	attrs, err := c.cipher.encryptChanges(attrs)
+	if err != nil {
+		return nil, err
+	}
+	info, err := c.runHooks(OperationInfo{Type: OpModify, DN: dn})
+	if err != nil {
+		return nil, err
 	}
-	return c.execute(msg, ldapModifyRequest, controls)
+	if len(info.Controls) > 0 {
+		if err := c.Flush(); err != nil {
+			return nil, err
+		}
+		return c.sendModify(dn, attrs, info)
+	}
+	return nil, c.push(info, attrs)
+}
+
+func (c *Client) modify(dn string, attrs []PartialAttribute, controls []Control) ([]ControlValue, error) {
+	attrs, err := c.cipher.encryptChanges(attrs)
+	if err != nil {
+		return nil, err
+	}
+	info, err := c.before(OperationInfo{Type: OpModify, DN: dn, Controls: controls})
+	if err != nil {
+		return nil, err
+	}
+	return c.sendModify(dn, attrs, info)
+}
+
+func (c *Client) sendModify(dn string, attrs []PartialAttribute, info OperationInfo) ([]ControlValue, error) {
+	msg := modifyRequest{
+		Name:    dn,
+		Changes: attrs,
+	}
+	values, err := c.executeRequest(msg, info.Controls)
+	if err != nil {
+		err = attributeError(err)
+	}
+	c.after(info, err)
+	return values, err
 }
 
 func (c *Client) Add(dn string, attrs []Attribute, controls ...Control) ([]ControlValue, error) {
+	if c.schema != nil {
+		if err := c.schema.ValidateAdd(dn, attrs, c.parentClasses(dn)); err != nil {
+			return nil, err
+		}
+	}
+	attrs, err := c.cipher.encryptAttrs(attrs)
+	if err != nil {
+		return nil, err
+	}
 	msg := struct {
 		Name  string `ber:"octetstr"`
 		Attrs []Attribute
@@ -256,50 +514,146 @@ func (c *Client) Add(dn string, attrs []Attribute, controls ...Control) ([]Contr
 		Name:  dn,
 		Attrs: attrs,
 	}
-	return c.execute(msg, ldapAddRequest, controls)
+	info, err := c.before(OperationInfo{Type: OpAdd, DN: dn, Controls: controls})
+	if err != nil {
+		return nil, err
+	}
+	values, err := c.execute(msg, ldapAddRequest, info.Controls)
+	c.after(info, err)
+	return values, err
 }
 
 func (c *Client) Delete(dn string, controls ...Control) ([]ControlValue, error) {
-	return c.execute([]byte(dn), ldapDelRequest, controls)
+	info, err := c.before(OperationInfo{Type: OpDelete, DN: dn, Controls: controls})
+	if err != nil {
+		return nil, err
+	}
+	values, err := c.execute([]byte(dn), ldapDelRequest, info.Controls)
+	c.after(info, err)
+	return values, err
 }
 
 func (c *Client) ModifyPassword(dn, curr, next string, controls ...Control) ([]ControlValue, error) {
-	msg := struct {
-		Name string `ber:"class:0x2,tag:0x0,omitempty"`
-		Old  string `ber:"class:0x2,tag:0x1,omitempty"`
-		New  string `ber:"class:0x2,tag:0x2,omitempty"`
-	}{
-		Name: dn,
-		Old:  curr,
-		New:  next,
+	var body []byte
+	for i, str := range []string{dn, curr, next} {
+		if str != "" {
+			body = append(body, encodeString(berClassContext|byte(i), str)...)
+		}
+	}
+	info, err := c.before(OperationInfo{Type: OpExtended, DN: dn, OID: oidChangePasswd, Controls: controls})
+	if err != nil {
+		return nil, err
+	}
+	req := createExtendedRequest(oidChangePasswd, encodeElement(berConstructed|berSequence, body))
+	_, values, err := c.executeExtendedRequest(req, info.Controls)
+	c.after(info, err)
+	return values, err
+}
+
+func (c *Client) Refresh(dn string, ttl time.Duration, controls ...Control) (time.Duration, error) {
+	var body []byte
+	body = append(body, encodeString(berClassContext|0, dn)...)
+	body = append(body, encodeInteger(berClassContext|1, int64(ttl.Seconds()))...)
+	req := createExtendedRequest(oidRefresh, encodeElement(berConstructed|berSequence, body))
+	res, _, err := c.executeExtended(req, controls)
+	if err != nil {
+		return 0, err
+	}
+	if len(res.Value) == 0 {
+		return 0, Result{
+			Code:       ProtocolError,
+			Diagnostic: fmt.Sprintf("%s: refresh response without responseTtl", dn),
+		}
+	}
+	var resp struct {
+		TTL int `ber:"class:0x2,tag:0x1"`
+	}
+	if err := ber.NewDecoder(res.Value).Decode(&resp); err != nil {
+		return 0, err
+	}
+	return time.Duration(resp.TTL) * time.Second, nil
+}
+
+func (c *Client) Extended(oid string, value []byte, controls ...Control) (ExtendedResult, error) {
+	var is []IntermediateResponse
+	res, err := c.ExtendedFunc(oid, value, func(ir IntermediateResponse) error {
+		is = append(is, ir)
+		return nil
+	}, controls...)
+	res.Intermediates = is
+	return res, err
+}
+
+func (c *Client) ExtendedFunc(oid string, value []byte, fn func(IntermediateResponse) error, controls ...Control) (ExtendedResult, error) {
+	info, err := c.before(OperationInfo{Type: OpExtended, OID: oid, Controls: controls})
+	if err != nil {
+		return ExtendedResult{}, err
 	}
-	req := createExtendedRequest(oidChangePasswd, msg)
-	return c.execute(req, ldapExtendedRequest, controls)
+	res, err := c.extended(oid, value, fn, info.Controls)
+	c.after(info, err)
+	return res, err
+}
+
+func (c *Client) extended(oid string, value []byte, fn func(IntermediateResponse) error, controls []Control) (ExtendedResult, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.msgid++
+
+	body := encodeMessage(c.msgid, createExtendedRequest(oid, value), controls)
+	return c.executeIntermediate(body, fn)
 }
 
 func (c *Client) StartTLS(cfg *tls.Config) error {
 	if _, ok := c.conn.(*tls.Conn); ok {
 		return nil
 	}
-	req := createExtendedRequest(oidStartTLS, nil)
-	_, _, err := c.executeExtended(req, nil)
-	if err == nil {
-		c.conn = tls.Client(c.conn, cfg)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.msgid++
+
+	body := encodeMessage(c.msgid, createExtendedRequest(oidStartTLS, nil), nil)
+	if _, _, err := c.extendedResult(body, true); err != nil {
+		return err
 	}
-	return err
+	conn := tls.Client(c.conn, c.verifyConfig(cfg))
+	if err := conn.Handshake(); err != nil {
+		return err
+	}
+	c.conn = conn
+	return nil
+}
+
+func (c *Client) verifyConfig(cfg *tls.Config) *tls.Config {
+	if cfg == nil {
+		cfg = &tls.Config{}
+	} else {
+		cfg = cfg.Clone()
+	}
+	if cfg.ServerName == "" && !cfg.InsecureSkipVerify {
+		host, _, err := net.SplitHostPort(c.addr)
+		if err != nil {
+			host = c.addr
+		}
+		cfg.ServerName = host
+	}
+	return cfg
 }
 
 func (c *Client) Rename(dn, rdn string, keep bool, controls ...Control) ([]ControlValue, error) {
-	msg := struct {
-		Name  string `ber:"octetstr"`
-		Value string `ber:"octetstr"`
-		Keep  bool
-	}{
-		Name:  dn,
-		Value: rdn,
-		Keep:  keep,
+	if c.schema != nil {
+		err := c.schema.ValidateModDN(dn, rdn, "", c.objectClasses(dn), c.parentClasses(dn))
+		if err != nil {
+			return nil, err
+		}
+	}
+	msg := modDNRequest{
+		Name:      dn,
+		RDN:       rdn,
+		DeleteOld: !keep,
 	}
-	return c.execute(msg, ldapModDNRequest, controls)
+	return c.modDN(msg, controls)
 }
 
 func (c *Client) Move(dn, parent string, controls ...Control) ([]ControlValue, error) {
@@ -307,21 +661,72 @@ func (c *Client) Move(dn, parent string, controls ...Control) ([]ControlValue, e
 	if err != nil {
 		return nil, err
 	}
-	msg := struct {
-		Name   string `ber:"octetstr"`
-		Value  string `ber:"octetstr"`
-		Keep   bool
-		Parent string `ber:"octetstr"`
-	}{
-		Name:   dn,
-		Value:  name.RDN().String(),
-		Keep:   false,
-		Parent: parent,
+	if c.schema != nil {
+		err := c.schema.ValidateModDN(dn, name.RDN().String(), parent, c.objectClasses(dn), c.objectClasses(parent))
+		if err != nil {
+			return nil, err
+		}
+	}
+	msg := modDNRequest{
+		Name:     dn,
+		RDN:      name.RDN().String(),
+		Superior: parent,
+	}
+	return c.modDN(msg, controls)
+}
+
+func (c *Client) ModDN(dn, rdn, parent string, keep bool, controls ...Control) ([]ControlValue, error) {
+	if c.schema != nil {
+		superior := c.parentClasses(dn)
+		if parent != "" {
+			superior = c.objectClasses(parent)
+		}
+		err := c.schema.ValidateModDN(dn, rdn, parent, c.objectClasses(dn), superior)
+		if err != nil {
+			return nil, err
+		}
 	}
-	return c.execute(msg, ldapModDNRequest, controls)
+	msg := modDNRequest{
+		Name:      dn,
+		RDN:       rdn,
+		DeleteOld: !keep,
+		Superior:  parent,
+	}
+	return c.modDN(msg, controls)
+}
+
+func (c *Client) modDN(msg modDNRequest, controls []Control) ([]ControlValue, error) {
+	info, err := c.before(OperationInfo{
+		Type:        OpModDN,
+		DN:          msg.Name,
+		NewRDN:      msg.RDN,
+		NewSuperior: msg.Superior,
+		Controls:    controls,
+	})
+	if err != nil {
+		return nil, err
+	}
+	values, err := c.executeRequest(msg, info.Controls)
+	c.after(info, err)
+	return values, err
 }
 
 func (c *Client) Compare(dn string, ava AttributeAssertion, controls ...Control) (bool, []ControlValue, error) {
+	vs, err := c.cipher.encrypt(ava.Desc, []string{ava.Attr})
+	if err != nil {
+		return false, nil, err
+	}
+	ava.Attr = vs[0]
+	info, err := c.before(OperationInfo{Type: OpCompare, DN: dn, Attrs: []string{ava.Desc}, Controls: controls})
+	if err != nil {
+		return false, nil, err
+	}
+	ok, values, err := c.compare(dn, ava, info.Controls)
+	c.after(info, err)
+	return ok, values, err
+}
+
+func (c *Client) compare(dn string, ava AttributeAssertion, controls []Control) (bool, []ControlValue, error) {
 	c.mu.Lock()
 	defer c.mu.Unlock()
 
@@ -349,32 +754,47 @@ func (c *Client) Compare(dn string, ava AttributeAssertion, controls ...Control)
 	return res.Code == CompareTrue, values, err
 }
 
-// func (c *Client) Abandon(msgid int, controls ...Control) error {
-// 	return nil
-// }
-//
 // func (c *Client) Cancel(msgid int, controls ...Control) error {
 // 	return nil
 // }
 
-func (c *Client) executeExtended(msg interface{}, controls []Control) (extendedResponse, []ControlValue, error) {
-	c.mu.Lock()
-	defer c.mu.Unlock()
-
-	c.msgid++
+func (c *Client) parentClasses(dn string) []string {
+	name, err := Explode(dn)
+	if err != nil || name.Len() <= 1 {
+		return nil
+	}
+	return c.objectClasses(name.Parent(1).String())
+}
 
-	var e ber.Encoder
-	e.EncodeInt(int64(c.msgid))
-	e.EncodeWithIdent(msg, ber.NewConstructed(ldapExtendedRequest).Application())
-	if len(controls) > 0 {
-		e.EncodeWithIdent(controls, ber.NewConstructed(0).Context())
+func (c *Client) objectClasses(dn string) []string {
+	if !c.lookup {
+		return nil
 	}
-	body, err := e.AsSequence()
+	es, _, err := c.Search(dn, WithAttributes([]string{attrObjectClass}))
+	if err != nil || len(es) == 0 {
+		return nil
+	}
+	return es[0].GetValues(attrObjectClass)
+}
+
+func (c *Client) executeExtended(req extendedRequest, controls []Control) (extendedResponse, []ControlValue, error) {
+	info, err := c.before(OperationInfo{Type: OpExtended, OID: req.OID, Controls: controls})
 	if err != nil {
 		return extendedResponse{}, nil, err
 	}
+	res, values, err := c.executeExtendedRequest(req, info.Controls)
+	c.after(info, err)
+	return res, values, err
+}
 
-	return c.extendedResult(body)
+func (c *Client) executeExtendedRequest(req extendedRequest, controls []Control) (extendedResponse, []ControlValue, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.msgid++
+
+	body := encodeMessage(c.msgid, req, controls)
+	return c.extendedResult(body, false)
 }
 
 func (c *Client) withTransaction(app uint64) (Control, bool) {
@@ -397,7 +817,7 @@ func (c *Client) execute(msg interface{}, app uint64, controls []Control) ([]Con
 
 	var id ber.Ident
 	switch app {
-	case ldapUnbindRequest, ldapDelRequest:
+	case ldapDelRequest:
 		id = ber.NewPrimitive(app)
 	default:
 		id = ber.NewConstructed(app)
@@ -418,32 +838,49 @@ func (c *Client) execute(msg interface{}, app uint64, controls []Control) ([]Con
 		return nil, err
 	}
 
+	_, values, err := c.result(body, responseOf(app))
+	return values, err
+}
+
+func (c *Client) executeRequest(req request, controls []Control) ([]ControlValue, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.msgid++
+
+	if ctrl, ok := c.withTransaction(req.operation()); ok {
+		controls = append(controls, ctrl)
+	}
+	body := encodeMessage(c.msgid, req, controls)
+	_, values, err := c.result(body, responseOf(req.operation()))
+	return values, err
+}
+
+func responseOf(app uint64) uint64 {
 	switch app {
 	default:
-		app = 0
+		return 0
 	case ldapBindRequest:
-		app = ldapBindResponse
+		return ldapBindResponse
 	case ldapAddRequest:
-		app = ldapAddResponse
+		return ldapAddResponse
 	case ldapModifyRequest:
-		app = ldapModifyResponse
+		return ldapModifyResponse
 	case ldapDelRequest:
-		app = ldapDelResponse
+		return ldapDelResponse
 	case ldapModDNRequest:
-		app = ldapModDNResponse
+		return ldapModDNResponse
 	}
-	_, values, err := c.result(body, app)
-	return values, err
 }
 
-func (c *Client) extendedResult(body []byte) (extendedResponse, []ControlValue, error) {
+func (c *Client) extendedResult(body []byte, strict bool) (extendedResponse, []ControlValue, error) {
 	var res extendedResponse
-	if _, err := c.conn.Write(body); err != nil {
+	if err := c.send(body); err != nil {
 		return res, nil, err
 	}
 
 	body = make([]byte, 1<<15)
-	n, err := c.conn.Read(body)
+	n, err := c.recv(body)
 	if err != nil {
 		return res, nil, err
 	}
@@ -458,6 +895,9 @@ func (c *Client) extendedResult(body []byte) (extendedResponse, []ControlValue,
 	if err := msg.Decode(&res); err != nil {
 		return res, nil, err
 	}
+	if strict && dec.Can() {
+		return res, nil, ErrTrailingData
+	}
 	if res.succeed() {
 		return res, msg.Controls, nil
 	}
@@ -465,14 +905,14 @@ func (c *Client) extendedResult(body []byte) (extendedResponse, []ControlValue,
 }
 
 func (c *Client) result(body []byte, app uint64) (Result, []ControlValue, error) {
-	if _, err := c.conn.Write(body); err != nil {
+	if err := c.send(body); err != nil {
 		return Result{}, nil, err
 	}
 	if app == 0 {
 		return Result{}, nil, nil
 	}
 	body = make([]byte, 1<<15)
-	n, err := c.conn.Read(body)
+	n, err := c.recv(body)
 	if err != nil {
 		return Result{}, nil, err
 	}
@@ -493,58 +933,110 @@ func (c *Client) result(body []byte, app uint64) (Result, []ControlValue, error)
 	return res, nil, res
 }
 
-func (c *Client) executeSearch(body []byte) ([]Entry, []ControlValue, error) {
-	if _, err := c.conn.Write(body); err != nil {
-		return nil, nil, err
+func (c *Client) executeSearch(body []byte, fn func(Entry) error) ([]ControlValue, error) {
+	if err := c.send(body); err != nil {
+		return nil, err
 	}
 	body = make([]byte, 1<<15)
 	var (
-		es   []Entry
 		vs   []ControlValue
 		res  Result
 		done bool
 		dec  = ber.NewDecoder(nil)
 	)
 	for !done {
-		n, err := c.conn.Read(body)
+		n, err := c.recv(body)
 		if err != nil {
-			return nil, nil, err
+			return nil, err
 		}
 		dec.Append(body[:n])
 		for dec.Can() && !done {
 			var msg rawMessage
 			if err := dec.Decode(&msg); err != nil {
-				return nil, nil, err
+				return nil, err
 			}
 			id, _ := msg.Body.Peek()
 			switch tag := id.Tag(); uint64(tag) {
 			case ldapSearchResDone:
 				if err := msg.Decode(&res); err != nil {
-					return nil, nil, err
+					return nil, err
 				}
 				vs = msg.Controls
 				done = true
 			case ldapSearchResEntry:
 				var e Entry
 				if err := msg.Decode(&e); err != nil {
-					return nil, nil, err
+					return nil, err
+				}
+				if err := fn(e); err != nil {
+					return nil, err
 				}
-				es = append(es, e)
 			case ldapSearchResRef:
 				var es []string
 				if err := msg.Decode(es); err != nil {
-					return nil, nil, err
+					return nil, err
 				}
 				_ = es
 			default:
-				return nil, nil, fmt.Errorf("unexpected response code (%02x)!", tag)
+				return nil, fmt.Errorf("unexpected response code (%02x)!", tag)
+			}
+		}
+	}
+	if !res.succeed() {
+		return nil, res
+	}
+	return vs, nil
+}
+
+func (c *Client) executeIntermediate(body []byte, fn func(IntermediateResponse) error) (ExtendedResult, error) {
+	var er ExtendedResult
+	if err := c.send(body); err != nil {
+		return er, err
+	}
+	body = make([]byte, 1<<15)
+	var (
+		res  extendedResponse
+		done bool
+		dec  = ber.NewDecoder(nil)
+	)
+	for !done {
+		n, err := c.recv(body)
+		if err != nil {
+			return er, err
+		}
+		dec.Append(body[:n])
+		for dec.Can() && !done {
+			var msg rawMessage
+			if err := dec.Decode(&msg); err != nil {
+				return er, err
+			}
+			id, _ := msg.Body.Peek()
+			switch tag := id.Tag(); uint64(tag) {
+			case ldapExtendedResponse:
+				if err := msg.Decode(&res); err != nil {
+					return er, err
+				}
+				er.Controls = msg.Controls
+				done = true
+			case ldapIntermediateResponse:
+				var ir IntermediateResponse
+				if err := msg.Decode(&ir); err != nil {
+					return er, err
+				}
+				if err := fn(ir); err != nil {
+					return er, err
+				}
+			default:
+				return er, fmt.Errorf("unexpected response code (%02x)!", tag)
 			}
 		}
 	}
+	er.Name = res.Name
+	er.Value = res.Value
 	if !res.succeed() {
-		return nil, nil, res
+		return er, res.Result
 	}
-	return es, vs, nil
+	return er, nil
 }
 
 type rawMessage struct {
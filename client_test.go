@@ -0,0 +1,95 @@
+package ldap
+
+import (
+	"bytes"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+func TestAbandonUnbind(t *testing.T) {
+	server, conn := net.Pipe()
+	defer server.Close()
+
+	c := NewClient(conn)
+	c.binded = true
+
+	data := []struct {
+		Name string
+		Run  func() error
+		Want string
+	}{
+		{
+			Name: "abandon",
+			Run:  func() error { return c.Abandon(5) },
+			Want: "30 06 02 01 01 50 01 05",
+		},
+		{
+			Name: "abandon/controls",
+			Run: func() error {
+				return c.Abandon(300, Control{OID: "2.16.840.1.113730.3.4.2"})
+			},
+			Want: "30 24 02 01 02 50 02 01 2c a0 1b 30 19 04 17 32 2e 31 36 2e 38 34 30 2e 31 2e 31 31 33 37 33 30 2e 33 2e 34 2e 32",
+		},
+		{
+			Name: "unbind",
+			Run:  func() error { return c.Unbind() },
+			Want: "30 05 02 01 03 42 00",
+		},
+	}
+	for _, d := range data {
+		want := decodeFixture(t, d.Want)
+		errs := make(chan error, 1)
+		go func(run func() error) {
+			errs <- run()
+		}(d.Run)
+
+		server.SetReadDeadline(time.Now().Add(time.Second))
+		got := make([]byte, len(want))
+		if _, err := io.ReadFull(server, got); err != nil {
+			t.Fatalf("%s: fail to read request: %s", d.Name, err)
+		}
+		if !bytes.Equal(got, want) {
+			t.Errorf("%s: bytes mismatch\nwant: % x\n got: % x", d.Name, want, got)
+		}
+		select {
+		case err := <-errs:
+			if err != nil {
+				t.Errorf("%s: unexpected error: %s", d.Name, err)
+			}
+		case <-time.After(time.Second):
+			t.Fatalf("%s: client still waiting for a response", d.Name)
+		}
+	}
+	server.SetReadDeadline(time.Now().Add(time.Second))
+	if _, err := server.Read(make([]byte, 1)); err != io.EOF {
+		t.Errorf("unbind: connection not closed (%v)", err)
+	}
+}
+
+func TestSchemaWithoutLookup(t *testing.T) {
+	server, conn := net.Pipe()
+	defer server.Close()
+
+	c := NewClient(conn)
+	c.binded = true
+	c.SetSchema(&Schema{})
+
+	done := make(chan error, 1)
+	go func() {
+		_, err := c.Rename("cn=john,dc=example,dc=com", "cn=jane", false)
+		done <- err
+	}()
+	server.SetReadDeadline(time.Now().Add(time.Second))
+	buf := make([]byte, 512)
+	n, err := server.Read(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if n < 6 || buf[5] != 0x6c {
+		t.Errorf("expected moddn request first, got % x", buf[:n])
+	}
+	server.Close()
+	<-done
+}
@@ -0,0 +1,130 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// The configuration file is a JSON document:
+//
+//	{
+//	  "default": "prod",
+//	  "profiles": {
+//	    "prod": {
+//	      "host": "ldap.example.com:389",
+//	      "binddn": "cn=admin,dc=example,dc=com",
+//	      "password": "secret",
+//	      "starttls": true,
+//	      "tls": "require",
+//	      "ca": "/etc/ssl/certs/example.pem",
+//	      "pin": "",
+//	      "insecure": false,
+//	      "base": "dc=example,dc=com"
+//	    }
+//	  }
+//	}
+//
+// Unknown fields are rejected.
+const (
+	configDir  = "ldap"
+	configFile = "config.json"
+)
+
+var ErrProfileNotFound = errors.New("profile not found")
+
+type Profile struct {
+	Name     string `json:"-"`
+	Host     string `json:"host"`
+	User     string `json:"binddn"`
+	Pass     string `json:"password"`
+	TLS      bool   `json:"starttls"`
+	Policy   string `json:"tls"`
+	Cert     string `json:"ca"`
+	Pin      string `json:"pin"`
+	Insecure bool   `json:"insecure"`
+	Base     string `json:"base"`
+}
+
+type Config struct {
+	Default  string
+	Profiles []Profile
+}
+
+func (c Config) Profile(name string) (Profile, error) {
+	if name == "" {
+		name = c.Default
+	}
+	for _, p := range c.Profiles {
+		if p.Name == name {
+			return p, nil
+		}
+	}
+	return Profile{}, fmt.Errorf("%s: %w", name, ErrProfileNotFound)
+}
+
+func configPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, configDir, configFile), nil
+}
+
+func LoadConfig() (Config, error) {
+	file, err := configPath()
+	if err != nil {
+		return Config{}, err
+	}
+	r, err := os.Open(file)
+	if err != nil {
+		return Config{}, err
+	}
+	defer r.Close()
+
+	cfg, err := ParseConfig(r)
+	if err != nil {
+		return cfg, fmt.Errorf("%s: %w", file, err)
+	}
+	return cfg, nil
+}
+
+func ParseConfig(r io.Reader) (Config, error) {
+	var (
+		cfg Config
+		doc struct {
+			Default  string             `json:"default"`
+			Profiles map[string]Profile `json:"profiles"`
+		}
+		dec = json.NewDecoder(r)
+	)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(&doc); err != nil {
+		return cfg, err
+	}
+	if dec.More() {
+		return cfg, fmt.Errorf("unexpected data after configuration")
+	}
+	cfg.Default = doc.Default
+	for name, p := range doc.Profiles {
+		if name == "" {
+			return cfg, fmt.Errorf("empty profile name")
+		}
+		p.Name = name
+		cfg.Profiles = append(cfg.Profiles, p)
+	}
+	sort.Slice(cfg.Profiles, func(i, j int) bool {
+		return cfg.Profiles[i].Name < cfg.Profiles[j].Name
+	})
+	if cfg.Default == "" {
+		return cfg, nil
+	}
+	if _, err := cfg.Profile(cfg.Default); err != nil {
+		return cfg, fmt.Errorf("default: %w", err)
+	}
+	return cfg, nil
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/midbel/cli"
+	"github.com/midbel/ldap"
+)
+
+var operationalAttributes = []string{
+	"createTimestamp",
+	"modifyTimestamp",
+	"creatorsName",
+	"modifiersName",
+	"entryUUID",
+	"entryCSN",
+	"entryDN",
+	"structuralObjectClass",
+	"hasSubordinates",
+	"subschemaSubentry",
+	"contextCSN",
+	"nsUniqueId",
+	"objectGUID",
+	"objectSid",
+	"whenCreated",
+	"whenChanged",
+	"uSNCreated",
+	"uSNChanged",
+	"pwdChangedTime",
+	"pwdFailureTime",
+	"pwdAccountLockedTime",
+	"memberOf",
+}
+
+func endpointFlags(fs *flag.FlagSet, c *Client, name string) {
+	fs.StringVar(&c.Addr, name, "", fmt.Sprintf("%s host or ldap uri", name))
+	fs.StringVar(&c.User, name+"-user", "", fmt.Sprintf("user on %s host", name))
+	fs.StringVar(&c.Pass, name+"-pass", "", fmt.Sprintf("password on %s host", name))
+}
+
+func sharedTLSFlags(fs *flag.FlagSet, c *Client) {
+	fs.BoolVar(&c.TLS, "z", false, "start tls")
+	fs.StringVar(&c.Cert, "ca", "", "file with trusted certificate authorities")
+	fs.BoolVar(&c.Insecure, "insecure", false, "skip verification of server certificate")
+	fs.StringVar(&c.Policy, "tls", "plaintext", "tls policy (plaintext, opportunistic, require)")
+}
+
+func bindEndpoint(c *Client, name string, tls Client) error {
+	if c.Addr == "" {
+		return fmt.Errorf("-%s: host should be given", name)
+	}
+	addr, err := parseURI(c.Addr)
+	if err != nil {
+		return err
+	}
+	c.Addr = addr
+	c.TLS, c.Policy, c.Cert, c.Pin, c.Insecure = tls.TLS, tls.Policy, tls.Cert, tls.Pin, tls.Insecure
+	if err := c.Bind(); err != nil {
+		return fmt.Errorf("%s: %w", name, err)
+	}
+	return nil
+}
+
+func runCopy(cmd *cli.Command, args []string) error {
+	var (
+		src    Client
+		dst    Client
+		tls    Client
+		filter Filter
+		keep   bool
+	)
+	endpointFlags(&cmd.Flag, &src, "from")
+	endpointFlags(&cmd.Flag, &dst, "to")
+	sharedTLSFlags(&cmd.Flag, &tls)
+	cmd.Flag.Var(&filter, "f", "filter selecting entries to copy")
+	cmd.Flag.BoolVar(&keep, "k", false, "skip entries already present on target")
+	cmd.Flag.BoolVar(&dst.JSON, "j", false, "json output")
+	if err := cmd.Flag.Parse(args); err != nil {
+		return err
+	}
+	if cmd.Flag.NArg() == 0 {
+		return fmt.Errorf("base should be given")
+	}
+
+	if err := bindEndpoint(&src, "from", tls); err != nil {
+		return err
+	}
+	defer src.Unbind()
+	if err := bindEndpoint(&dst, "to", tls); err != nil {
+		return err
+	}
+	defer dst.Unbind()
+
+	es, err := readSubtree(&src, cmd.Flag.Arg(0), filter.Filter)
+	if err != nil {
+		return err
+	}
+	var copied int
+	for _, e := range es {
+		_, err := dst.Add(e.Name, e.Attrs)
+		st := statusOf("copy", e.Name, err)
+		switch {
+		case err == nil:
+			copied++
+			st.text = fmt.Sprintf("%s copied", e.Name)
+		case keep && isResultCode(err, ldap.EntryAlreadyExists):
+			st.text = fmt.Sprintf("%s skipped (already exists)", e.Name)
+			err = nil
+		default:
+			st.text = fmt.Sprintf("fail to copy %s: %s", e.Name, err)
+		}
+		if err := dst.Output().Status(st); err != nil {
+			return err
+		}
+		if err != nil {
+			return err
+		}
+	}
+	st := statusOf("copy", cmd.Flag.Arg(0), nil)
+	st.Value = copied
+	st.text = fmt.Sprintf("%d/%d entries copied", copied, len(es))
+	return dst.Output().Status(st)
+}
+
+func readSubtree(c *Client, base string, filter ldap.Filter) ([]ldap.Entry, error) {
+	options := []ldap.SearchOption{
+		ldap.WithScope(ldap.ScopeWhole),
+		ldap.WithAttributes([]string{"*"}),
+	}
+	if filter != nil {
+		options = append(options, ldap.WithFilter(filter))
+	}
+	es, _, err := c.Client.Search(base, options...)
+	if err != nil {
+		return nil, err
+	}
+	schema, _ := c.Schema()
+	for i := range es {
+		es[i] = userAttributes(es[i], schema)
+	}
+	sort.SliceStable(es, func(i, j int) bool {
+		return depthOf(es[i].Name) < depthOf(es[j].Name)
+	})
+	return es, nil
+}
+
+func userAttributes(e ldap.Entry, schema *ldap.Schema) ldap.Entry {
+	attrs := make([]ldap.Attribute, 0, len(e.Attrs))
+	for _, a := range e.Attrs {
+		if isOperational(a.Name, schema) {
+			continue
+		}
+		attrs = append(attrs, a)
+	}
+	e.Attrs = attrs
+	return e
+}
+
+func isOperational(name string, schema *ldap.Schema) bool {
+	if schema != nil {
+		if at, ok := schema.AttributeType(name); ok {
+			return at.NoUserModification || (at.Usage != "" && at.Usage != ldap.UsageUser)
+		}
+	}
+	for _, n := range operationalAttributes {
+		if strings.EqualFold(n, name) {
+			return true
+		}
+	}
+	return false
+}
+
+func depthOf(dn string) int {
+	if d, err := ldap.Explode(dn); err == nil {
+		return d.Len()
+	}
+	return strings.Count(dn, ",") + 1
+}
+
+func isResultCode(err error, code int64) bool {
+	var res ldap.Result
+	return errors.As(err, &res) && res.Code == code
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/midbel/cli"
+	"github.com/midbel/ldap"
+)
+
+type rdnComponent struct {
+	Attr  string `json:"attr"`
+	Value string `json:"value"`
+}
+
+type explodedDN struct {
+	DN         string           `json:"dn"`
+	RDN        string           `json:"rdn"`
+	Parent     string           `json:"parent"`
+	Depth      int              `json:"depth"`
+	Components [][]rdnComponent `json:"components"`
+}
+
+type explodeError struct {
+	Position int `json:"position"`
+}
+
+func runExplode(cmd *cli.Command, args []string) error {
+	var (
+		client Client
+		parent bool
+		rdn    bool
+	)
+	cmd.Flag.BoolVar(&parent, "parent", false, "print the parent of the dn")
+	cmd.Flag.BoolVar(&rdn, "rdn", false, "print the rdn of the dn")
+	cmd.Flag.BoolVar(&client.JSON, "j", false, "json output")
+	if err := cmd.Flag.Parse(args); err != nil {
+		return err
+	}
+
+	var (
+		invalid int
+		total   int
+	)
+	err := eachDN(cmd.Flag.Args(), os.Stdin, func(str string) error {
+		total++
+		dn, err := ldap.Explode(str)
+		st := statusOf("explode", str, err)
+		if err != nil {
+			invalid++
+			st.text = fmt.Sprintf("%s: %s", str, err)
+			var derr *ldap.DNSyntaxError
+			if errors.As(err, &derr) {
+				st.Value = explodeError{Position: derr.Offset}
+				st.text = fmt.Sprintf("%s\n%s\n%*s", st.text, str, derr.Offset+1, "^")
+			}
+		} else {
+			st.Value = explodeDN(dn)
+			st.text = formatExplode(dn, rdn, parent)
+		}
+		return client.Output().Status(st)
+	})
+	if err != nil {
+		return err
+	}
+	if invalid > 0 {
+		return fmt.Errorf("%d/%d invalid DN(s)", invalid, total)
+	}
+	return nil
+}
+
+func eachDN(args []string, r io.Reader, fn func(string) error) error {
+	if len(args) > 0 && !(len(args) == 1 && args[0] == "-") {
+		for _, a := range args {
+			if err := fn(a); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	scan := bufio.NewScanner(r)
+	for scan.Scan() {
+		line := strings.TrimSpace(scan.Text())
+		if line == "" {
+			continue
+		}
+		if err := fn(line); err != nil {
+			return err
+		}
+	}
+	return scan.Err()
+}
+
+func explodeDN(dn ldap.DN) explodedDN {
+	x := explodedDN{
+		DN:         dn.String(),
+		RDN:        dn.RDN().String(),
+		Parent:     dn.Parent(1).String(),
+		Depth:      dn.Len(),
+		Components: make([][]rdnComponent, 0, dn.Len()),
+	}
+	for i := 0; i < dn.Len(); i++ {
+		var cs []rdnComponent
+		for _, a := range dn.At(i).Attributes() {
+			cs = append(cs, rdnComponent{Attr: a.Name, Value: a.Values[0]})
+		}
+		x.Components = append(x.Components, cs)
+	}
+	return x
+}
+
+func formatExplode(dn ldap.DN, rdn, parent bool) string {
+	var parts []string
+	if rdn {
+		parts = append(parts, dn.RDN().String())
+	}
+	if parent {
+		parts = append(parts, dn.Parent(1).String())
+	}
+	if len(parts) > 0 {
+		return strings.Join(parts, "\t")
+	}
+	for i := 0; i < dn.Len(); i++ {
+		parts = append(parts, dn.At(i).String())
+	}
+	return strings.Join(parts, "\t")
+}
@@ -0,0 +1,286 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/midbel/ldap"
+)
+
+type Status struct {
+	Command string      `json:"command"`
+	Target  string      `json:"target,omitempty"`
+	Code    int64       `json:"code"`
+	Error   string      `json:"error,omitempty"`
+	Value   interface{} `json:"value,omitempty"`
+
+	text string
+}
+
+func statusOf(command, target string, err error) Status {
+	st := Status{
+		Command: command,
+		Target:  target,
+	}
+	if err == nil {
+		return st
+	}
+	var res ldap.Result
+	if errors.As(err, &res) {
+		st.Code = res.Code
+	} else {
+		st.Code = ldap.Other
+	}
+	st.Error = err.Error()
+	return st
+}
+
+type Formatter interface {
+	Entry(ldap.Entry) error
+	Status(Status) error
+}
+
+var formatters = map[string]func(io.Writer, io.Writer) Formatter{
+	"text": newTextFormatter,
+	"json": newJSONFormatter,
+}
+
+func NewFormatter(name string, out, errs io.Writer) (Formatter, error) {
+	mk, ok := formatters[name]
+	if !ok {
+		return nil, fmt.Errorf("%s: unknown output format", name)
+	}
+	return mk(out, errs), nil
+}
+
+type textFormatter struct {
+	out  io.Writer
+	errs io.Writer
+}
+
+func newTextFormatter(out, errs io.Writer) Formatter {
+	return &textFormatter{
+		out:  out,
+		errs: errs,
+	}
+}
+
+func (f *textFormatter) Entry(e ldap.Entry) error {
+	return ldap.WriteLDIF(f.out, e)
+}
+
+func (f *textFormatter) Status(st Status) error {
+	if st.text == "" {
+		return nil
+	}
+	w := f.out
+	if st.Error != "" {
+		w = f.errs
+	}
+	fmt.Fprintln(w, st.text)
+	return nil
+}
+
+type jsonFormatter struct {
+	enc *json.Encoder
+}
+
+func newJSONFormatter(out, _ io.Writer) Formatter {
+	return jsonFormatter{
+		enc: json.NewEncoder(out),
+	}
+}
+
+func (f jsonFormatter) Entry(e ldap.Entry) error {
+	return f.enc.Encode(e)
+}
+
+func (f jsonFormatter) Status(st Status) error {
+	return f.enc.Encode(st)
+}
+
+type flusher interface {
+	Flush() error
+}
+
+type columnFormatter struct {
+	rows    func([]string) error
+	flush   func() error
+	errs    io.Writer
+	sep     string
+	columns []string
+	dynamic bool
+	header  bool
+	pending []ldap.Entry
+}
+
+func newCSVFormatter(out, errs io.Writer, columns []string) Formatter {
+	w := csv.NewWriter(out)
+	f := newColumnFormatter(errs, columns, "|")
+	f.rows = w.Write
+	f.flush = func() error {
+		w.Flush()
+		return w.Error()
+	}
+	return f
+}
+
+func newTableFormatter(out, errs io.Writer, columns []string) Formatter {
+	w := tabwriter.NewWriter(out, 0, 8, 2, ' ', 0)
+	f := newColumnFormatter(errs, columns, ", ")
+	f.rows = func(row []string) error {
+		_, err := fmt.Fprintln(w, strings.Join(row, "\t"))
+		return err
+	}
+	f.flush = w.Flush
+	return f
+}
+
+func newColumnFormatter(errs io.Writer, columns []string, sep string) *columnFormatter {
+	f := columnFormatter{
+		errs: errs,
+		sep:  sep,
+	}
+	for _, c := range columns {
+		switch c {
+		case "", "1.1":
+		case "*", "+":
+			f.dynamic = true
+		default:
+			f.columns = append(f.columns, c)
+		}
+	}
+	f.dynamic = f.dynamic || len(f.columns) == 0
+	return &f
+}
+
+func (f *columnFormatter) Entry(e ldap.Entry) error {
+	if f.dynamic {
+		f.pending = append(f.pending, e)
+		return nil
+	}
+	return f.write(e)
+}
+
+func (f *columnFormatter) Status(st Status) error {
+	if st.text == "" || st.Error == "" {
+		return nil
+	}
+	fmt.Fprintln(f.errs, st.text)
+	return nil
+}
+
+func (f *columnFormatter) Flush() error {
+	if f.dynamic {
+		seen := make(map[string]struct{})
+		for _, c := range f.columns {
+			seen[strings.ToLower(c)] = struct{}{}
+		}
+		for _, e := range f.pending {
+			for _, a := range e.Attrs {
+				if _, ok := seen[strings.ToLower(a.Name)]; ok {
+					continue
+				}
+				seen[strings.ToLower(a.Name)] = struct{}{}
+				f.columns = append(f.columns, a.Name)
+			}
+		}
+		for _, e := range f.pending {
+			if err := f.write(e); err != nil {
+				return err
+			}
+		}
+		f.pending = nil
+	}
+	if !f.header {
+		if err := f.writeHeader(); err != nil {
+			return err
+		}
+	}
+	return f.flush()
+}
+
+func (f *columnFormatter) write(e ldap.Entry) error {
+	if !f.header {
+		if err := f.writeHeader(); err != nil {
+			return err
+		}
+	}
+	row := []string{e.Name}
+	for _, c := range f.columns {
+		row = append(row, strings.Join(e.GetValues(c), f.sep))
+	}
+	return f.rows(row)
+}
+
+func (f *columnFormatter) writeHeader() error {
+	f.header = true
+	return f.rows(append([]string{"dn"}, f.columns...))
+}
+
+func PrintFeatures(out Formatter, attr, prefix string, values []string, names map[string]string) error {
+	if names == nil {
+		names = make(map[string]string)
+	}
+	for _, v := range values {
+		st := Status{
+			Command: "support",
+			Target:  v,
+			Value: map[string]string{
+				"type": attr,
+				"name": names[v],
+			},
+		}
+		if str := names[v]; str != "" {
+			st.text = fmt.Sprintf("- %s: %s (%s)", prefix, str, v)
+		} else {
+			st.text = fmt.Sprintf("- %s: %s", prefix, v)
+		}
+		if err := out.Status(st); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func PrintVendor(out Formatter, dse ldap.RootDSE) error {
+	for _, v := range dse.Versions {
+		st := Status{
+			Command: "support",
+			Target:  strconv.Itoa(v),
+			Value: map[string]string{
+				"type": supportedVersions,
+			},
+			text: fmt.Sprintf("- V: LDAPv%d", v),
+		}
+		if err := out.Status(st); err != nil {
+			return err
+		}
+	}
+	vendor, version := dse.DetectVendor()
+	if dse.VendorName == "" && dse.VendorVersion == "" && vendor == ldap.VendorUnknown {
+		return nil
+	}
+	st := Status{
+		Command: "support",
+		Target:  dse.VendorName,
+		Value: map[string]string{
+			"type":    vendorInfo,
+			"name":    dse.VendorName,
+			"version": dse.VendorVersion,
+			"flavor":  vendor.String(),
+		},
+		text: strings.TrimSpace(fmt.Sprintf("- V: %s %s (%s)", dse.VendorName, dse.VendorVersion, vendor)),
+	}
+	if dse.VendorName == "" && dse.VendorVersion == "" {
+		st.Target = vendor.String()
+		st.text = strings.TrimSpace(fmt.Sprintf("- V: %s %s", vendor, version))
+	}
+	return out.Status(st)
+}
@@ -1,16 +1,17 @@
 package main
 
 import (
-	"crypto/md5"
-	"crypto/sha1"
+	"bytes"
 	"crypto/tls"
-	"encoding/base64"
+	"errors"
 	"flag"
 	"fmt"
 	"io"
+	"io/ioutil"
 	"os"
-	"sort"
 	"strings"
+	"sync"
+	"time"
 
 	"github.com/midbel/cli"
 	"github.com/midbel/ldap"
@@ -25,12 +26,26 @@ const (
 	scopeTree   = "subtree"
 )
 
+const (
+	envURI  = "LDAP_URI"
+	envUser = "LDAP_BINDDN"
+	envPass = "LDAP_PASSWORD"
+)
+
 const (
 	supportedFeatures   = "supportedFeatures"
 	supportedControls   = "supportedControl"
 	supportedExtensions = "supportedExtension"
+	supportedSASL       = "supportedSASLMechanisms"
+	supportedVersions   = "supportedLDAPVersion"
+	namingContexts      = "namingContexts"
+	vendorInfo          = "vendor"
 )
 
+var localURLs = ldap.WithURLPolicy(ldap.URLPolicy{
+	Schemes: []string{"file"},
+})
+
 type Filter struct {
 	ldap.Filter
 }
@@ -146,14 +161,144 @@ func (a *Attributes) Option() []ldap.SearchOption {
 	return options
 }
 
+type Replace struct {
+	Attrs []ldap.PartialAttribute
+}
+
+func (r *Replace) Set(str string) error {
+	x := strings.Index(str, "=")
+	if x <= 0 {
+		return fmt.Errorf("%s: invalid attribute (missing =)", str)
+	}
+	var pa ldap.PartialAttribute
+	pa.Mod = ldap.ModReplace
+	pa.Name = str[:x]
+	pa.Values = append(pa.Values, str[x+1:])
+	r.Attrs = append(r.Attrs, pa)
+	return nil
+}
+
+func (r *Replace) String() string {
+	return "replace"
+}
+
 type Client struct {
 	*ldap.Client
 
-	User string
-	Pass string
-	Cert string
-	Addr string
-	TLS  bool
+	User     string
+	Pass     string
+	Cert     string
+	Pin      string
+	Addr     string
+	TLS      bool
+	Insecure bool
+	Policy   string
+	JSON     bool
+	Profile  string
+	Prompt   bool
+	Continue bool
+	Workers  int
+	Base     string
+
+	flags *flag.FlagSet
+	out   Formatter
+	log   *ldap.LDIFWriter
+}
+
+func (c *Client) Output() Formatter {
+	if c.out != nil {
+		return c.out
+	}
+	name := "text"
+	if c.JSON {
+		name = "json"
+	}
+	c.out, _ = NewFormatter(name, os.Stdout, os.Stderr)
+	return c.out
+}
+
+func (c *Client) TLSFlags(fs *flag.FlagSet) {
+	fs.StringVar(&c.Cert, "ca", "", "file with trusted certificate authorities")
+	fs.StringVar(&c.Pin, "pin", "", "pinned public keys (base64 sha256, comma separated)")
+	fs.BoolVar(&c.Insecure, "insecure", false, "skip verification of server certificate")
+	fs.StringVar(&c.Policy, "tls", "plaintext", "tls policy (plaintext, opportunistic, require)")
+	fs.StringVar(&c.Profile, "P", "", "profile from configuration file")
+	fs.BoolVar(&c.Prompt, "W", false, "prompt for password")
+	c.flags = fs
+}
+
+func (c *Client) explicit() map[string]struct{} {
+	set := make(map[string]struct{})
+	c.flags.Visit(func(f *flag.Flag) {
+		set[f.Name] = struct{}{}
+	})
+	return set
+}
+
+func (c *Client) loadProfile(set map[string]struct{}) error {
+	cfg, err := LoadConfig()
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) && c.Profile == "" {
+			return nil
+		}
+		return err
+	}
+	if c.Profile == "" && cfg.Default == "" {
+		return nil
+	}
+	p, err := cfg.Profile(c.Profile)
+	if err != nil {
+		return err
+	}
+	apply := func(flag string, dst *string, value string) {
+		if _, ok := set[flag]; !ok && value != "" {
+			*dst = value
+		}
+	}
+	apply("r", &c.Addr, p.Host)
+	apply("u", &c.User, p.User)
+	apply("p", &c.Pass, p.Pass)
+	apply("tls", &c.Policy, p.Policy)
+	apply("ca", &c.Cert, p.Cert)
+	apply("pin", &c.Pin, p.Pin)
+	if _, ok := set["z"]; !ok && p.TLS {
+		c.TLS = true
+	}
+	if _, ok := set["insecure"]; !ok && p.Insecure {
+		c.Insecure = true
+	}
+	c.Base = p.Base
+	return nil
+}
+
+func (c *Client) loadEnv(set map[string]struct{}) error {
+	if uri := os.Getenv(envURI); uri != "" {
+		if _, ok := set["r"]; !ok {
+			addr, err := parseURI(uri)
+			if err != nil {
+				return fmt.Errorf("%s: %w", envURI, err)
+			}
+			c.Addr = addr
+		}
+	}
+	if user := os.Getenv(envUser); user != "" {
+		if _, ok := set["u"]; !ok {
+			c.User = user
+		}
+	}
+	if pass := os.Getenv(envPass); pass != "" {
+		if _, ok := set["p"]; !ok {
+			c.Pass = pass
+		}
+	}
+	return nil
+}
+
+func (c *Client) DefaultBase() (string, error) {
+	if c.Base != "" {
+		return c.Base, nil
+	}
+	return c.Client.DefaultBase()
 }
 
 func (c *Client) Search(base string, options []ldap.SearchOption) error {
@@ -161,87 +306,255 @@ func (c *Client) Search(base string, options []ldap.SearchOption) error {
 	if err != nil {
 		return err
 	}
-	for i, e := range es {
+	for _, e := range es {
 		if e.Name == flag.Arg(1) {
 			continue
 		}
-		if i > 0 {
-			fmt.Fprintln(os.Stdout)
+		if err := c.Output().Entry(e); err != nil {
+			return err
 		}
-		PrintEntry(e)
 	}
 	return nil
 }
 
-func (c *Client) SupportedControls() error {
-	return c.searchFeatures(supportedControls, "C", ldap.ControlNames)
+func (c *Client) Bind() error {
+	if c.flags != nil {
+		set := c.explicit()
+		if err := c.loadProfile(set); err != nil {
+			return err
+		}
+		if err := c.loadEnv(set); err != nil {
+			return err
+		}
+	}
+	if c.Prompt {
+		pass, err := readPassword(fmt.Sprintf("password for %s: ", c.User))
+		if err != nil {
+			return err
+		}
+		c.Pass = pass
+	}
+	var policy ldap.TLSPolicy
+	if err := policy.UnmarshalText([]byte(c.Policy)); err != nil {
+		return err
+	}
+	if c.TLS {
+		policy = ldap.RequireTLS
+	}
+	if c.User != "" && c.Pass == "" {
+		return fmt.Errorf("%s: %w", c.User, ldap.ErrUnauthBind)
+	}
+	cfg, err := c.tlsConfig()
+	if err != nil {
+		return err
+	}
+	c.Client, err = ldap.BindPolicy(c.Addr, c.User, c.Pass, policy, cfg)
+	return err
 }
 
-func (c *Client) SupportedExtensions() error {
-	return c.searchFeatures(supportedExtensions, "E", ldap.ExtensionNames)
+func (c *Client) tlsConfig() (*tls.Config, error) {
+	var options []ldap.TLSOption
+	if c.Cert != "" {
+		options = append(options, ldap.WithRootCAFile(c.Cert))
+	}
+	if c.Pin != "" {
+		options = append(options, ldap.WithPinnedKeys(strings.Split(c.Pin, ",")...))
+	}
+	if c.Insecure {
+		options = append(options, ldap.WithInsecureSkipVerify())
+	}
+	return ldap.NewTLSConfig(options...)
 }
 
-func (c *Client) SupportedFeatures() error {
-	return c.searchFeatures(supportedFeatures, "F", ldap.FeatureNames)
+type execSummary struct {
+	Added    int `json:"added"`
+	Modified int `json:"modified"`
+	Deleted  int `json:"deleted"`
+	Renamed  int `json:"renamed"`
+	Failed   int `json:"failed"`
 }
 
-func (c *Client) Bind() error {
-	var err error
-	if c.TLS {
-		cfg := tls.Config{
-			InsecureSkipVerify: true,
-		}
-		c.Client, err = ldap.BindTLS(c.Addr, c.User, c.Pass, &cfg)
-	} else {
-		c.Client, err = ldap.Bind(c.Addr, c.User, c.Pass)
+func (s *execSummary) update(op string, err error) {
+	if err != nil {
+		s.Failed++
+		return
 	}
-	return err
+	switch op {
+	case "add":
+		s.Added++
+	case "delete":
+		s.Deleted++
+	case "modify":
+		s.Modified++
+	case "modrdn":
+		s.Renamed++
+	}
+}
+
+func (s execSummary) Total() int {
+	return s.Added + s.Modified + s.Deleted + s.Renamed + s.Failed
+}
+
+func (s execSummary) String() string {
+	return fmt.Sprintf("added: %d, modified: %d, deleted: %d, renamed: %d, failed: %d", s.Added, s.Modified, s.Deleted, s.Renamed, s.Failed)
 }
 
 func (c *Client) ExecFromReader(r io.Reader) error {
-	return ldap.ReadLDIF(r, func(ct ldap.ChangeType, cg ldap.Change) error {
-		var err error
-		switch ct {
-		case ldap.ModAdd:
-			attrs := make([]ldap.Attribute, len(cg.Attrs))
-			for i := range cg.Attrs {
-				attrs[i] = cg.Attrs[i].Attribute
+	if c.Workers > 1 {
+		return c.execParallel(r)
+	}
+	var (
+		rs     = ldap.NewReader(r, localURLs)
+		report = c.newReport()
+	)
+	for {
+		rec, err := rs.Read()
+		if err != nil {
+			if errors.Is(err, io.EOF) {
+				break
 			}
-			_, err = c.Client.Add(cg.Name, attrs)
-		case ldap.ModDelete:
-			_, err = c.Client.Delete(cg.Name)
-		case ldap.ModReplace:
-			_, err = c.Client.Modify(cg.Name, cg.Attrs)
-		default:
-			err = fmt.Errorf("unsupported/unknown action")
+			return recordError(rec, err)
+		}
+		op, err := c.execChange(rec.Type, rec.Change)
+		if err := report.record(rec, op, err); err != nil {
+			return err
+		}
+	}
+	return report.finish()
+}
+
+type execReport struct {
+	mu       sync.Mutex
+	client   *Client
+	sum      execSummary
+	progress bool
+}
+
+func (c *Client) newReport() *execReport {
+	return &execReport{
+		client:   c,
+		progress: c.Continue && isTerminal(os.Stderr),
+	}
+}
+
+func (r *execReport) record(rec ldap.ChangeRecord, op string, err error) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.sum.update(op, err)
+	if r.progress {
+		fmt.Fprintf(os.Stderr, "\r%d records processed", r.sum.Total())
+	}
+	st := statusOf("execute", rec.Name, err)
+	st.Value = op
+	if err != nil && r.client.Continue {
+		if r.progress {
+			fmt.Fprintln(os.Stderr)
 		}
+		st.text = fmt.Sprintf("line %d: fail to %s %s: %s", rec.Line, op, rec.Name, err)
+	}
+	if err := r.client.Output().Status(st); err != nil {
 		return err
-	})
+	}
+	if err != nil && !r.client.Continue {
+		return err
+	}
+	return nil
 }
 
-func (c *Client) ExecFromFile(file string) error {
-	r, err := os.Open(file)
-	if err != nil {
+func (r *execReport) finish() error {
+	if !r.client.Continue {
+		return nil
+	}
+	if r.progress {
+		fmt.Fprintln(os.Stderr)
+	}
+	st := statusOf("execute", "", nil)
+	st.Value = r.sum
+	st.text = r.sum.String()
+	if err := r.client.Output().Status(st); err != nil {
 		return err
 	}
-	defer r.Close()
-	return c.ExecFromReader(r)
+	if r.sum.Failed > 0 {
+		return fmt.Errorf("%d record(s) failed", r.sum.Failed)
+	}
+	return nil
+}
+
+func recordError(rec ldap.ChangeRecord, err error) error {
+	if rec.Line > 0 {
+		err = fmt.Errorf("line %d: %w", rec.Line, err)
+	}
+	return err
+}
+
+func (c *Client) execChange(ct ldap.ChangeType, cg ldap.Change) (string, error) {
+	op, err := c.apply(ct, cg)
+	if err == nil && c.log != nil {
+		err = c.log.WriteChange(ct, cg)
+	}
+	return op, err
 }
 
-func (c *Client) searchFeatures(attr, prefix string, names map[string]string) error {
+func (c *Client) apply(ct ldap.ChangeType, cg ldap.Change) (string, error) {
 	var (
-		list  = ldap.WithAttributes([]string{attr})
-		lim   = ldap.WithLimit(1)
-		scope = ldap.WithScope(ldap.ScopeBase)
+		err error
+		op  string
 	)
-	es, _, err := c.Client.Search("", list, lim, scope)
+	switch ct {
+	case ldap.ModAdd:
+		attrs := make([]ldap.Attribute, len(cg.Attrs))
+		for i := range cg.Attrs {
+			attrs[i] = cg.Attrs[i].Attribute
+		}
+		op = "add"
+		_, err = c.Client.Add(cg.Name, attrs)
+	case ldap.ModDelete:
+		op = "delete"
+		_, err = c.Client.Delete(cg.Name)
+	case ldap.ModReplace:
+		op = "modify"
+		_, err = c.Client.Modify(cg.Name, cg.Attrs)
+	case ldap.ModRDN:
+		op = "modrdn"
+		if cg.NewSuperior == "" {
+			_, err = c.Client.Rename(cg.Name, cg.NewRDN, !cg.DeleteOld)
+		} else {
+			_, err = c.Client.ModDN(cg.Name, cg.NewRDN, cg.NewSuperior, !cg.DeleteOld)
+		}
+	default:
+		err = fmt.Errorf("unsupported/unknown action")
+	}
+	return op, err
+}
+
+func (c *Client) DeleteTree(dn string, force bool, controls ...ldap.Control) error {
+	done, err := c.Client.DeleteTree(dn, force, controls...)
+	for _, d := range done {
+		st := statusOf("delete", d, nil)
+		if err := c.Output().Status(st); err != nil {
+			return err
+		}
+	}
 	if err != nil {
-		return err
+		st := statusOf("delete", dn, err)
+		if errors.Is(err, ldap.ErrReferencedEntries) {
+			st.text = fmt.Sprintf("fail to delete %s: %s (use -force to delete anyway)", dn, err)
+		} else {
+			st.text = fmt.Sprintf("fail to delete %s: %s", dn, err)
+		}
+		return c.Output().Status(st)
 	}
-	if len(es) == 0 {
-		return nil
+	return nil
+}
+
+func (c *Client) ExecFromFile(file string) error {
+	r, err := os.Open(file)
+	if err != nil {
+		return err
 	}
-	return PrintFeatures(es[0], attr, prefix, names)
+	defer r.Close()
+	return c.ExecFromReader(r)
 }
 
 var commands = []*cli.Command{
@@ -252,24 +565,35 @@ var commands = []*cli.Command{
 		Run:   runBind,
 	},
 	{
-		Usage: "search [-u] [-p] [-r] [-t] [-a] [-s] <base> <filter>",
+		Usage: "search [-u] [-p] [-r] [-t] [-a] [-s] [-n] [-m] [-j] [-format] [<base> [<filter>]]",
 		Alias: []string{"filter", "find"},
 		Short: "search for entries in directory",
 		Run:   runSearch,
 	},
 	{
-		Usage: "support [-u] [-p] [-r] [-e] [-f] [-c] [-a]",
+		Usage: "support [-u] [-p] [-r] [-e] [-f] [-c] [-m] [-n] [-v] [-j]",
 		Short: "get list of supported features",
 		Run:   runSupported,
 	},
 	{
-		Usage: "compare [-u] [-p] [-r] <base> <assertion...>",
+		Usage: "compare [-u] [-p] [-r] [-j] <base> <assertion...>",
 		Alias: []string{"cmp"},
 		Short: "compare entry's attributes with assertion",
 		Run:   runCompare,
 	},
 	{
-		Usage: "delete [-u] [-p] [-r] <dn...>",
+		Usage: "add [-u] [-p] [-r] [-j] [-f] <dn> [<attr=value...>]",
+		Short: "add an entry built from attribute/value pairs",
+		Run:   runAdd,
+	},
+	{
+		Usage: "modify [-u] [-p] [-r] [-j] [-f] <dn> <op:attr[=value]...>",
+		Alias: []string{"mod"},
+		Short: "modify an entry with inline add, replace, delete and increment operations",
+		Run:   runModify,
+	},
+	{
+		Usage: "delete [-u] [-p] [-r] [-R] [-force] [-j] <dn...>",
 		Alias: []string{"rm", "del", "remove"},
 		Short: "remove entries from directory",
 		Run:   runDelete,
@@ -286,7 +610,7 @@ var commands = []*cli.Command{
 		Run:   runMove,
 	},
 	{
-		Usage: "execute [-u] [-p] [-r] <file|->",
+		Usage: "execute [-u] [-p] [-r] [-j] [-o] [-n] [-s] [-c] [-w] <file|->",
 		Alias: []string{"exec"},
 		Short: "execute given operations to directory",
 		Run:   runExec,
@@ -297,20 +621,90 @@ var commands = []*cli.Command{
 		Run:   runModifyPasswd,
 	},
 	{
-		Usage: "whoami [-u] [-p] [-r] ",
+		Usage: "expire [-u] [-p] [-r] [-s] [-a] [-d] [-x] [-n] [-b] [-w] [-l] [-o] <base> [<filter>]",
+		Short: "delete or disable entries older than a given age",
+		Run:   runExpire,
+	},
+	{
+		Usage: "rewrite [-u] [-p] [-r] [-a] [-n] [-b] [-w] [-o] <base> <old> <new>",
+		Short: "rewrite dn-valued attributes referencing entries under a moved base",
+		Run:   runRewrite,
+	},
+	{
+		Usage: "export [-u] [-p] [-r] [-a] [-s] [-n] [-o|-out] [-c] [-q] [<base> [<filter>]]",
+		Short: "export entries to ldif with resumable checkpoints",
+		Run:   runExport,
+	},
+	{
+		Usage: "plan [-u] [-p] [-r] [-o] <file>",
+		Short: "compute changes needed to reach declared entries",
+		Run:   runPlan,
+	},
+	{
+		Usage: "drift [-u] [-p] [-r] [-j] <file>",
+		Short: "report entries differing from declared state",
+		Run:   runDrift,
+	},
+	{
+		Usage: "schema [-u] [-p] [-r] [-j] [attr|class] <name>",
+		Short: "describe attribute types and object classes of the server schema",
+		Run:   runSchema,
+	},
+	{
+		Usage: "schema-diff [-u] [-p] [-z] [-j] <source> <target>",
+		Short: "compare attribute types and object classes of two subschemas",
+		Run:   runSchemaDiff,
+	},
+	{
+		Usage: "generate [-u] [-p] [-z] [-k] [-o] <source> <class...>",
+		Short: "generate go structs for object classes of a subschema",
+		Run:   runGenerate,
+	},
+	{
+		Usage: "monitor [-u] [-p] [-r] [-j] [-interval] [-n]",
+		Short: "summarize connections, operations and statistics from cn=monitor",
+		Run:   runMonitor,
+	},
+	{
+		Usage: "shell [-u] [-p] [-r] [<base>]",
+		Short: "interactive shell on a single bound connection",
+		Run:   runShell,
+	},
+	{
+		Usage: "copy [-z] [-j] [-f] [-k] -from <host> -to <host> <base>",
+		Short: "copy a subtree from one server to another",
+		Run:   runCopy,
+	},
+	{
+		Usage: "sync [-z] [-j] [-f] [-k] [-n] [-from <host>|-i <file>] -to <host> <base>",
+		Short: "mirror a subtree or ldif file onto a server applying only needed changes",
+		Run:   runSync,
+	},
+	{
+		Usage: "tree [-u] [-p] [-r] [-d] [-f] [<base>]",
+		Short: "render the directory tree below base",
+		Run:   runTree,
+	},
+	{
+		Usage: "ping [-r] [-j] [-t] [-c] [-i]",
+		Short: "check server reachability, starttls availability and latency",
+		Run:   runPing,
+	},
+	{
+		Usage: "whoami [-u] [-p] [-r] [-j]",
 		Short: "whoami request",
 		Run:   runWhoami,
 	},
-	// {
-	// 	Usage: "explode <dn...>",
-	// 	Short: "explode dn components",
-	// 	Run:   runExplode,
-	// },
-	// {
-	// 	Usage: "passwd [-a] [-s]",
-	// 	Short: "create password",
-	// 	Run:   runPasswd,
-	// },
+	{
+		Usage: "explode [-j] [-parent] [-rdn] [<dn...>]",
+		Short: "explode dn components",
+		Run:   runExplode,
+	},
+	{
+		Usage: "passwd [-a] [-s] [-g] [-n] [-c]",
+		Short: "hash a password for use as userPassword value",
+		Run:   runPasswd,
+	},
 }
 
 func main() {
@@ -319,53 +713,34 @@ func main() {
 
 func runPasswd(cmd *cli.Command, args []string) error {
 	var (
-		alg      = cmd.Flag.String("a", "plain", "algorithm")
+		alg      = cmd.Flag.String("a", "ssha", "scheme (plain, md5, smd5, sha, ssha, sha256, ssha256, sha512, ssha512, crypt, pbkdf2, pbkdf2-sha256, pbkdf2-sha512, bcrypt)")
 		secret   = cmd.Flag.String("s", "", "secret")
 		generate = cmd.Flag.Bool("g", false, "generate")
 		length   = cmd.Flag.Int("n", 8, "length")
+		cost     = cmd.Flag.Int("c", 0, "rounds, iterations or cost of the scheme (0 for default)")
 	)
 	if err := cmd.Flag.Parse(args); err != nil {
 		return err
 	}
 	if *generate {
-		fmt.Fprintln(os.Stdout, strrand.String(*length))
-		return nil
+		*secret = strrand.String(*length)
+		fmt.Fprintln(os.Stdout, *secret)
 	}
 	if *secret == "" {
-		return nil
+		pass, err := readPassword("password: ")
+		if err != nil {
+			return err
+		}
+		*secret = pass
 	}
-	var passwd []byte
-	switch strings.ToLower(*alg) {
-	case "md5":
-		sum := md5.Sum([]byte(*secret))
-		passwd = sum[:]
-	case "sha":
-		sum := sha1.Sum([]byte(*secret))
-		passwd = sum[:]
-	case "", "plain":
-		fmt.Fprintln(os.Stdout, *secret)
-		return nil
-	default:
-		return fmt.Errorf("%s: unsupported algorithm", *alg)
+	if *secret == "" {
+		return fmt.Errorf("empty secret")
 	}
-	fmt.Fprintf(os.Stdout, "{%s}%s", strings.ToUpper(*alg), base64.StdEncoding.EncodeToString(passwd))
-	fmt.Fprintln(os.Stdout)
-	return nil
-}
-
-func runExplode(cmd *cli.Command, args []string) error {
-	if err := cmd.Flag.Parse(args); err != nil {
+	passwd, err := ldap.HashPassword(*alg, *secret, *cost)
+	if err != nil {
 		return err
 	}
-	for _, a := range cmd.Flag.Args() {
-		dn, err := ldap.Explode(a)
-		if err != nil {
-			fmt.Fprintln(os.Stderr, a, ":", err)
-			continue
-		}
-		fmt.Fprintf(os.Stdout, "%s: %s", dn.RDN(), dn)
-		fmt.Fprintln(os.Stdout)
-	}
+	fmt.Fprintln(os.Stdout, passwd)
 	return nil
 }
 
@@ -379,6 +754,7 @@ func runModifyPasswd(cmd *cli.Command, args []string) error {
 	cmd.Flag.StringVar(&client.User, "u", "", "user")
 	cmd.Flag.StringVar(&client.Pass, "p", "", "password")
 	cmd.Flag.BoolVar(&client.TLS, "z", false, "start tls")
+	client.TLSFlags(&cmd.Flag)
 	if err := cmd.Flag.Parse(args); err != nil {
 		return err
 	}
@@ -411,6 +787,7 @@ func runBind(cmd *cli.Command, args []string) error {
 	cmd.Flag.StringVar(&client.User, "u", "", "user")
 	cmd.Flag.StringVar(&client.Pass, "p", "", "password")
 	cmd.Flag.BoolVar(&client.TLS, "z", false, "start tls")
+	client.TLSFlags(&cmd.Flag)
 	if err := cmd.Flag.Parse(args); err != nil {
 		return err
 	}
@@ -431,6 +808,8 @@ func runWhoami(cmd *cli.Command, args []string) error {
 	cmd.Flag.StringVar(&client.User, "u", "", "user")
 	cmd.Flag.StringVar(&client.Pass, "p", "", "password")
 	cmd.Flag.BoolVar(&client.TLS, "z", false, "start tls")
+	client.TLSFlags(&cmd.Flag)
+	cmd.Flag.BoolVar(&client.JSON, "j", false, "json output")
 	if err := cmd.Flag.Parse(args); err != nil {
 		return err
 	}
@@ -440,71 +819,732 @@ func runWhoami(cmd *cli.Command, args []string) error {
 	}
 	defer client.Unbind()
 	who, _, err := client.Whoami(filter.Control())
-	if err == nil {
-		fmt.Fprintln(os.Stdout, strings.TrimPrefix(who, "dn:"))
+	if err != nil {
+		return err
 	}
-	return err
+	st := statusOf("whoami", "", nil)
+	st.Value = strings.TrimPrefix(who, "dn:")
+	st.text = strings.TrimPrefix(who, "dn:")
+	return client.Output().Status(st)
 }
 
-func runExec(cmd *cli.Command, args []string) error {
+func runPing(cmd *cli.Command, args []string) error {
 	var (
-		client Client
-		filter Filter
-		tx     bool
+		client   Client
+		timeout  time.Duration
+		interval time.Duration
+		count    int
+		failed   int
 	)
-	cmd.Flag.Var(&filter, "f", "assertion filter")
 	cmd.Flag.StringVar(&client.Addr, "r", "localhost:389", "remote host")
-	cmd.Flag.StringVar(&client.User, "u", "", "user")
-	cmd.Flag.StringVar(&client.Pass, "p", "", "password")
-	cmd.Flag.BoolVar(&client.TLS, "z", false, "start tls")
-	cmd.Flag.BoolVar(&tx, "t", tx, "execute operation(s) in a transaction")
+	cmd.Flag.DurationVar(&timeout, "t", 5*time.Second, "timeout of each probe")
+	cmd.Flag.IntVar(&count, "c", 1, "number of probes")
+	cmd.Flag.DurationVar(&interval, "i", time.Second, "interval between probes")
+	cmd.Flag.BoolVar(&client.JSON, "j", false, "json output")
 	if err := cmd.Flag.Parse(args); err != nil {
 		return err
 	}
-
-	if err := client.Bind(); err != nil {
+	addr, err := parseURI(client.Addr)
+	if err != nil {
 		return err
 	}
-	defer client.Unbind()
-	var err error
-	if tx {
-		err = client.Begin()
+	for i := 0; i < count; i++ {
+		if i > 0 {
+			time.Sleep(interval)
+		}
+		res, err := ldap.Ping(addr, timeout)
+		st := statusOf("ping", addr, err)
+		st.Value = res
 		if err != nil {
+			failed++
+			st.text = fmt.Sprintf("%s: unreachable: %s", addr, err)
+		} else {
+			starttls := "unavailable"
+			if res.StartTLS {
+				starttls = "available"
+			}
+			st.text = fmt.Sprintf("%s: reachable, starttls %s, connect=%s, rtt=%s", addr, starttls, res.Connect, res.Latency)
+		}
+		if err := client.Output().Status(st); err != nil {
 			return err
 		}
 	}
-	if cmd.Flag.NArg() == 0 {
-		err = client.ExecFromReader(os.Stdin)
-	} else {
-		err = client.ExecFromFile(cmd.Flag.Arg(0))
-	}
-	if tx {
-		if err == nil {
-			err = client.Commit()
-		} else {
-			err = client.Rollback()
-		}
+	if failed > 0 {
+		return fmt.Errorf("%s: %d/%d probe(s) failed", addr, failed, count)
 	}
-	return err
+	return nil
 }
 
-func runMove(cmd *cli.Command, args []string) error {
+func runExpire(cmd *cli.Command, args []string) error {
 	var (
-		client Client
-		filter Filter
+		client  Client
+		scope   Scope
+		disable Replace
+		undo    string
+		rt      ldap.Retention
 	)
-	cmd.Flag.Var(&filter, "f", "assertion filter")
+	scope.Scope = ldap.ScopeWhole
+	cmd.Flag.Var(&scope, "s", "scope")
+	cmd.Flag.Var(&disable, "x", "disable entries by replacing attribute (attr=value) instead of deleting")
+	cmd.Flag.StringVar(&rt.Attr, "a", "createTimestamp", "timestamp attribute")
+	cmd.Flag.DurationVar(&rt.Age, "d", 0, "minimum age of entries")
+	cmd.Flag.BoolVar(&rt.DryRun, "n", false, "dry run")
+	cmd.Flag.IntVar(&rt.PauseEvery, "b", 0, "pause after every n entries")
+	cmd.Flag.DurationVar(&rt.Pause, "w", 0, "length of the pause")
+	cmd.Flag.IntVar(&rt.Rate, "l", 0, "maximum number of operations per second")
+	cmd.Flag.StringVar(&undo, "o", "", "write undo ldif to file")
 	cmd.Flag.StringVar(&client.Addr, "r", "localhost:389", "remote host")
 	cmd.Flag.StringVar(&client.User, "u", "", "user")
 	cmd.Flag.StringVar(&client.Pass, "p", "", "password")
 	cmd.Flag.BoolVar(&client.TLS, "z", false, "start tls")
+	client.TLSFlags(&cmd.Flag)
 	if err := cmd.Flag.Parse(args); err != nil {
 		return err
 	}
-
-	if err := client.Bind(); err != nil {
-		return err
-	}
+	if cmd.Flag.NArg() > 1 {
+		filter, err := ldap.ParseFilter(cmd.Flag.Arg(1))
+		if err != nil {
+			return err
+		}
+		rt.Filter = filter
+	}
+	rt.Scope = scope.Scope
+	rt.Disable = disable.Attrs
+	if undo != "" {
+		w, err := os.Create(undo)
+		if err != nil {
+			return err
+		}
+		defer w.Close()
+		rt.Undo = w
+	}
+
+	if err := client.Bind(); err != nil {
+		return err
+	}
+	defer client.Unbind()
+
+	dns, err := client.Expire(cmd.Flag.Arg(0), rt)
+	for _, dn := range dns {
+		fmt.Fprintln(os.Stdout, dn)
+	}
+	return err
+}
+
+func runRewrite(cmd *cli.Command, args []string) error {
+	var (
+		client Client
+		attr   Attributes
+		undo   string
+		rw     ldap.Rewrite
+	)
+	cmd.Flag.Var(&attr, "a", "dn-valued attributes to rewrite")
+	cmd.Flag.BoolVar(&rw.DryRun, "n", false, "dry run")
+	cmd.Flag.IntVar(&rw.Batch, "b", 0, "number of entries per batch")
+	cmd.Flag.DurationVar(&rw.Pause, "w", 0, "pause between batches")
+	cmd.Flag.StringVar(&undo, "o", "", "write undo ldif to file")
+	cmd.Flag.StringVar(&client.Addr, "r", "localhost:389", "remote host")
+	cmd.Flag.StringVar(&client.User, "u", "", "user")
+	cmd.Flag.StringVar(&client.Pass, "p", "", "password")
+	cmd.Flag.BoolVar(&client.TLS, "z", false, "start tls")
+	client.TLSFlags(&cmd.Flag)
+	if err := cmd.Flag.Parse(args); err != nil {
+		return err
+	}
+	if cmd.Flag.NArg() != 3 {
+		return fmt.Errorf("base, old and new dn expected")
+	}
+	rw.Attrs = attr.Attrs
+	if undo != "" {
+		w, err := os.Create(undo)
+		if err != nil {
+			return err
+		}
+		defer w.Close()
+		rw.Undo = w
+	}
+
+	if err := client.Bind(); err != nil {
+		return err
+	}
+	defer client.Unbind()
+
+	dns, err := client.RewriteReferences(cmd.Flag.Arg(0), cmd.Flag.Arg(1), cmd.Flag.Arg(2), rw)
+	for _, dn := range dns {
+		fmt.Fprintln(os.Stdout, dn)
+	}
+	return err
+}
+
+func runExport(cmd *cli.Command, args []string) error {
+	var (
+		client Client
+		attr   Attributes
+		scope  = Scope{Scope: ldap.ScopeWhole}
+		file   string
+		quiet  bool
+		ex     ldap.Export
+	)
+	cmd.Flag.Var(&attr, "a", "attributes")
+	cmd.Flag.Var(&scope, "s", "scope")
+	cmd.Flag.IntVar(&ex.Page, "n", 0, "number of entries per page")
+	cmd.Flag.StringVar(&file, "o", "", "output ldif file")
+	cmd.Flag.StringVar(&file, "out", "", "output ldif file")
+	cmd.Flag.StringVar(&ex.State, "c", "", "checkpoint file (default to <output>.state)")
+	cmd.Flag.BoolVar(&quiet, "q", false, "do not report progress")
+	cmd.Flag.StringVar(&client.Addr, "r", "localhost:389", "remote host")
+	cmd.Flag.StringVar(&client.User, "u", "", "user")
+	cmd.Flag.StringVar(&client.Pass, "p", "", "password")
+	cmd.Flag.BoolVar(&client.TLS, "z", false, "start tls")
+	client.TLSFlags(&cmd.Flag)
+	if err := cmd.Flag.Parse(args); err != nil {
+		return err
+	}
+	if cmd.Flag.NArg() > 1 {
+		filter, err := ldap.ParseFilter(cmd.Flag.Arg(1))
+		if err != nil {
+			return err
+		}
+		ex.Filter = filter
+	}
+	if len(attr.Attrs) == 0 {
+		attr.Attrs = []string{"*", "+"}
+	}
+	ex.Options = append(attr.Option(), scope.Option())
+	if !quiet {
+		ex.Progress = func(cp ldap.Checkpoint) {
+			fmt.Fprintf(os.Stderr, "\r%d entries exported", cp.Count)
+		}
+	}
+
+	var w io.Writer = os.Stdout
+	if file != "" {
+		if ex.State == "" {
+			ex.State = file + ".state"
+		}
+		cp, err := ldap.ReadCheckpoint(ex.State)
+		if err != nil {
+			return err
+		}
+		f, err := os.OpenFile(file, os.O_CREATE|os.O_WRONLY, 0o644)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		if err := f.Truncate(cp.Offset); err != nil {
+			return err
+		}
+		if _, err := f.Seek(cp.Offset, io.SeekStart); err != nil {
+			return err
+		}
+		w = f
+	} else if ex.State != "" {
+		return fmt.Errorf("checkpoint requires an output file")
+	}
+
+	if err := client.Bind(); err != nil {
+		return err
+	}
+	defer client.Unbind()
+
+	base := cmd.Flag.Arg(0)
+	if base == "" {
+		b, err := client.DefaultBase()
+		if err != nil {
+			return err
+		}
+		base = b
+	}
+	cp, err := client.Export(w, base, ex)
+	fmt.Fprintf(os.Stderr, "\r%d entries exported\n", cp.Count)
+	return err
+}
+
+func runPlan(cmd *cli.Command, args []string) error {
+	var (
+		client Client
+		file   string
+	)
+	cmd.Flag.StringVar(&file, "o", "", "write plan to ldif file")
+	cmd.Flag.StringVar(&client.Addr, "r", "localhost:389", "remote host")
+	cmd.Flag.StringVar(&client.User, "u", "", "user")
+	cmd.Flag.StringVar(&client.Pass, "p", "", "password")
+	cmd.Flag.BoolVar(&client.TLS, "z", false, "start tls")
+	client.TLSFlags(&cmd.Flag)
+	if err := cmd.Flag.Parse(args); err != nil {
+		return err
+	}
+	declared, err := readDeclared(cmd.Flag.Arg(0))
+	if err != nil {
+		return err
+	}
+	var w io.Writer = os.Stdout
+	if file != "" {
+		f, err := os.Create(file)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+
+	if err := client.Bind(); err != nil {
+		return err
+	}
+	defer client.Unbind()
+
+	n, err := ldap.Plan(client, declared, ldap.NewLDIFWriter(w).WriteChange)
+	if err == nil {
+		fmt.Fprintf(os.Stderr, "%d change(s) planned\n", n)
+	}
+	return err
+}
+
+func runDrift(cmd *cli.Command, args []string) error {
+	var client Client
+	cmd.Flag.StringVar(&client.Addr, "r", "localhost:389", "remote host")
+	cmd.Flag.StringVar(&client.User, "u", "", "user")
+	cmd.Flag.StringVar(&client.Pass, "p", "", "password")
+	cmd.Flag.BoolVar(&client.TLS, "z", false, "start tls")
+	client.TLSFlags(&cmd.Flag)
+	cmd.Flag.BoolVar(&client.JSON, "j", false, "json output")
+	if err := cmd.Flag.Parse(args); err != nil {
+		return err
+	}
+	declared, err := readDeclared(cmd.Flag.Arg(0))
+	if err != nil {
+		return err
+	}
+
+	if err := client.Bind(); err != nil {
+		return err
+	}
+	defer client.Unbind()
+
+	n, err := ldap.Plan(client, declared, func(ct ldap.ChangeType, cg ldap.Change) error {
+		st := statusOf("drift", cg.Name, nil)
+		if ct == ldap.ModAdd {
+			st.Value = "missing"
+			st.text = fmt.Sprintf("+ %s", cg.Name)
+		} else {
+			var attrs []string
+			for _, a := range cg.Attrs {
+				attrs = append(attrs, a.Name)
+			}
+			st.Value = attrs
+			st.text = fmt.Sprintf("~ %s (%s)", cg.Name, strings.Join(attrs, ", "))
+		}
+		return client.Output().Status(st)
+	})
+	if err == nil && n > 0 {
+		err = fmt.Errorf("%d entries drifted from %s", n, cmd.Flag.Arg(0))
+	}
+	return err
+}
+
+func runSchema(cmd *cli.Command, args []string) error {
+	var client Client
+	cmd.Flag.StringVar(&client.Addr, "r", "localhost:389", "remote host")
+	cmd.Flag.StringVar(&client.User, "u", "", "user")
+	cmd.Flag.StringVar(&client.Pass, "p", "", "password")
+	cmd.Flag.BoolVar(&client.TLS, "z", false, "start tls")
+	client.TLSFlags(&cmd.Flag)
+	cmd.Flag.BoolVar(&client.JSON, "j", false, "json output")
+	if err := cmd.Flag.Parse(args); err != nil {
+		return err
+	}
+	var kind, name string
+	switch cmd.Flag.NArg() {
+	case 1:
+		name = cmd.Flag.Arg(0)
+	case 2:
+		kind, name = cmd.Flag.Arg(0), cmd.Flag.Arg(1)
+	default:
+		return fmt.Errorf("name of attribute type or object class should be given")
+	}
+	if err := client.Bind(); err != nil {
+		return err
+	}
+	defer client.Unbind()
+
+	schema, err := client.Schema()
+	if err != nil {
+		return err
+	}
+	var found bool
+	if kind == "" || kind == "attr" {
+		if at, ok := schema.AttributeType(name); ok {
+			found = true
+			if err := client.Output().Status(describeAttribute(schema, at)); err != nil {
+				return err
+			}
+		}
+	}
+	if kind == "" || kind == "class" {
+		if oc, ok := schema.ObjectClass(name); ok {
+			found = true
+			if err := client.Output().Status(describeClass(schema, oc)); err != nil {
+				return err
+			}
+		}
+	}
+	if kind != "" && kind != "attr" && kind != "class" {
+		return fmt.Errorf("%s: unknown kind (use attr or class)", kind)
+	}
+	if !found {
+		return fmt.Errorf("%s: not found in schema", name)
+	}
+	return nil
+}
+
+func describeAttribute(schema *ldap.Schema, at ldap.AttributeType) Status {
+	var (
+		chain    []string
+		syntax   = at.Syntax
+		length   = at.Length
+		equality = at.Equality
+		ordering = at.Ordering
+		substr   = at.Substr
+	)
+	for _, sup := range schema.Supertypes(at.OID) {
+		chain = append(chain, sup.Name())
+		if syntax == "" {
+			syntax, length = sup.Syntax, sup.Length
+		}
+		if equality == "" {
+			equality = sup.Equality
+		}
+		if ordering == "" {
+			ordering = sup.Ordering
+		}
+		if substr == "" {
+			substr = sup.Substr
+		}
+	}
+	desc := syntax
+	if sx, ok := schema.Syntax(syntax); ok && sx.Desc != "" {
+		desc = fmt.Sprintf("%s (%s)", syntax, sx.Desc)
+	}
+	if length > 0 {
+		desc = fmt.Sprintf("%s{%d}", desc, length)
+	}
+	lines := []string{
+		fmt.Sprintf("attribute type %s (%s)", at.Name(), at.OID),
+		fmt.Sprintf("  names       : %s", strings.Join(at.Names, ", ")),
+		fmt.Sprintf("  description : %s", at.Desc),
+		fmt.Sprintf("  inheritance : %s", strings.Join(chain, " > ")),
+		fmt.Sprintf("  syntax      : %s", desc),
+		fmt.Sprintf("  equality    : %s", equality),
+		fmt.Sprintf("  ordering    : %s", ordering),
+		fmt.Sprintf("  substring   : %s", substr),
+		fmt.Sprintf("  single-value: %t", at.SingleValue),
+		fmt.Sprintf("  usage       : %s", at.Usage),
+	}
+	if at.Obsolete {
+		lines = append(lines, "  obsolete")
+	}
+	st := statusOf("schema", at.Name(), nil)
+	st.Value = map[string]interface{}{
+		"type":        "attributeType",
+		"definition":  at.String(),
+		"inheritance": chain,
+		"syntax":      syntax,
+		"equality":    equality,
+		"ordering":    ordering,
+		"substring":   substr,
+	}
+	st.text = strings.Join(lines, "\n")
+	return st
+}
+
+func describeClass(schema *ldap.Schema, oc ldap.ObjectClass) Status {
+	var (
+		chain []string
+		must  []string
+		may   []string
+	)
+	for _, sup := range schema.Superclasses(oc.OID) {
+		chain = append(chain, sup.Name())
+		for _, m := range sup.Must {
+			must = append(must, fmt.Sprintf("%s (%s)", m, sup.Name()))
+		}
+		for _, m := range sup.May {
+			may = append(may, fmt.Sprintf("%s (%s)", m, sup.Name()))
+		}
+	}
+	lines := []string{
+		fmt.Sprintf("object class %s (%s) %s", oc.Name(), oc.OID, oc.Kind),
+		fmt.Sprintf("  names       : %s", strings.Join(oc.Names, ", ")),
+		fmt.Sprintf("  description : %s", oc.Desc),
+		fmt.Sprintf("  inheritance : %s", strings.Join(chain, " > ")),
+		fmt.Sprintf("  must        : %s", strings.Join(must, ", ")),
+		fmt.Sprintf("  may         : %s", strings.Join(may, ", ")),
+	}
+	if oc.Obsolete {
+		lines = append(lines, "  obsolete")
+	}
+	st := statusOf("schema", oc.Name(), nil)
+	st.Value = map[string]interface{}{
+		"type":        "objectClass",
+		"definition":  oc.String(),
+		"kind":        oc.Kind.String(),
+		"inheritance": chain,
+		"must":        must,
+		"may":         may,
+	}
+	st.text = strings.Join(lines, "\n")
+	return st
+}
+
+func runSchemaDiff(cmd *cli.Command, args []string) error {
+	var client Client
+	cmd.Flag.StringVar(&client.User, "u", "", "user")
+	cmd.Flag.StringVar(&client.Pass, "p", "", "password")
+	cmd.Flag.BoolVar(&client.TLS, "z", false, "start tls")
+	client.TLSFlags(&cmd.Flag)
+	cmd.Flag.BoolVar(&client.JSON, "j", false, "json output")
+	if err := cmd.Flag.Parse(args); err != nil {
+		return err
+	}
+	if cmd.Flag.NArg() != 2 {
+		return fmt.Errorf("source and target subschemas should be given")
+	}
+	source, err := loadSchema(client, cmd.Flag.Arg(0))
+	if err != nil {
+		return err
+	}
+	target, err := loadSchema(client, cmd.Flag.Arg(1))
+	if err != nil {
+		return err
+	}
+	changes := ldap.DiffSchema(source, target)
+	for _, c := range changes {
+		st := statusOf("schema-diff", c.Name, nil)
+		st.Value = c
+		switch {
+		case c.Removed():
+			st.text = fmt.Sprintf("- %s %s\n  %s", c.Kind, c.Name, c.Left)
+		case c.Added():
+			st.text = fmt.Sprintf("+ %s %s\n  %s", c.Kind, c.Name, c.Right)
+		default:
+			st.text = fmt.Sprintf("~ %s %s\n  - %s\n  + %s", c.Kind, c.Name, c.Left, c.Right)
+		}
+		if err := client.Output().Status(st); err != nil {
+			return err
+		}
+	}
+	if len(changes) > 0 {
+		return fmt.Errorf("%d schema definitions differ", len(changes))
+	}
+	return nil
+}
+
+func runGenerate(cmd *cli.Command, args []string) error {
+	var (
+		client Client
+		pkg    = "main"
+		file   string
+	)
+	cmd.Flag.StringVar(&client.User, "u", "", "user")
+	cmd.Flag.StringVar(&client.Pass, "p", "", "password")
+	cmd.Flag.BoolVar(&client.TLS, "z", false, "start tls")
+	client.TLSFlags(&cmd.Flag)
+	cmd.Flag.StringVar(&pkg, "k", pkg, "package name")
+	cmd.Flag.StringVar(&file, "o", "", "output file")
+	if err := cmd.Flag.Parse(args); err != nil {
+		return err
+	}
+	if cmd.Flag.NArg() < 2 {
+		return fmt.Errorf("subschema and object classes should be given")
+	}
+	schema, err := loadSchema(client, cmd.Flag.Arg(0))
+	if err != nil {
+		return err
+	}
+	w := io.Writer(os.Stdout)
+	if file != "" {
+		f, err := os.Create(file)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		w = f
+	}
+	return ldap.GenerateStructs(w, schema, pkg, cmd.Flag.Args()[1:]...)
+}
+
+func loadSchema(client Client, source string) (*ldap.Schema, error) {
+	if _, err := os.Stat(source); err == nil {
+		es, err := readDeclared(source)
+		if err != nil {
+			return nil, err
+		}
+		if len(es) == 0 {
+			return nil, fmt.Errorf("%s: no subschema entry found", source)
+		}
+		return ldap.ParseSchema(es[0])
+	}
+	client.Addr = source
+	if err := client.Bind(); err != nil {
+		return nil, err
+	}
+	defer client.Unbind()
+	return client.Schema()
+}
+
+func readDeclared(file string) ([]ldap.Entry, error) {
+	r, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+
+	var es []ldap.Entry
+	err = ldap.ReadLDIF(r, func(ct ldap.ChangeType, cg ldap.Change) error {
+		if ct != ldap.ModAdd {
+			return fmt.Errorf("%s: only entries can be declared", cg.Name)
+		}
+		e := ldap.Entry{Name: cg.Name}
+		for _, a := range cg.Attrs {
+			e.Attrs = append(e.Attrs, a.Attribute)
+		}
+		es = append(es, e)
+		return nil
+	}, localURLs)
+	return es, err
+}
+
+func runExec(cmd *cli.Command, args []string) error {
+	var (
+		client Client
+		filter Filter
+		tx     bool
+		dry    bool
+		strict bool
+		file   string
+	)
+	cmd.Flag.Var(&filter, "f", "assertion filter")
+	cmd.Flag.StringVar(&file, "o", "", "write applied changes to ldif file")
+	cmd.Flag.BoolVar(&dry, "n", false, "validate ldif and print the operations without executing them")
+	cmd.Flag.BoolVar(&strict, "s", false, "validate entries against the server schema")
+	cmd.Flag.StringVar(&client.Addr, "r", "localhost:389", "remote host")
+	cmd.Flag.StringVar(&client.User, "u", "", "user")
+	cmd.Flag.StringVar(&client.Pass, "p", "", "password")
+	cmd.Flag.BoolVar(&client.TLS, "z", false, "start tls")
+	client.TLSFlags(&cmd.Flag)
+	cmd.Flag.BoolVar(&client.JSON, "j", false, "json output")
+	cmd.Flag.BoolVar(&tx, "t", tx, "execute operation(s) in a transaction")
+	cmd.Flag.BoolVar(&client.Continue, "c", false, "continue on error and print a summary")
+	cmd.Flag.IntVar(&client.Workers, "w", 1, "number of connections applying independent changes concurrently")
+	if err := cmd.Flag.Parse(args); err != nil {
+		return err
+	}
+	if dry {
+		var schema *ldap.Schema
+		if strict {
+			if err := client.Bind(); err != nil {
+				return err
+			}
+			defer client.Unbind()
+
+			s, err := client.Schema()
+			if err != nil {
+				return err
+			}
+			schema = s
+		}
+		return dryRun(cmd.Flag.Arg(0), schema)
+	}
+	if file != "" {
+		w, err := os.Create(file)
+		if err != nil {
+			return err
+		}
+		defer w.Close()
+		client.log = ldap.NewLDIFWriter(w)
+	}
+
+	if tx && client.Workers > 1 {
+		return fmt.Errorf("transaction can not be used with concurrent workers")
+	}
+
+	if err := client.Bind(); err != nil {
+		return err
+	}
+	defer client.Unbind()
+	var err error
+	if tx {
+		err = client.Begin()
+		if err != nil {
+			return err
+		}
+	}
+	if cmd.Flag.NArg() == 0 {
+		err = client.ExecFromReader(os.Stdin)
+	} else {
+		err = client.ExecFromFile(cmd.Flag.Arg(0))
+	}
+	if tx {
+		if err == nil {
+			err = client.Commit()
+		} else {
+			err = client.Rollback()
+		}
+	}
+	return err
+}
+
+func dryRun(file string, schema *ldap.Schema) error {
+	r := io.Reader(os.Stdin)
+	if file != "" && file != "-" {
+		f, err := os.Open(file)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		r = f
+	}
+	buf, err := ioutil.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	if err := validateLDIF(bytes.NewReader(buf), schema); err != nil {
+		return err
+	}
+	w := ldap.NewLDIFWriter(os.Stdout)
+	return ldap.ReadLDIF(bytes.NewReader(buf), w.WriteChange, localURLs)
+}
+
+func validateLDIF(r io.Reader, schema *ldap.Schema) error {
+	err := ldap.ValidateLDIF(r, schema)
+	var errs ldap.LDIFErrors
+	if !errors.As(err, &errs) {
+		return err
+	}
+	for _, e := range errs {
+		fmt.Fprintln(os.Stderr, e)
+	}
+	return fmt.Errorf("%d problem(s) found", len(errs))
+}
+
+func runMove(cmd *cli.Command, args []string) error {
+	var (
+		client Client
+		filter Filter
+	)
+	cmd.Flag.Var(&filter, "f", "assertion filter")
+	cmd.Flag.StringVar(&client.Addr, "r", "localhost:389", "remote host")
+	cmd.Flag.StringVar(&client.User, "u", "", "user")
+	cmd.Flag.StringVar(&client.Pass, "p", "", "password")
+	cmd.Flag.BoolVar(&client.TLS, "z", false, "start tls")
+	client.TLSFlags(&cmd.Flag)
+	if err := cmd.Flag.Parse(args); err != nil {
+		return err
+	}
+
+	if err := client.Bind(); err != nil {
+		return err
+	}
 	defer client.Unbind()
 
 	_, err := client.Move(cmd.Flag.Arg(0), cmd.Flag.Arg(1), filter.Control())
@@ -523,6 +1563,7 @@ func runRename(cmd *cli.Command, args []string) error {
 	cmd.Flag.StringVar(&client.Pass, "p", "", "password")
 	cmd.Flag.BoolVar(&keep, "k", false, "keep old rdn")
 	cmd.Flag.BoolVar(&client.TLS, "z", false, "start tls")
+	client.TLSFlags(&cmd.Flag)
 	if err := cmd.Flag.Parse(args); err != nil {
 		return err
 	}
@@ -536,7 +1577,103 @@ func runRename(cmd *cli.Command, args []string) error {
 	return err
 }
 
-func runDelete(cmd *cli.Command, args []string) error {
+func runAdd(cmd *cli.Command, args []string) error {
+	var (
+		client Client
+		file   string
+	)
+	cmd.Flag.StringVar(&file, "f", "", "read attr=value pairs from file (one per line)")
+	cmd.Flag.StringVar(&client.Addr, "r", "localhost:389", "remote host")
+	cmd.Flag.StringVar(&client.User, "u", "", "user")
+	cmd.Flag.StringVar(&client.Pass, "p", "", "password")
+	cmd.Flag.BoolVar(&client.TLS, "z", false, "start tls")
+	client.TLSFlags(&cmd.Flag)
+	cmd.Flag.BoolVar(&client.JSON, "j", false, "json output")
+	if err := cmd.Flag.Parse(args); err != nil {
+		return err
+	}
+	if cmd.Flag.NArg() == 0 {
+		return fmt.Errorf("dn of entry should be given")
+	}
+	pairs := cmd.Flag.Args()[1:]
+	if file != "" {
+		lines, err := readPairs(file)
+		if err != nil {
+			return err
+		}
+		pairs = append(lines, pairs...)
+	}
+	var attrs []ldap.Attribute
+	for _, p := range pairs {
+		x := strings.IndexByte(p, '=')
+		if x <= 0 {
+			return fmt.Errorf("%s: invalid pair (expected attr=value)", p)
+		}
+		attrs = appendPair(attrs, strings.TrimSpace(p[:x]), p[x+1:])
+	}
+	if len(attrs) == 0 {
+		return fmt.Errorf("%s: no attributes given", cmd.Flag.Arg(0))
+	}
+
+	if err := client.Bind(); err != nil {
+		return err
+	}
+	defer client.Unbind()
+
+	dn := cmd.Flag.Arg(0)
+	_, err := client.Add(dn, attrs)
+	st := statusOf("add", dn, err)
+	if err != nil {
+		st.text = fmt.Sprintf("fail to add %s: %s", dn, err)
+	}
+	if err := client.Output().Status(st); err != nil {
+		return err
+	}
+	return err
+}
+
+func readPairs(file string) ([]string, error) {
+	var (
+		r   = io.Reader(os.Stdin)
+		buf []byte
+		err error
+	)
+	if file != "-" {
+		f, err := os.Open(file)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		r = f
+	}
+	if buf, err = ioutil.ReadAll(r); err != nil {
+		return nil, err
+	}
+	var pairs []string
+	for _, line := range strings.Split(string(buf), "\n") {
+		line = strings.TrimRight(line, "\r")
+		if strings.TrimSpace(line) == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		pairs = append(pairs, line)
+	}
+	return pairs, nil
+}
+
+func appendPair(attrs []ldap.Attribute, name, value string) []ldap.Attribute {
+	for i := range attrs {
+		if strings.EqualFold(attrs[i].Name, name) {
+			attrs[i].Values = append(attrs[i].Values, value)
+			return attrs
+		}
+	}
+	return append(attrs, ldap.Attribute{
+		Name:   name,
+		Values: []string{value},
+	})
+}
+
+func runModify(cmd *cli.Command, args []string) error {
 	var (
 		client Client
 		filter Filter
@@ -546,6 +1683,102 @@ func runDelete(cmd *cli.Command, args []string) error {
 	cmd.Flag.StringVar(&client.User, "u", "", "user")
 	cmd.Flag.StringVar(&client.Pass, "p", "", "password")
 	cmd.Flag.BoolVar(&client.TLS, "z", false, "start tls")
+	client.TLSFlags(&cmd.Flag)
+	cmd.Flag.BoolVar(&client.JSON, "j", false, "json output")
+	if err := cmd.Flag.Parse(args); err != nil {
+		return err
+	}
+	if cmd.Flag.NArg() < 2 {
+		return fmt.Errorf("dn and at least one operation should be given")
+	}
+	changes, err := parseOperations(cmd.Flag.Args()[1:])
+	if err != nil {
+		return err
+	}
+
+	if err := client.Bind(); err != nil {
+		return err
+	}
+	defer client.Unbind()
+
+	dn := cmd.Flag.Arg(0)
+	_, err = client.Modify(dn, changes, filter.Control())
+	st := statusOf("modify", dn, err)
+	if err != nil {
+		st.text = fmt.Sprintf("fail to modify %s: %s", dn, err)
+	}
+	if err := client.Output().Status(st); err != nil {
+		return err
+	}
+	return err
+}
+
+func parseOperations(args []string) ([]ldap.PartialAttribute, error) {
+	var changes []ldap.PartialAttribute
+	for _, a := range args {
+		x := strings.IndexByte(a, ':')
+		if x <= 0 {
+			return nil, fmt.Errorf("%s: invalid operation (expected op:attr[=value])", a)
+		}
+		var mod ldap.ChangeType
+		switch op := strings.ToLower(a[:x]); op {
+		case "add":
+			mod = ldap.ModAdd
+		case "replace":
+			mod = ldap.ModReplace
+		case "delete":
+			mod = ldap.ModDelete
+		case "increment":
+			mod = ldap.ModIncrement
+		default:
+			return nil, fmt.Errorf("%s: unknown operation", op)
+		}
+		var (
+			name  = a[x+1:]
+			value string
+			ok    bool
+		)
+		if y := strings.IndexByte(name, '='); y >= 0 {
+			name, value, ok = name[:y], name[y+1:], true
+		}
+		if name == "" {
+			return nil, fmt.Errorf("%s: missing attribute name", a)
+		}
+		if !ok && mod != ldap.ModDelete && mod != ldap.ModReplace {
+			return nil, fmt.Errorf("%s: value required", a)
+		}
+		if n := len(changes) - 1; n >= 0 && changes[n].Mod == mod && strings.EqualFold(changes[n].Name, name) {
+			if ok {
+				changes[n].Values = append(changes[n].Values, value)
+			}
+			continue
+		}
+		pa := ldap.PartialAttribute{Mod: mod}
+		pa.Name = name
+		if ok {
+			pa.Values = append(pa.Values, value)
+		}
+		changes = append(changes, pa)
+	}
+	return changes, nil
+}
+
+func runDelete(cmd *cli.Command, args []string) error {
+	var (
+		client    Client
+		filter    Filter
+		recursive bool
+		force     bool
+	)
+	cmd.Flag.Var(&filter, "f", "assertion filter")
+	cmd.Flag.BoolVar(&recursive, "R", false, "delete entries and their subtree")
+	cmd.Flag.BoolVar(&force, "force", false, "delete subtree even if it contains aliases or referrals")
+	cmd.Flag.StringVar(&client.Addr, "r", "localhost:389", "remote host")
+	cmd.Flag.StringVar(&client.User, "u", "", "user")
+	cmd.Flag.StringVar(&client.Pass, "p", "", "password")
+	cmd.Flag.BoolVar(&client.TLS, "z", false, "start tls")
+	client.TLSFlags(&cmd.Flag)
+	cmd.Flag.BoolVar(&client.JSON, "j", false, "json output")
 	if err := cmd.Flag.Parse(args); err != nil {
 		return err
 	}
@@ -556,9 +1789,19 @@ func runDelete(cmd *cli.Command, args []string) error {
 	defer client.Unbind()
 
 	for _, a := range cmd.Flag.Args() {
-		if _, err := client.Delete(a, filter.Control()); err != nil {
-			fmt.Fprintf(os.Stderr, "fail to delete %s: %s", a, err)
-			fmt.Fprintln(os.Stderr)
+		if recursive {
+			if err := client.DeleteTree(a, force, filter.Control()); err != nil {
+				return err
+			}
+			continue
+		}
+		_, err := client.Delete(a, filter.Control())
+		st := statusOf("delete", a, err)
+		if err != nil {
+			st.text = fmt.Sprintf("fail to delete %s: %s", a, err)
+		}
+		if err := client.Output().Status(st); err != nil {
+			return err
 		}
 	}
 	return nil
@@ -574,6 +1817,8 @@ func runCompare(cmd *cli.Command, args []string) error {
 	cmd.Flag.StringVar(&client.User, "u", "", "user")
 	cmd.Flag.StringVar(&client.Pass, "p", "", "password")
 	cmd.Flag.BoolVar(&client.TLS, "z", false, "start tls")
+	client.TLSFlags(&cmd.Flag)
+	cmd.Flag.BoolVar(&client.JSON, "j", false, "json output")
 	if err := cmd.Flag.Parse(args); err != nil {
 		return err
 	}
@@ -592,12 +1837,18 @@ func runCompare(cmd *cli.Command, args []string) error {
 		if err != nil {
 			return err
 		}
+		st := statusOf("compare", cmd.Flag.Arg(0), nil)
+		st.Value = ok
 		if ok {
-			fmt.Fprintf(os.Stdout, "TRUE:  %s", cmd.Flag.Arg(i))
+			st.Code = ldap.CompareTrue
+			st.text = fmt.Sprintf("TRUE:  %s", cmd.Flag.Arg(i))
 		} else {
-			fmt.Fprintf(os.Stdout, "FALSE: %s", cmd.Flag.Arg(i))
+			st.Code = ldap.CompareFalse
+			st.text = fmt.Sprintf("FALSE: %s", cmd.Flag.Arg(i))
+		}
+		if err := client.Output().Status(st); err != nil {
+			return err
 		}
-		fmt.Fprintln(os.Stdout)
 	}
 	return nil
 }
@@ -608,41 +1859,65 @@ func runSupported(cmd *cli.Command, args []string) error {
 		onlyExtension bool
 		onlyFeature   bool
 		onlyControl   bool
+		onlySASL      bool
+		onlyContexts  bool
+		onlyVendor    bool
 	)
 	cmd.Flag.BoolVar(&onlyExtension, "e", onlyExtension, "show list of supported extension")
 	cmd.Flag.BoolVar(&onlyControl, "c", onlyControl, "show list of supported controls")
 	cmd.Flag.BoolVar(&onlyFeature, "f", onlyFeature, "show list of supported features")
+	cmd.Flag.BoolVar(&onlySASL, "m", onlySASL, "show list of supported sasl mechanisms")
+	cmd.Flag.BoolVar(&onlyContexts, "n", onlyContexts, "show list of naming contexts")
+	cmd.Flag.BoolVar(&onlyVendor, "v", onlyVendor, "show supported ldap versions and vendor info")
 	cmd.Flag.StringVar(&client.Addr, "r", "localhost:389", "remote host")
 	cmd.Flag.StringVar(&client.User, "u", "", "user")
 	cmd.Flag.StringVar(&client.Pass, "p", "", "password")
 	cmd.Flag.BoolVar(&client.TLS, "z", false, "start tls")
+	client.TLSFlags(&cmd.Flag)
+	cmd.Flag.BoolVar(&client.JSON, "j", false, "json output")
 	if err := cmd.Flag.Parse(args); err != nil {
 		return err
 	}
 
-	all := !onlyFeature && !onlyExtension && !onlyControl
+	all := !onlyFeature && !onlyExtension && !onlyControl && !onlySASL && !onlyContexts && !onlyVendor
 
 	if err := client.Bind(); err != nil {
 		return err
 	}
 	defer client.Unbind()
 
-	var err error
+	dse, err := client.RootDSE()
+	if err != nil {
+		return err
+	}
+	out := client.Output()
+	if onlyVendor || all {
+		if err := PrintVendor(out, dse); err != nil {
+			return err
+		}
+	}
+	if onlyContexts || all {
+		if err := PrintFeatures(out, namingContexts, "N", dse.NamingContexts, nil); err != nil {
+			return err
+		}
+	}
+	if onlySASL || all {
+		if err := PrintFeatures(out, supportedSASL, "M", dse.SASLMechanisms, nil); err != nil {
+			return err
+		}
+	}
 	if onlyExtension || all {
-		err = client.SupportedExtensions()
-		if err != nil {
+		if err := PrintFeatures(out, supportedExtensions, "E", dse.Extensions, ldap.ExtensionNames); err != nil {
 			return err
 		}
 	}
 	if onlyFeature || all {
-		err = client.SupportedFeatures()
-		if err != nil {
+		if err := PrintFeatures(out, supportedFeatures, "F", dse.Features, ldap.FeatureNames); err != nil {
 			return err
 		}
 	}
 	if onlyControl || all {
-		err = client.SupportedControls()
-		if err != nil {
+		if err := PrintFeatures(out, supportedControls, "C", dse.Controls, ldap.ControlNames); err != nil {
 			return err
 		}
 	}
@@ -656,7 +1931,9 @@ func runSearch(cmd *cli.Command, args []string) error {
 		order  OrderBy
 		types  bool
 		limit  int
+		size   int
 		filter Filter
+		format string
 		client Client
 	)
 	cmd.Flag.Var(&filter, "f", "assertion filter")
@@ -665,13 +1942,28 @@ func runSearch(cmd *cli.Command, args []string) error {
 	cmd.Flag.Var(&order, "o", "sort")
 	cmd.Flag.BoolVar(&types, "t", false, "types only")
 	cmd.Flag.IntVar(&limit, "n", 0, "limit number of entries returned")
+	cmd.Flag.IntVar(&size, "m", 0, "truncate attribute values larger than given bytes")
 	cmd.Flag.StringVar(&client.Addr, "r", "localhost:389", "remote host")
 	cmd.Flag.StringVar(&client.User, "u", "", "user")
 	cmd.Flag.StringVar(&client.Pass, "p", "", "password")
 	cmd.Flag.BoolVar(&client.TLS, "z", false, "start tls")
+	client.TLSFlags(&cmd.Flag)
+	cmd.Flag.BoolVar(&client.JSON, "j", false, "json output")
+	cmd.Flag.StringVar(&format, "format", "ldif", "output format (ldif, json, csv, table)")
 	if err := cmd.Flag.Parse(args); err != nil {
 		return err
 	}
+	switch format {
+	case "ldif":
+	case "json":
+		client.JSON = true
+	case "csv":
+		client.out = newCSVFormatter(os.Stdout, os.Stderr, attr.Attrs)
+	case "table":
+		client.out = newTableFormatter(os.Stdout, os.Stderr, attr.Attrs)
+	default:
+		return fmt.Errorf("%s: unknown output format", format)
+	}
 
 	if err := client.Bind(); err != nil {
 		return err
@@ -681,6 +1973,7 @@ func runSearch(cmd *cli.Command, args []string) error {
 	options := []ldap.SearchOption{
 		ldap.WithTypes(types),
 		ldap.WithLimit(limit),
+		ldap.WithValueLimit(size),
 		scope.Option(),
 	}
 	if opt := order.Option(); opt != nil {
@@ -697,46 +1990,19 @@ func runSearch(cmd *cli.Command, args []string) error {
 		}
 		options = append(options, ldap.WithFilter(filter))
 	}
-	return client.Search(cmd.Flag.Arg(0), options)
-}
-
-func PrintFeatures(e ldap.Entry, attr, prefix string, names map[string]string) error {
-	sort.Slice(e.Attrs, func(i, j int) bool {
-		return e.Attrs[i].Name < e.Attrs[j].Name
-	})
-	x := sort.Search(len(e.Attrs), func(i int) bool {
-		return e.Attrs[i].Name >= attr
-	})
-	if x >= len(e.Attrs) || e.Attrs[x].Name != attr {
-		return fmt.Errorf("%s: attribute not found", attr)
-	}
-	if names == nil {
-		names = make(map[string]string)
-	}
-	for _, v := range e.Attrs[x].Values {
-		if str := names[v]; str != "" {
-			fmt.Printf("- %s: %s (%s)", prefix, str, v)
-		} else {
-			fmt.Printf("- %s: %s", prefix, v)
+	base := cmd.Flag.Arg(0)
+	if cmd.Flag.NArg() == 0 {
+		b, err := client.DefaultBase()
+		if err != nil {
+			return err
 		}
-		fmt.Println()
+		base = b
 	}
-	return nil
-}
-
-func PrintEntry(e ldap.Entry) {
-	fmt.Fprintf(os.Stdout, "dn: %s", e.Name)
-	if len(e.Attrs) == 0 {
-		return
+	if err := client.Search(base, options); err != nil {
+		return err
 	}
-	fmt.Fprintln(os.Stdout)
-	for _, a := range e.Attrs {
-		if len(a.Values) == 0 {
-			a.Values = append(a.Values, "")
-		}
-		for _, v := range a.Values {
-			fmt.Fprintf(os.Stdout, "%s: %s", a.Name, v)
-			fmt.Fprintln(os.Stdout)
-		}
+	if f, ok := client.Output().(flusher); ok {
+		return f.Flush()
 	}
+	return nil
 }
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/midbel/cli"
+	"github.com/midbel/ldap"
+)
+
+func runMonitor(cmd *cli.Command, args []string) error {
+	var (
+		client   Client
+		interval time.Duration
+		count    int
+	)
+	cmd.Flag.DurationVar(&interval, "interval", 0, "sample repeatedly at the given interval")
+	cmd.Flag.IntVar(&count, "n", 0, "number of samples to take with -interval (0 for unlimited)")
+	cmd.Flag.StringVar(&client.Addr, "r", "localhost:389", "remote host")
+	cmd.Flag.StringVar(&client.User, "u", "", "user")
+	cmd.Flag.StringVar(&client.Pass, "p", "", "password")
+	cmd.Flag.BoolVar(&client.TLS, "z", false, "start tls")
+	client.TLSFlags(&cmd.Flag)
+	cmd.Flag.BoolVar(&client.JSON, "j", false, "json output")
+	if err := cmd.Flag.Parse(args); err != nil {
+		return err
+	}
+
+	if err := client.Bind(); err != nil {
+		return err
+	}
+	defer client.Unbind()
+
+	var prev *ldap.Monitor
+	for i := 0; ; i++ {
+		mon, err := client.Monitor()
+		if err != nil {
+			return err
+		}
+		st := statusOf("monitor", client.Addr, nil)
+		st.Value = mon
+		st.text = formatMonitor(mon, prev)
+		if err := client.Output().Status(st); err != nil {
+			return err
+		}
+		if interval <= 0 || (count > 0 && i+1 >= count) {
+			return nil
+		}
+		prev = &mon
+		time.Sleep(interval)
+	}
+}
+
+func formatMonitor(mon ldap.Monitor, prev *ldap.Monitor) string {
+	var str strings.Builder
+	fmt.Fprintf(&str, "%s (uptime %s)\n", mon.When.Format(time.RFC3339), mon.Uptime)
+	writeCounters(&str, "connections", mon.Connections)
+	writeCounters(&str, "waiters", mon.Waiters)
+	writeCounters(&str, "statistics", mon.Statistics)
+	fmt.Fprintf(&str, "%-12s %12s %12s", "operations", "initiated", "completed")
+	if prev != nil {
+		fmt.Fprintf(&str, " %10s", "ops/s")
+	}
+	for _, o := range mon.Operations {
+		fmt.Fprintf(&str, "\n  %-10s %12d %12d", strings.ToLower(o.Name), o.Initiated, o.Completed)
+		if prev == nil {
+			continue
+		}
+		var (
+			before, _ = prev.Operation(o.Name)
+			elapsed   = mon.When.Sub(prev.When).Seconds()
+			rate      float64
+		)
+		if elapsed > 0 {
+			rate = float64(o.Completed-before.Completed) / elapsed
+		}
+		fmt.Fprintf(&str, " %10.1f", rate)
+	}
+	return str.String()
+}
+
+func writeCounters(str *strings.Builder, name string, counters []ldap.MonitorCounter) {
+	if len(counters) == 0 {
+		return
+	}
+	list := make([]string, len(counters))
+	for i, c := range counters {
+		list[i] = fmt.Sprintf("%s=%d", strings.ToLower(c.Name), c.Value)
+	}
+	fmt.Fprintf(str, "%-12s %s\n", name, strings.Join(list, ", "))
+}
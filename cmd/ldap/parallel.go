@@ -0,0 +1,164 @@
+package main
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"sync"
+
+	"github.com/midbel/ldap"
+)
+
+type execTask struct {
+	id   int
+	rec  ldap.ChangeRecord
+	keys []string
+}
+
+type execScheduler struct {
+	mu       sync.Mutex
+	cond     *sync.Cond
+	inflight map[int][]string
+	err      error
+}
+
+func (s *execScheduler) acquire(t execTask) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for s.err == nil && s.conflicts(t.keys) {
+		s.cond.Wait()
+	}
+	if s.err != nil {
+		return false
+	}
+	s.inflight[t.id] = t.keys
+	return true
+}
+
+func (s *execScheduler) release(t execTask, err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.inflight, t.id)
+	if err != nil && s.err == nil {
+		s.err = err
+	}
+	s.cond.Broadcast()
+}
+
+func (s *execScheduler) conflicts(keys []string) bool {
+	for _, other := range s.inflight {
+		for _, k := range keys {
+			for _, o := range other {
+				if relatedDN(k, o) {
+					return true
+				}
+			}
+		}
+	}
+	return false
+}
+
+func (c *Client) execParallel(r io.Reader) error {
+	workers := []*Client{c}
+	for i := 1; i < c.Workers; i++ {
+		w := c.clone()
+		if err := w.Bind(); err != nil {
+			return err
+		}
+		defer w.Unbind()
+		workers = append(workers, w)
+	}
+	var (
+		rs     = ldap.NewReader(r, localURLs)
+		report = c.newReport()
+		queue  = make(chan execTask)
+		sched  = execScheduler{inflight: make(map[int][]string)}
+		wg     sync.WaitGroup
+	)
+	sched.cond = sync.NewCond(&sched.mu)
+	for _, w := range workers {
+		wg.Add(1)
+		go func(w *Client) {
+			defer wg.Done()
+			for t := range queue {
+				op, err := w.apply(t.rec.Type, t.rec.Change)
+				if err == nil && c.log != nil {
+					report.mu.Lock()
+					err = c.log.WriteChange(t.rec.Type, t.rec.Change)
+					report.mu.Unlock()
+				}
+				sched.release(t, report.record(t.rec, op, err))
+			}
+		}(w)
+	}
+
+	var err error
+	for id := 0; ; id++ {
+		rec, e := rs.Read()
+		if e != nil {
+			if !errors.Is(e, io.EOF) {
+				err = recordError(rec, e)
+			}
+			break
+		}
+		t := execTask{
+			id:   id,
+			rec:  rec,
+			keys: recordKeys(rec),
+		}
+		if !sched.acquire(t) {
+			break
+		}
+		queue <- t
+	}
+	close(queue)
+	wg.Wait()
+
+	if err != nil {
+		return err
+	}
+	if sched.err != nil {
+		return sched.err
+	}
+	return report.finish()
+}
+
+func (c *Client) clone() *Client {
+	return &Client{
+		User:     c.User,
+		Pass:     c.Pass,
+		Cert:     c.Cert,
+		Pin:      c.Pin,
+		Addr:     c.Addr,
+		TLS:      c.TLS,
+		Insecure: c.Insecure,
+		Policy:   c.Policy,
+	}
+}
+
+func recordKeys(rec ldap.ChangeRecord) []string {
+	keys := []string{normalizeDN(rec.Name)}
+	if rec.Type != ldap.ModRDN {
+		return keys
+	}
+	parent := rec.NewSuperior
+	if parent == "" {
+		if dn, err := ldap.Explode(rec.Name); err == nil {
+			parent = dn.Parent(1).String()
+		}
+	}
+	if parent != "" {
+		keys = append(keys, normalizeDN(rec.NewRDN+","+parent))
+	}
+	return keys
+}
+
+func relatedDN(left, right string) bool {
+	if left == right {
+		return true
+	}
+	if len(left) > len(right) {
+		left, right = right, left
+	}
+	return left == "" || strings.HasSuffix(right, ","+left)
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/url"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+const (
+	schemeLDAP  = "ldap"
+	schemeLDAPS = "ldaps"
+	defaultPort = "389"
+	ttyDevice   = "/dev/tty"
+)
+
+var ErrNoTerminal = errors.New("no terminal available")
+
+func parseURI(uri string) (string, error) {
+	if fs := strings.Fields(uri); len(fs) > 0 {
+		uri = fs[0]
+	}
+	if !strings.Contains(uri, "://") {
+		return uri, nil
+	}
+	u, err := url.Parse(uri)
+	if err != nil {
+		return "", err
+	}
+	switch strings.ToLower(u.Scheme) {
+	case schemeLDAP:
+	case schemeLDAPS:
+		return "", fmt.Errorf("%s: scheme not supported (use %s:// with starttls)", u.Scheme, schemeLDAP)
+	default:
+		return "", fmt.Errorf("%s: unknown scheme", u.Scheme)
+	}
+	host, port := u.Hostname(), u.Port()
+	if host == "" {
+		host = "localhost"
+	}
+	if port == "" {
+		port = defaultPort
+	}
+	return net.JoinHostPort(host, port), nil
+}
+
+func readPassword(prompt string) (string, error) {
+	tty, err := os.OpenFile(ttyDevice, os.O_RDWR, 0)
+	if err != nil {
+		return "", fmt.Errorf("%w to prompt for password (%s): use -p or %s", ErrNoTerminal, err, envPass)
+	}
+	defer tty.Close()
+
+	fmt.Fprint(tty, prompt)
+	if err := stty(tty, "-echo"); err != nil {
+		return "", err
+	}
+	defer func() {
+		stty(tty, "echo")
+		fmt.Fprintln(tty)
+	}()
+	var (
+		line []byte
+		char = make([]byte, 1)
+	)
+	for {
+		n, err := tty.Read(char)
+		if n > 0 {
+			if char[0] == '\n' {
+				break
+			}
+			line = append(line, char[0])
+		}
+		if err != nil {
+			if err == io.EOF && len(line) > 0 {
+				break
+			}
+			return "", fmt.Errorf("reading password: %w", err)
+		}
+	}
+	return strings.TrimRight(string(line), "\r"), nil
+}
+
+func isTerminal(f *os.File) bool {
+	fi, err := f.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+func stty(tty *os.File, args ...string) error {
+	cmd := exec.Command("stty", args...)
+	cmd.Stdin = tty
+	return cmd.Run()
+}
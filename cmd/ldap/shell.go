@@ -0,0 +1,315 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/midbel/cli"
+	"github.com/midbel/ldap"
+)
+
+const (
+	historyFile = ".ldap_history"
+	historySize = 500
+)
+
+type shell struct {
+	client  *Client
+	cwd     string
+	home    string
+	history []string
+	out     io.Writer
+}
+
+func runShell(cmd *cli.Command, args []string) error {
+	var client Client
+	cmd.Flag.StringVar(&client.Addr, "r", "localhost:389", "remote host")
+	cmd.Flag.StringVar(&client.User, "u", "", "user")
+	cmd.Flag.StringVar(&client.Pass, "p", "", "password")
+	cmd.Flag.BoolVar(&client.TLS, "z", false, "start tls")
+	client.TLSFlags(&cmd.Flag)
+	if err := cmd.Flag.Parse(args); err != nil {
+		return err
+	}
+
+	if err := client.Bind(); err != nil {
+		return err
+	}
+	defer client.Unbind()
+
+	sh := shell{
+		client: &client,
+		cwd:    cmd.Flag.Arg(0),
+		out:    os.Stdout,
+	}
+	if sh.cwd == "" {
+		sh.cwd, _ = client.DefaultBase()
+	}
+	sh.home = sh.cwd
+	sh.loadHistory()
+	defer sh.saveHistory()
+	return sh.run(os.Stdin)
+}
+
+func (s *shell) run(r io.Reader) error {
+	scan := bufio.NewScanner(r)
+	for {
+		fmt.Fprintf(s.out, "%s> ", s.cwd)
+		if !scan.Scan() {
+			fmt.Fprintln(s.out)
+			return scan.Err()
+		}
+		line := strings.TrimSpace(scan.Text())
+		if line == "" {
+			continue
+		}
+		if strings.HasPrefix(line, "!") {
+			prev, err := s.recall(line[1:])
+			if err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				continue
+			}
+			line = prev
+			fmt.Fprintln(s.out, line)
+		}
+		s.history = append(s.history, line)
+		fields := strings.Fields(line)
+		if fields[0] == "exit" || fields[0] == "quit" {
+			return nil
+		}
+		if err := s.exec(fields[0], fields[1:]); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+		}
+	}
+}
+
+func (s *shell) exec(name string, args []string) error {
+	switch name {
+	case "pwd":
+		fmt.Fprintln(s.out, s.cwd)
+	case "cd":
+		return s.cd(args)
+	case "ls":
+		return s.ls(args)
+	case "cat":
+		return s.cat(args)
+	case "search":
+		return s.search(args)
+	case "modify":
+		return s.modify(args)
+	case "whoami":
+		who, _, err := s.client.Whoami()
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(s.out, strings.TrimPrefix(who, "dn:"))
+	case "complete":
+		return s.complete(args)
+	case "history":
+		for i, h := range s.history {
+			fmt.Fprintf(s.out, "%4d  %s\n", i+1, h)
+		}
+	case "help":
+		fmt.Fprintln(s.out, "cd <rdn|dn|..>, ls [filter], cat [rdn], search <filter> [attr...],")
+		fmt.Fprintln(s.out, "modify <rdn|.> <op:attr[=value]...>, complete <prefix>, whoami, pwd, history, !n, !!, exit")
+	default:
+		return fmt.Errorf("%s: unknown command (try help)", name)
+	}
+	return nil
+}
+
+func (s *shell) resolve(arg string) string {
+	switch arg {
+	case "", ".":
+		return s.cwd
+	case "~":
+		return s.home
+	case "..":
+		dn, err := ldap.Explode(s.cwd)
+		if err != nil || dn.Len() <= 1 {
+			return ""
+		}
+		return dn.Parent(1).String()
+	}
+	if _, err := ldap.ParseRDN(arg); err == nil && s.cwd != "" {
+		return arg + "," + s.cwd
+	}
+	return arg
+}
+
+func (s *shell) cd(args []string) error {
+	var target string
+	if len(args) > 0 {
+		target = args[0]
+	} else {
+		target = "~"
+	}
+	dn := s.resolve(target)
+	if dn != "" {
+		_, _, err := s.client.Client.Search(dn, ldap.WithScope(ldap.ScopeBase), ldap.WithAttributes([]string{"1.1"}))
+		if err != nil {
+			return err
+		}
+	}
+	s.cwd = dn
+	return nil
+}
+
+func (s *shell) ls(args []string) error {
+	options := []ldap.SearchOption{
+		ldap.WithScope(ldap.ScopeSingle),
+		ldap.WithAttributes([]string{"1.1"}),
+	}
+	if len(args) > 0 {
+		filter, err := ldap.ParseFilter(strings.Join(args, " "))
+		if err != nil {
+			return err
+		}
+		options = append(options, ldap.WithFilter(filter))
+	}
+	es, _, err := s.client.Client.Search(s.cwd, options...)
+	if err != nil {
+		return err
+	}
+	for _, e := range es {
+		dn, err := ldap.Explode(e.Name)
+		if err != nil {
+			fmt.Fprintln(s.out, e.Name)
+			continue
+		}
+		fmt.Fprintln(s.out, dn.RDN())
+	}
+	return nil
+}
+
+func (s *shell) cat(args []string) error {
+	var target string
+	if len(args) > 0 {
+		target = args[0]
+	}
+	es, _, err := s.client.Client.Search(s.resolve(target), ldap.WithScope(ldap.ScopeBase))
+	if err != nil {
+		return err
+	}
+	for _, e := range es {
+		if err := s.client.Output().Entry(e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (s *shell) search(args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("filter should be given")
+	}
+	filter, err := ldap.ParseFilter(args[0])
+	if err != nil {
+		return err
+	}
+	options := []ldap.SearchOption{
+		ldap.WithFilter(filter),
+	}
+	if len(args) > 1 {
+		options = append(options, ldap.WithAttributes(args[1:]))
+	}
+	return s.client.Search(s.cwd, options)
+}
+
+func (s *shell) modify(args []string) error {
+	if len(args) < 2 {
+		return fmt.Errorf("target and at least one operation should be given")
+	}
+	changes, err := parseOperations(args[1:])
+	if err != nil {
+		return err
+	}
+	_, err = s.client.Modify(s.resolve(args[0]), changes)
+	return err
+}
+
+func (s *shell) complete(args []string) error {
+	var prefix string
+	if len(args) > 0 {
+		prefix = strings.ToLower(args[0])
+	}
+	es, _, err := s.client.Client.Search(s.cwd, ldap.WithScope(ldap.ScopeSingle), ldap.WithAttributes([]string{"1.1"}))
+	if err != nil {
+		return err
+	}
+	for _, e := range es {
+		dn, err := ldap.Explode(e.Name)
+		if err != nil {
+			continue
+		}
+		if rdn := dn.RDN().String(); strings.HasPrefix(strings.ToLower(rdn), prefix) {
+			fmt.Fprintln(s.out, rdn)
+		}
+	}
+	schema, err := s.client.Schema()
+	if err != nil {
+		return nil
+	}
+	for _, at := range schema.AttributeTypes {
+		for _, n := range at.Names {
+			if prefix != "" && strings.HasPrefix(strings.ToLower(n), prefix) {
+				fmt.Fprintln(s.out, n)
+			}
+		}
+	}
+	return nil
+}
+
+func (s *shell) recall(ref string) (string, error) {
+	if len(s.history) == 0 {
+		return "", fmt.Errorf("history is empty")
+	}
+	if ref == "!" {
+		return s.history[len(s.history)-1], nil
+	}
+	n, err := strconv.Atoi(ref)
+	if err != nil || n <= 0 || n > len(s.history) {
+		return "", fmt.Errorf("!%s: event not found", ref)
+	}
+	return s.history[n-1], nil
+}
+
+func (s *shell) loadHistory() {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return
+	}
+	r, err := os.Open(filepath.Join(home, historyFile))
+	if err != nil {
+		return
+	}
+	defer r.Close()
+	scan := bufio.NewScanner(r)
+	for scan.Scan() {
+		s.history = append(s.history, scan.Text())
+	}
+}
+
+func (s *shell) saveHistory() {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return
+	}
+	w, err := os.OpenFile(filepath.Join(home, historyFile), os.O_CREATE|os.O_TRUNC|os.O_WRONLY, 0o600)
+	if err != nil {
+		return
+	}
+	defer w.Close()
+	history := s.history
+	if len(history) > historySize {
+		history = history[len(history)-historySize:]
+	}
+	for _, h := range history {
+		fmt.Fprintln(w, h)
+	}
+}
@@ -0,0 +1,205 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/midbel/cli"
+	"github.com/midbel/ldap"
+)
+
+type syncStep struct {
+	Type   ldap.ChangeType
+	Change ldap.Change
+}
+
+func runSync(cmd *cli.Command, args []string) error {
+	var (
+		src    Client
+		dst    Client
+		tls    Client
+		filter Filter
+		file   string
+		dry    bool
+		keep   bool
+	)
+	endpointFlags(&cmd.Flag, &src, "from")
+	endpointFlags(&cmd.Flag, &dst, "to")
+	sharedTLSFlags(&cmd.Flag, &tls)
+	cmd.Flag.StringVar(&file, "i", "", "ldif file used as source instead of a server")
+	cmd.Flag.Var(&filter, "f", "filter selecting entries to synchronize")
+	cmd.Flag.BoolVar(&dry, "n", false, "print changes as ldif without applying them")
+	cmd.Flag.BoolVar(&keep, "k", false, "keep entries missing from source on target")
+	cmd.Flag.BoolVar(&dst.JSON, "j", false, "json output")
+	if err := cmd.Flag.Parse(args); err != nil {
+		return err
+	}
+	if cmd.Flag.NArg() == 0 {
+		return fmt.Errorf("base should be given")
+	}
+	if (file == "") == (src.Addr == "") {
+		return fmt.Errorf("one of -from or -i should be given")
+	}
+	base := cmd.Flag.Arg(0)
+
+	var source []ldap.Entry
+	if file != "" {
+		es, err := readDeclared(file)
+		if err != nil {
+			return err
+		}
+		if source, err = selectEntries(es, base, filter.Filter); err != nil {
+			return err
+		}
+	} else {
+		if err := bindEndpoint(&src, "from", tls); err != nil {
+			return err
+		}
+		defer src.Unbind()
+		es, err := readSubtree(&src, base, filter.Filter)
+		if err != nil {
+			return err
+		}
+		source = es
+	}
+	if err := bindEndpoint(&dst, "to", tls); err != nil {
+		return err
+	}
+	defer dst.Unbind()
+
+	target, err := readSubtree(&dst, base, filter.Filter)
+	if err != nil && !isResultCode(err, ldap.NoSuchObject) {
+		return err
+	}
+	steps := planSync(source, target, keep)
+	if dry {
+		w := ldap.NewLDIFWriter(os.Stdout)
+		for _, s := range steps {
+			if err := w.WriteChange(s.Type, s.Change); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	for _, s := range steps {
+		var (
+			dn  = s.Change.Name
+			err error
+			op  string
+		)
+		switch s.Type {
+		case ldap.ModAdd:
+			op = "added"
+			attrs := make([]ldap.Attribute, 0, len(s.Change.Attrs))
+			for _, a := range s.Change.Attrs {
+				attrs = append(attrs, a.Attribute)
+			}
+			_, err = dst.Add(dn, attrs)
+		case ldap.ModReplace:
+			op = "modified"
+			_, err = dst.Modify(dn, s.Change.Attrs)
+		case ldap.ModDelete:
+			op = "deleted"
+			_, err = dst.Delete(dn)
+		}
+		st := statusOf("sync", dn, err)
+		st.Value = op
+		if err != nil {
+			st.text = fmt.Sprintf("fail to sync %s: %s", dn, err)
+		} else {
+			st.text = fmt.Sprintf("%s %s", dn, op)
+		}
+		if err := dst.Output().Status(st); err != nil {
+			return err
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func planSync(source, target []ldap.Entry, keep bool) []syncStep {
+	var (
+		steps   []syncStep
+		deletes []syncStep
+		live    = make(map[string]ldap.Entry)
+		seen    = make(map[string]struct{})
+	)
+	for _, e := range target {
+		live[normalizeDN(e.Name)] = e
+	}
+	sort.SliceStable(source, func(i, j int) bool {
+		return depthOf(source[i].Name) < depthOf(source[j].Name)
+	})
+	for _, e := range source {
+		key := normalizeDN(e.Name)
+		seen[key] = struct{}{}
+		curr, ok := live[key]
+		if !ok {
+			c := ldap.Change{Name: e.Name}
+			for _, a := range e.Attrs {
+				c.Attrs = append(c.Attrs, ldap.PartialAttribute{Attribute: a})
+			}
+			steps = append(steps, syncStep{Type: ldap.ModAdd, Change: c})
+			continue
+		}
+		mods := ldap.Diff(curr, e)
+		if len(mods) == 0 {
+			continue
+		}
+		steps = append(steps, syncStep{
+			Type:   ldap.ModReplace,
+			Change: ldap.Change{Name: curr.Name, Attrs: mods},
+		})
+	}
+	if keep {
+		return steps
+	}
+	for _, e := range target {
+		if _, ok := seen[normalizeDN(e.Name)]; ok {
+			continue
+		}
+		deletes = append(deletes, syncStep{
+			Type:   ldap.ModDelete,
+			Change: ldap.Change{Name: e.Name},
+		})
+	}
+	sort.SliceStable(deletes, func(i, j int) bool {
+		return depthOf(deletes[i].Change.Name) > depthOf(deletes[j].Change.Name)
+	})
+	return append(steps, deletes...)
+}
+
+func selectEntries(es []ldap.Entry, base string, filter ldap.Filter) ([]ldap.Entry, error) {
+	var (
+		list   []ldap.Entry
+		suffix = normalizeDN(base)
+	)
+	for _, e := range es {
+		dn := normalizeDN(e.Name)
+		if dn != suffix && !strings.HasSuffix(dn, ","+suffix) {
+			continue
+		}
+		if filter != nil {
+			ok, err := ldap.Match(filter, e)
+			if err != nil {
+				return nil, err
+			}
+			if !ok {
+				continue
+			}
+		}
+		list = append(list, e)
+	}
+	return list, nil
+}
+
+func normalizeDN(dn string) string {
+	if d, err := ldap.Explode(dn); err == nil {
+		return strings.ToLower(d.Normalize().String())
+	}
+	return strings.ToLower(dn)
+}
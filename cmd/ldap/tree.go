@@ -0,0 +1,121 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/midbel/cli"
+	"github.com/midbel/ldap"
+)
+
+const (
+	treeBranch = "├── "
+	treeLast   = "└── "
+	treePipe   = "│   "
+	treeSpace  = "    "
+)
+
+type tree struct {
+	client *Client
+	filter ldap.Filter
+	depth  int
+	out    io.Writer
+}
+
+func runTree(cmd *cli.Command, args []string) error {
+	var (
+		client Client
+		filter Filter
+		depth  int
+	)
+	cmd.Flag.Var(&filter, "f", "filter applied to children")
+	cmd.Flag.IntVar(&depth, "d", 0, "maximum depth (0 for unlimited)")
+	cmd.Flag.StringVar(&client.Addr, "r", "localhost:389", "remote host")
+	cmd.Flag.StringVar(&client.User, "u", "", "user")
+	cmd.Flag.StringVar(&client.Pass, "p", "", "password")
+	cmd.Flag.BoolVar(&client.TLS, "z", false, "start tls")
+	client.TLSFlags(&cmd.Flag)
+	if err := cmd.Flag.Parse(args); err != nil {
+		return err
+	}
+
+	if err := client.Bind(); err != nil {
+		return err
+	}
+	defer client.Unbind()
+
+	base := cmd.Flag.Arg(0)
+	if base == "" {
+		b, err := client.DefaultBase()
+		if err != nil {
+			return err
+		}
+		base = b
+	}
+	t := tree{
+		client: &client,
+		filter: filter.Filter,
+		depth:  depth,
+		out:    os.Stdout,
+	}
+	fmt.Fprintln(t.out, base)
+	return t.walk(base, "", 1)
+}
+
+func (t tree) walk(base, prefix string, level int) error {
+	if t.depth > 0 && level > t.depth {
+		return nil
+	}
+	children, err := t.children(base)
+	if err != nil {
+		return err
+	}
+	for i, c := range children {
+		branch, next := treeBranch, treePipe
+		if i == len(children)-1 {
+			branch, next = treeLast, treeSpace
+		}
+		fmt.Fprintf(t.out, "%s%s%s\n", prefix, branch, c.rdn)
+		if err := t.walk(c.dn, prefix+next, level+1); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type treeNode struct {
+	dn  string
+	rdn string
+}
+
+func (t tree) children(base string) ([]treeNode, error) {
+	options := []ldap.SearchOption{
+		ldap.WithScope(ldap.ScopeSingle),
+		ldap.WithAttributes([]string{"1.1"}),
+	}
+	if t.filter != nil {
+		options = append(options, ldap.WithFilter(t.filter))
+	}
+	es, _, err := t.client.Client.Search(base, options...)
+	if err != nil {
+		return nil, err
+	}
+	list := make([]treeNode, 0, len(es))
+	for _, e := range es {
+		n := treeNode{
+			dn:  e.Name,
+			rdn: e.Name,
+		}
+		if dn, err := ldap.Explode(e.Name); err == nil {
+			n.rdn = dn.RDN().String()
+		}
+		list = append(list, n)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		return strings.ToLower(list[i].rdn) < strings.ToLower(list[j].rdn)
+	})
+	return list, nil
+}
@@ -0,0 +1,116 @@
+package ldap
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+)
+
+type pendingModify struct {
+	dn      string
+	info    OperationInfo
+	changes []PartialAttribute
+}
+
+type writeBuffer struct {
+	mu      sync.Mutex
+	window  time.Duration
+	timer   *time.Timer
+	pending []*pendingModify
+	index   map[string]*pendingModify
+	err     error
+}
+
+func (c *Client) CoalesceWrites(window time.Duration) error {
+	err := c.Flush()
+	if window <= 0 {
+		c.wb = nil
+		return err
+	}
+	c.wb = &writeBuffer{
+		window: window,
+		index:  make(map[string]*pendingModify),
+	}
+	return err
+}
+
+func (c *Client) Flush() error {
+	return c.flush(c.wb)
+}
+
+func (c *Client) flush(wb *writeBuffer) error {
+	if wb == nil {
+		return nil
+	}
+	wb.mu.Lock()
+	if wb.timer != nil {
+		wb.timer.Stop()
+		wb.timer = nil
+	}
+	pending, err := wb.pending, wb.err
+	wb.pending, wb.err = nil, nil
+	wb.index = make(map[string]*pendingModify)
+	wb.mu.Unlock()
+
+	for _, p := range pending {
+		if _, e := c.sendModify(p.dn, p.changes, p.info); e != nil && err == nil {
+			err = fmt.Errorf("%s: %w", p.dn, e)
+		}
+	}
+	return err
+}
+
+func (c *Client) push(info OperationInfo, changes []PartialAttribute) error {
+	var (
+		wb  = c.wb
+		dn  = info.DN
+		key = strings.ToLower(dn)
+	)
+	if name, err := Explode(dn); err == nil {
+		key = name.Normalize().String()
+	}
+
+	wb.mu.Lock()
+	defer wb.mu.Unlock()
+
+	p, ok := wb.index[key]
+	if !ok {
+		p = &pendingModify{dn: dn, info: info}
+		wb.index[key] = p
+		wb.pending = append(wb.pending, p)
+	}
+	p.changes = mergeChanges(p.changes, changes)
+	if wb.timer == nil {
+		wb.timer = time.AfterFunc(wb.window, func() {
+			err := c.flush(wb)
+			if err == nil {
+				return
+			}
+			wb.mu.Lock()
+			defer wb.mu.Unlock()
+			if wb.err == nil {
+				wb.err = err
+			}
+		})
+	}
+	return nil
+}
+
+func mergeChanges(prev, next []PartialAttribute) []PartialAttribute {
+	for _, n := range next {
+		overwrite := n.Mod == ModReplace || (n.Mod == ModDelete && len(n.Values) == 0)
+		if overwrite {
+			list := prev[:0]
+			for _, p := range prev {
+				if !strings.EqualFold(p.Name, n.Name) {
+					list = append(list, p)
+				}
+			}
+			prev = list
+		}
+		n.Values = append([]string{}, n.Values...)
+		prev = append(prev, n)
+	}
+	return prev
+}
@@ -0,0 +1,47 @@
+package ldap
+
+import (
+	"errors"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestCoalesceHooks(t *testing.T) {
+	server, conn := net.Pipe()
+	defer server.Close()
+
+	var (
+		c      = NewClient(conn)
+		denied = errors.New("denied")
+		calls  int
+	)
+	c.binded = true
+	c.BeforeOp(func(info *OperationInfo) error {
+		calls++
+		if strings.HasSuffix(info.DN, "ou=admins,dc=example,dc=com") {
+			return denied
+		}
+		return nil
+	})
+	if err := c.CoalesceWrites(time.Hour); err != nil {
+		t.Fatal(err)
+	}
+	changes := []PartialAttribute{
+		{Mod: ModReplace, Attribute: Attribute{Name: "mail", Values: []string{"root@example.com"}}},
+	}
+	if _, err := c.Modify("cn=root,ou=admins,dc=example,dc=com", changes); !errors.Is(err, denied) {
+		t.Errorf("vetoed modify: expected %v, got %v", denied, err)
+	}
+	if _, err := c.Modify("cn=john,ou=people,dc=example,dc=com", changes); err != nil {
+		t.Errorf("buffered modify: unexpected error: %s", err)
+	}
+	if calls != 2 {
+		t.Errorf("hooks not run when change is buffered: want 2 calls, got %d", calls)
+	}
+	if n := len(c.wb.pending); n != 1 {
+		t.Errorf("pending modifies mismatch: want 1, got %d", n)
+	}
+	c.wb.timer.Stop()
+}
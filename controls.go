@@ -187,3 +187,7 @@ func CreateControl(oid string, value []byte, critical bool) Control {
 		Value:    value,
 	}
 }
+
+func ManageDsaIT() Control {
+	return CreateControl(CtrlManageDsaItOID, nil, true)
+}
@@ -0,0 +1,205 @@
+package ldap
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"golang.org/x/crypto/hkdf"
+)
+
+const (
+	aesPrefix = "{AES-GCM}"
+
+	hkdfInfoNonce   = "ldap attribute cipher nonce"
+	hkdfInfoEncrypt = "ldap attribute cipher encryption"
+)
+
+var ErrDecrypt = errors.New("fail to decrypt value")
+
+type Cipher interface {
+	Encrypt(attr, value string) (string, error)
+	Decrypt(attr, value string) (string, error)
+}
+
+type attrCipher struct {
+	Cipher
+	attrs []string
+}
+
+func (c *Client) SetCipher(ci Cipher, attrs ...string) {
+	if ci == nil || len(attrs) == 0 {
+		c.cipher = nil
+		return
+	}
+	c.cipher = &attrCipher{
+		Cipher: ci,
+		attrs:  attrs,
+	}
+}
+
+func (ac *attrCipher) protected(name string) bool {
+	if ac == nil {
+		return false
+	}
+	a := Attribute{Name: name}
+	for _, n := range ac.attrs {
+		if matchAttribute(a, n) {
+			return true
+		}
+	}
+	return false
+}
+
+func (ac *attrCipher) encrypt(name string, values []string) ([]string, error) {
+	if !ac.protected(name) || len(values) == 0 {
+		return values, nil
+	}
+	list := make([]string, len(values))
+	for i, v := range values {
+		v, err := ac.Encrypt(name, v)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", name, err)
+		}
+		list[i] = v
+	}
+	return list, nil
+}
+
+func (ac *attrCipher) encryptAttrs(attrs []Attribute) ([]Attribute, error) {
+	if ac == nil {
+		return attrs, nil
+	}
+	list := make([]Attribute, len(attrs))
+	for i, a := range attrs {
+		vs, err := ac.encrypt(a.Name, a.Values)
+		if err != nil {
+			return nil, err
+		}
+		a.Values = vs
+		list[i] = a
+	}
+	return list, nil
+}
+
+func (ac *attrCipher) encryptChanges(changes []PartialAttribute) ([]PartialAttribute, error) {
+	if ac == nil {
+		return changes, nil
+	}
+	list := make([]PartialAttribute, len(changes))
+	for i, c := range changes {
+		if c.Mod != ModIncrement {
+			vs, err := ac.encrypt(c.Name, c.Values)
+			if err != nil {
+				return nil, err
+			}
+			c.Values = vs
+		}
+		list[i] = c
+	}
+	return list, nil
+}
+
+func (ac *attrCipher) decryptEntry(e Entry) (Entry, error) {
+	if ac == nil {
+		return e, nil
+	}
+	for i, a := range e.Attrs {
+		if !ac.protected(a.Name) {
+			continue
+		}
+		for j, v := range a.Values {
+			v, err := ac.Decrypt(a.Name, v)
+			if err != nil {
+				return e, fmt.Errorf("%s(%s): %w", e.Name, a.Name, err)
+			}
+			e.Attrs[i].Values[j] = v
+		}
+	}
+	return e, nil
+}
+
+type aesCipher struct {
+	aead cipher.AEAD
+	mac  []byte
+}
+
+// NewAESCipher encrypts values with AES-GCM under a key derived from key
+// with HKDF-SHA256; the nonce is an HMAC of the attribute and the value
+// under a second derived key. The scheme is deterministic on purpose: the
+// same value of the same attribute always gives the same ciphertext so
+// that Compare and Delete of a value keep working on the server. As a
+// consequence, it reveals which entries share a value.
+func NewAESCipher(key []byte) (Cipher, error) {
+	switch len(key) {
+	case 16, 24, 32:
+	default:
+		return nil, aes.KeySizeError(len(key))
+	}
+	enc, err := deriveKey(key, hkdfInfoEncrypt, len(key))
+	if err != nil {
+		return nil, err
+	}
+	mac, err := deriveKey(key, hkdfInfoNonce, sha256.Size)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(enc)
+	if err != nil {
+		return nil, err
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+	return aesCipher{
+		aead: aead,
+		mac:  mac,
+	}, nil
+}
+
+func (a aesCipher) Encrypt(attr, value string) (string, error) {
+	attr = strings.ToLower(attr)
+
+	mac := hmac.New(sha256.New, a.mac)
+	mac.Write([]byte(attr))
+	mac.Write([]byte{0})
+	mac.Write([]byte(value))
+	nonce := mac.Sum(nil)[:a.aead.NonceSize()]
+
+	buf := a.aead.Seal(nonce, nonce, []byte(value), []byte(attr))
+	return aesPrefix + base64.StdEncoding.EncodeToString(buf), nil
+}
+
+func (a aesCipher) Decrypt(attr, value string) (string, error) {
+	if !strings.HasPrefix(value, aesPrefix) {
+		return value, nil
+	}
+	buf, err := base64.StdEncoding.DecodeString(value[len(aesPrefix):])
+	if err != nil {
+		return "", fmt.Errorf("%w: %s", ErrDecrypt, err)
+	}
+	size := a.aead.NonceSize()
+	if len(buf) < size {
+		return "", ErrDecrypt
+	}
+	plain, err := a.aead.Open(nil, buf[:size], buf[size:], []byte(strings.ToLower(attr)))
+	if err != nil {
+		return "", fmt.Errorf("%w: %s", ErrDecrypt, err)
+	}
+	return string(plain), nil
+}
+
+func deriveKey(secret []byte, info string, size int) ([]byte, error) {
+	key := make([]byte, size)
+	if _, err := io.ReadFull(hkdf.New(sha256.New, secret, nil, []byte(info)), key); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
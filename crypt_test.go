@@ -0,0 +1,63 @@
+package ldap
+
+import (
+	"bytes"
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+func TestDeriveKey(t *testing.T) {
+	var (
+		secret = bytes.Repeat([]byte{0x0b}, 22)
+		want   = "8da4e775a563c18f715f802a063c5a31b8a11f5c5ee1879ec3454e5f3c738d2d9d201395faa4b61a96c8"
+	)
+	key, err := deriveKey(secret, "", 42)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := hex.EncodeToString(key); got != want {
+		t.Errorf("key mismatch\nwant: %s\n got: %s", want, got)
+	}
+}
+
+func TestAESCipher(t *testing.T) {
+	key := bytes.Repeat([]byte{0x2a}, 32)
+	ci, err := NewAESCipher(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := ci.(aesCipher)
+	sub, err := deriveKey(key, hkdfInfoEncrypt, len(key))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if bytes.Equal(a.mac, key) || bytes.Equal(a.mac, sub) {
+		t.Errorf("nonce and encryption keys should differ from each other and from the key")
+	}
+	enc, err := ci.Encrypt("mail", "john@example.org")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(enc, aesPrefix) {
+		t.Errorf("%s: missing prefix", enc)
+	}
+	again, _ := ci.Encrypt("MAIL", "john@example.org")
+	if again != enc {
+		t.Errorf("encryption should be deterministic: %s != %s", enc, again)
+	}
+	other, _ := ci.Encrypt("cn", "john@example.org")
+	if other == enc {
+		t.Errorf("same value of different attributes should differ")
+	}
+	plain, err := ci.Decrypt("mail", enc)
+	if err != nil || plain != "john@example.org" {
+		t.Errorf("decrypt mismatch: %q (%v)", plain, err)
+	}
+	if _, err := ci.Decrypt("cn", enc); err == nil {
+		t.Errorf("value decrypted under another attribute")
+	}
+	if _, err := NewAESCipher([]byte("short")); err == nil {
+		t.Errorf("invalid key size accepted")
+	}
+}
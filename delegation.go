@@ -0,0 +1,121 @@
+package ldap
+
+import (
+	"fmt"
+)
+
+type Grant struct {
+	Base string   `json:"base"`
+	Own  bool     `json:"own"`
+	Ops  []OpType `json:"ops"`
+}
+
+func (g Grant) allow(user DN, op OpType, target DN) bool {
+	var found bool
+	for _, o := range g.Ops {
+		if found = o == op; found {
+			break
+		}
+	}
+	if !found {
+		return false
+	}
+	var base DN
+	if g.Own {
+		base = user.Parent(1)
+	} else {
+		dn, err := Explode(g.Base)
+		if err != nil {
+			return false
+		}
+		base = dn
+	}
+	return target.Equal(base) || target.IsDescendantOf(base)
+}
+
+type Delegation struct {
+	Users   map[string][]Grant `json:"users"`
+	Default []Grant            `json:"default"`
+}
+
+func (d Delegation) Allow(user string, op OpType, target string) bool {
+	switch {
+	case op == OpBind, op == OpUnbind, op == OpAbandon:
+		return true
+	case op == OpExtended && target == "":
+		return true
+	}
+	who, err := Explode(user)
+	if err != nil {
+		return false
+	}
+	dn, err := Explode(target)
+	if err != nil {
+		return false
+	}
+	for _, g := range d.grants(who) {
+		if g.allow(who, op, dn) {
+			return true
+		}
+	}
+	return false
+}
+
+func (d Delegation) Hook(user string) BeforeHook {
+	return func(info *OperationInfo) error {
+		if !d.Allow(user, info.Type, info.DN) {
+			return Result{
+				Code:       InsufficientAccessRight,
+				Diagnostic: fmt.Sprintf("%s not permitted on %s for %s", info.Type, info.DN, user),
+			}
+		}
+		if info.Type != OpModDN {
+			return nil
+		}
+		next, err := modDNTarget(info.DN, info.NewRDN, info.NewSuperior)
+		if err != nil {
+			return Result{
+				Code:       InsufficientAccessRight,
+				Diagnostic: fmt.Sprintf("%s not permitted on %s for %s: %s", info.Type, info.DN, user, err),
+			}
+		}
+		if d.Allow(user, info.Type, next) {
+			return nil
+		}
+		return Result{
+			Code:       InsufficientAccessRight,
+			Diagnostic: fmt.Sprintf("%s not permitted from %s to %s for %s", info.Type, info.DN, next, user),
+		}
+	}
+}
+
+func modDNTarget(dn, rdn, parent string) (string, error) {
+	name, err := Explode(dn)
+	if err != nil {
+		return "", err
+	}
+	if rdn != "" {
+		next, err := ParseRDN(rdn)
+		if err != nil {
+			return "", err
+		}
+		name = name.Rename(next)
+	}
+	if parent != "" {
+		superior, err := Explode(parent)
+		if err != nil {
+			return "", err
+		}
+		name = name.Move(superior)
+	}
+	return name.String(), nil
+}
+
+func (d Delegation) grants(user DN) []Grant {
+	for u, gs := range d.Users {
+		if dn, err := Explode(u); err == nil && dn.Equal(user) {
+			return append(append([]Grant{}, gs...), d.Default...)
+		}
+	}
+	return d.Default
+}
@@ -0,0 +1,48 @@
+package ldap
+
+import (
+	"testing"
+)
+
+func TestDelegationModDN(t *testing.T) {
+	d := Delegation{
+		Users: map[string][]Grant{
+			"cn=admin,ou=people,dc=example,dc=org": {
+				{Base: "ou=people,dc=example,dc=org", Ops: []OpType{OpModDN}},
+			},
+		},
+	}
+	hook := d.Hook("cn=admin,ou=people,dc=example,dc=org")
+	data := []struct {
+		Info OperationInfo
+		Want bool
+	}{
+		{
+			Info: OperationInfo{DN: "uid=john,ou=people,dc=example,dc=org", NewRDN: "uid=jdoe"},
+			Want: true,
+		},
+		{
+			Info: OperationInfo{DN: "uid=john,ou=people,dc=example,dc=org", NewRDN: "uid=john", NewSuperior: "ou=staff,ou=people,dc=example,dc=org"},
+			Want: true,
+		},
+		{
+			Info: OperationInfo{DN: "uid=john,ou=people,dc=example,dc=org", NewRDN: "uid=john", NewSuperior: "ou=groups,dc=example,dc=org"},
+			Want: false,
+		},
+		{
+			Info: OperationInfo{DN: "uid=john,ou=people,dc=example,dc=org", NewRDN: "uid=john", NewSuperior: "dc=example,dc=org"},
+			Want: false,
+		},
+		{
+			Info: OperationInfo{DN: "uid=john,ou=groups,dc=example,dc=org", NewRDN: "uid=john", NewSuperior: "ou=people,dc=example,dc=org"},
+			Want: false,
+		},
+	}
+	for _, d := range data {
+		d.Info.Type = OpModDN
+		err := hook(&d.Info)
+		if got := err == nil; got != d.Want {
+			t.Errorf("%s -> %s,%s: want allowed %t, got %t (%v)", d.Info.DN, d.Info.NewRDN, d.Info.NewSuperior, d.Want, got, err)
+		}
+	}
+}
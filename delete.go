@@ -0,0 +1,52 @@
+package ldap
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const (
+	classAlias    = "alias"
+	classReferral = "referral"
+)
+
+var ErrReferencedEntries = errors.New("subtree contains aliases or referrals")
+
+func (c *Client) DeleteTree(dn string, force bool, controls ...Control) ([]string, error) {
+	var (
+		manage = ManageDsaIT()
+		refs   []string
+		es     []Entry
+	)
+	_, err := c.Stream(dn, func(e Entry) error {
+		es = append(es, e)
+		for _, v := range e.GetValues(attrObjectClass) {
+			if strings.EqualFold(v, classAlias) || strings.EqualFold(v, classReferral) {
+				refs = append(refs, e.Name)
+				break
+			}
+		}
+		return nil
+	}, WithScope(ScopeWhole), WithDeref(DerefNever), WithAttributes([]string{attrObjectClass}), WithControl(manage))
+	if err != nil {
+		return nil, err
+	}
+	if len(refs) > 0 && !force {
+		return nil, fmt.Errorf("%w: %s", ErrReferencedEntries, strings.Join(refs, "; "))
+	}
+	sort.SliceStable(es, func(i, j int) bool {
+		return depthOf(es[i].Name) > depthOf(es[j].Name)
+	})
+	controls = append(controls, manage)
+
+	var done []string
+	for _, e := range es {
+		if _, err := c.Delete(e.Name, controls...); err != nil {
+			return done, fmt.Errorf("%s: %w", e.Name, err)
+		}
+		done = append(done, e.Name)
+	}
+	return done, nil
+}
@@ -0,0 +1,70 @@
+package ldap
+
+import (
+	"strings"
+)
+
+const optBinary = "binary"
+
+type AttributeDescription struct {
+	Type    string
+	Options []string
+}
+
+func ParseAttributeDescription(str string) AttributeDescription {
+	parts := strings.Split(str, ";")
+	ad := AttributeDescription{
+		Type: parts[0],
+	}
+	for _, p := range parts[1:] {
+		if p == "" {
+			continue
+		}
+		ad.Options = append(ad.Options, p)
+	}
+	return ad
+}
+
+func (a AttributeDescription) String() string {
+	if len(a.Options) == 0 {
+		return a.Type
+	}
+	return a.Type + ";" + strings.Join(a.Options, ";")
+}
+
+func (a AttributeDescription) Has(option string) bool {
+	return containsName(a.Options, option)
+}
+
+func (a AttributeDescription) Lang() string {
+	for _, o := range a.Options {
+		if len(o) > 5 && strings.EqualFold(o[:5], "lang-") {
+			return o[5:]
+		}
+	}
+	return ""
+}
+
+func (a AttributeDescription) Binary() bool {
+	return a.Has(optBinary)
+}
+
+func (a AttributeDescription) Match(other AttributeDescription) bool {
+	if !strings.EqualFold(a.Type, other.Type) {
+		return false
+	}
+	for _, o := range other.Options {
+		if !a.Has(o) {
+			return false
+		}
+	}
+	return true
+}
+
+func (a Attribute) Description() AttributeDescription {
+	return ParseAttributeDescription(a.Name)
+}
+
+func matchAttribute(a Attribute, name string) bool {
+	return a.Description().Match(ParseAttributeDescription(name))
+}
@@ -0,0 +1,56 @@
+package ldap
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var diagnostics = []*regexp.Regexp{
+	regexp.MustCompile(`attribute ['"]([^'"]+)['"] not allowed`),
+	regexp.MustCompile(`requires attribute ['"]([^'"]+)['"]`),
+	regexp.MustCompile(`missing attribute ['"]([^'"]+)['"]`),
+	regexp.MustCompile(`(?:^|: )([A-Za-z][\w;-]*): (?:value #\d+|no such (?:attribute|value)|attribute type undefined|multiple values|no equality matching rule|no user modification)`),
+	regexp.MustCompile(`Att [0-9a-fA-Fx]+ \(([^)]+)\)`),
+}
+
+type AttributeError struct {
+	Result
+	Attrs []string
+}
+
+func (e AttributeError) Error() string {
+	return fmt.Sprintf("%s [%s]", e.Result.Error(), strings.Join(e.Attrs, ", "))
+}
+
+func (e AttributeError) Unwrap() error {
+	return e.Result
+}
+
+func ParseDiagnostic(diag string) []string {
+	var attrs []string
+	for _, re := range diagnostics {
+		for _, m := range re.FindAllStringSubmatch(diag, -1) {
+			if !containsName(attrs, m[1]) {
+				attrs = append(attrs, m[1])
+			}
+		}
+	}
+	return attrs
+}
+
+func attributeError(err error) error {
+	var res Result
+	if !errors.As(err, &res) {
+		return err
+	}
+	attrs := ParseDiagnostic(res.Diagnostic)
+	if len(attrs) == 0 {
+		return err
+	}
+	return AttributeError{
+		Result: res,
+		Attrs:  attrs,
+	}
+}
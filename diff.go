@@ -0,0 +1,109 @@
+package ldap
+
+import (
+	"strings"
+)
+
+func Diff(old, new Entry) []PartialAttribute {
+	var (
+		before = groupAttributes(old.Attrs)
+		after  = groupAttributes(new.Attrs)
+		mods   []PartialAttribute
+	)
+	for _, a := range after.attrs {
+		key := strings.ToLower(a.Name)
+		prev, ok := before.get(key)
+		if !ok {
+			mods = append(mods, createModification(ModAdd, a.Name, a.Values))
+			continue
+		}
+		mods = append(mods, diffValues(a.Name, prev.Values, a.Values)...)
+	}
+	for _, a := range before.attrs {
+		if _, ok := after.get(strings.ToLower(a.Name)); ok {
+			continue
+		}
+		mods = append(mods, createModification(ModDelete, a.Name, nil))
+	}
+	return mods
+}
+
+func diffValues(name string, prev, next []string) []PartialAttribute {
+	var (
+		removed = subtractValues(name, prev, next)
+		added   = subtractValues(name, next, prev)
+	)
+	switch {
+	case len(removed) == 0 && len(added) == 0:
+		return nil
+	case len(removed) == 0:
+		return []PartialAttribute{createModification(ModAdd, name, added)}
+	case len(added) == 0:
+		if len(removed) == len(prev) {
+			return []PartialAttribute{createModification(ModDelete, name, nil)}
+		}
+		return []PartialAttribute{createModification(ModDelete, name, removed)}
+	case len(next) <= len(added)+len(removed):
+		return []PartialAttribute{createModification(ModReplace, name, next)}
+	default:
+		return []PartialAttribute{
+			createModification(ModDelete, name, removed),
+			createModification(ModAdd, name, added),
+		}
+	}
+}
+
+func subtractValues(name string, left, right []string) []string {
+	seen := make(map[string]struct{})
+	for _, v := range right {
+		seen[canonicalValue(name, v)] = struct{}{}
+	}
+	var values []string
+	for _, v := range left {
+		if _, ok := seen[canonicalValue(name, v)]; !ok {
+			values = append(values, v)
+		}
+	}
+	return values
+}
+
+func createModification(mod ChangeType, name string, values []string) PartialAttribute {
+	pa := PartialAttribute{
+		Mod: mod,
+	}
+	pa.Name = name
+	pa.Values = append(pa.Values, values...)
+	return pa
+}
+
+type attributeSet struct {
+	attrs []Attribute
+	index map[string]int
+}
+
+func groupAttributes(attrs []Attribute) attributeSet {
+	set := attributeSet{
+		index: make(map[string]int),
+	}
+	for _, a := range attrs {
+		key := strings.ToLower(a.Name)
+		if x, ok := set.index[key]; ok {
+			set.attrs[x].Values = append(set.attrs[x].Values, a.Values...)
+			continue
+		}
+		set.index[key] = len(set.attrs)
+		set.attrs = append(set.attrs, Attribute{
+			Name:   a.Name,
+			Values: append([]string{}, a.Values...),
+		})
+	}
+	return set
+}
+
+func (s attributeSet) get(key string) (Attribute, bool) {
+	x, ok := s.index[key]
+	if !ok {
+		return Attribute{}, false
+	}
+	return s.attrs[x], true
+}
@@ -1,13 +1,30 @@
 package ldap
 
 import (
+	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"fmt"
-	"io"
+	"sort"
 	"strings"
 	"unicode/utf8"
 )
 
+var ErrInvalidDN = errors.New("invalid DN")
+
+type DNSyntaxError struct {
+	Offset int
+	Reason string
+}
+
+func (e *DNSyntaxError) Error() string {
+	return fmt.Sprintf("%s: %s at position %d", ErrInvalidDN, e.Reason, e.Offset)
+}
+
+func (e *DNSyntaxError) Unwrap() error {
+	return ErrInvalidDN
+}
+
 type DN struct {
 	parts []RDN
 }
@@ -20,6 +37,30 @@ func (d DN) String() string {
 	return strings.Join(parts, ",")
 }
 
+func (d DN) MarshalText() ([]byte, error) {
+	return []byte(d.String()), nil
+}
+
+func (d *DN) UnmarshalText(b []byte) error {
+	dn, err := Explode(string(b))
+	if err == nil {
+		*d = dn
+	}
+	return err
+}
+
+func (d DN) MarshalJSON() ([]byte, error) {
+	return json.Marshal(d.String())
+}
+
+func (d *DN) UnmarshalJSON(b []byte) error {
+	var str string
+	if err := json.Unmarshal(b, &str); err != nil {
+		return err
+	}
+	return d.UnmarshalText([]byte(str))
+}
+
 func (d DN) Len() int {
 	return len(d.parts)
 }
@@ -46,14 +87,287 @@ func (d DN) At(i int) RDN {
 	return d.parts[i]
 }
 
+func (d DN) Normalize() DN {
+	n := DN{
+		parts: make([]RDN, len(d.parts)),
+	}
+	for i := range d.parts {
+		n.parts[i] = d.parts[i].Normalize()
+	}
+	return n
+}
+
+func (d DN) Equal(other DN) bool {
+	return d.Normalize().String() == other.Normalize().String()
+}
+
+func (d DN) Child(attr, value string) DN {
+	return d.Prepend(NewRDN(attr, value))
+}
+
+func (d DN) Prepend(rdns ...RDN) DN {
+	parts := make([]RDN, 0, len(rdns)+len(d.parts))
+	parts = append(parts, rdns...)
+	return DN{parts: append(parts, d.parts...)}
+}
+
+func (d DN) Append(suffix DN) DN {
+	parts := make([]RDN, 0, len(d.parts)+len(suffix.parts))
+	parts = append(parts, d.parts...)
+	return DN{parts: append(parts, suffix.parts...)}
+}
+
+func (d DN) Rename(rdn RDN) DN {
+	if len(d.parts) == 0 {
+		return DN{parts: []RDN{rdn}}
+	}
+	return d.Parent(1).Prepend(rdn)
+}
+
+func (d DN) Move(parent DN) DN {
+	return parent.Prepend(d.RDN())
+}
+
+func RenamedDN(dn, rdn string) (string, error) {
+	name, err := Explode(dn)
+	if err != nil {
+		return "", err
+	}
+	next, err := ParseRDN(rdn)
+	if err != nil {
+		return "", err
+	}
+	return name.Rename(next).String(), nil
+}
+
+func MovedDN(dn, parent string) (string, error) {
+	name, err := Explode(dn)
+	if err != nil {
+		return "", err
+	}
+	if name.Len() == 0 {
+		return "", fmt.Errorf("%w: empty DN can not be moved", ErrInvalidDN)
+	}
+	superior, err := Explode(parent)
+	if err != nil {
+		return "", err
+	}
+	return name.Move(superior).String(), nil
+}
+
+func NewRDNString(dn, attr, value string) (string, error) {
+	name, err := Explode(dn)
+	if err != nil {
+		return "", err
+	}
+	return name.RDN().Replace(attr, value).String(), nil
+}
+
+func (d DN) Canonical() string {
+	var (
+		domain []string
+		path   []string
+	)
+	for i := len(d.parts) - 1; i >= 0; i-- {
+		a := d.parts[i].attrs[0]
+		if strings.EqualFold(a.Name, "dc") && len(path) == 0 {
+			domain = append([]string{a.Values[0]}, domain...)
+			continue
+		}
+		path = append(path, strings.ReplaceAll(a.Values[0], "/", "\\/"))
+	}
+	if len(path) == 0 {
+		path = append(path, "")
+	}
+	return strings.Join(append([]string{strings.Join(domain, ".")}, path...), "/")
+}
+
+func ParseCanonical(str string) (DN, error) {
+	var (
+		parts []string
+		buf   strings.Builder
+	)
+	for i := 0; i < len(str); i++ {
+		switch c := str[i]; {
+		case c == backslash && i+1 < len(str) && str[i+1] == '/':
+			buf.WriteByte('/')
+			i++
+		case c == '/':
+			parts = append(parts, buf.String())
+			buf.Reset()
+		default:
+			buf.WriteByte(c)
+		}
+	}
+	parts = append(parts, buf.String())
+	if parts[0] == "" {
+		return DN{}, fmt.Errorf("%w: %s: missing domain in canonical name", ErrInvalidDN, str)
+	}
+	var dn DN
+	for _, dc := range strings.Split(parts[0], ".") {
+		if dc == "" {
+			return DN{}, fmt.Errorf("%w: %s: empty domain component", ErrInvalidDN, str)
+		}
+		dn = dn.Append(DN{parts: []RDN{NewRDN("dc", dc)}})
+	}
+	for i, p := range parts[1:] {
+		if p == "" {
+			if i == len(parts)-2 {
+				break
+			}
+			return DN{}, fmt.Errorf("%w: %s: empty path component", ErrInvalidDN, str)
+		}
+		attr := "ou"
+		if i == len(parts)-2 {
+			attr = "cn"
+		}
+		dn = dn.Child(attr, p)
+	}
+	return dn, nil
+}
+
+func (d DN) Depth() int {
+	return d.Len()
+}
+
+func (d DN) IsChildOf(parent DN) bool {
+	return d.Len() == parent.Len()+1 && d.IsDescendantOf(parent)
+}
+
+func (d DN) IsDescendantOf(ancestor DN) bool {
+	if d.Len() <= ancestor.Len() {
+		return false
+	}
+	return d.Parent(d.Len() - ancestor.Len()).Equal(ancestor)
+}
+
+func (d DN) CommonAncestor(other DN) DN {
+	var (
+		left  = d.Normalize()
+		right = other.Normalize()
+		count int
+	)
+	for i, j := left.Len()-1, right.Len()-1; i >= 0 && j >= 0; i, j = i-1, j-1 {
+		if left.parts[i].String() != right.parts[j].String() {
+			break
+		}
+		count++
+	}
+	if count == 0 {
+		return DN{}
+	}
+	return d.Parent(d.Len() - count)
+}
+
 type RDN struct {
 	attrs []Attribute
 }
 
+func NewRDN(attr, value string) RDN {
+	return RDN{
+		attrs: []Attribute{{Name: attr, Values: []string{value}}},
+	}
+}
+
+func (r RDN) Add(attr, value string) RDN {
+	attrs := append([]Attribute{}, r.attrs...)
+	return RDN{attrs: append(attrs, Attribute{Name: attr, Values: []string{value}})}
+}
+
+func ParseRDN(str string) (RDN, error) {
+	dn, err := Explode(str)
+	if err != nil {
+		return RDN{}, err
+	}
+	if dn.Len() != 1 {
+		return RDN{}, fmt.Errorf("%s: not a valid RDN", str)
+	}
+	return dn.RDN(), nil
+}
+
+func (r RDN) MarshalText() ([]byte, error) {
+	return []byte(r.String()), nil
+}
+
+func (r *RDN) UnmarshalText(b []byte) error {
+	rdn, err := ParseRDN(string(b))
+	if err == nil {
+		*r = rdn
+	}
+	return err
+}
+
+func (r RDN) MarshalJSON() ([]byte, error) {
+	return json.Marshal(r.String())
+}
+
+func (r *RDN) UnmarshalJSON(b []byte) error {
+	var str string
+	if err := json.Unmarshal(b, &str); err != nil {
+		return err
+	}
+	return r.UnmarshalText([]byte(str))
+}
+
+func (r RDN) Replace(attr, value string) RDN {
+	var (
+		attrs = make([]Attribute, 0, len(r.attrs))
+		found bool
+	)
+	for _, a := range r.attrs {
+		if strings.EqualFold(a.Name, attr) {
+			a = Attribute{Name: a.Name, Values: []string{value}}
+			found = true
+		}
+		attrs = append(attrs, a)
+	}
+	if !found {
+		return NewRDN(attr, value)
+	}
+	return RDN{attrs: attrs}
+}
+
+func (r RDN) Attributes() []Attribute {
+	attrs := make([]Attribute, len(r.attrs))
+	for i, a := range r.attrs {
+		attrs[i] = Attribute{Name: a.Name, Values: append([]string{}, a.Values...)}
+	}
+	return attrs
+}
+
 func (r RDN) MultiValue() bool {
 	return len(r.attrs) > 1
 }
 
+func (r RDN) Normalize() RDN {
+	n := RDN{
+		attrs: make([]Attribute, len(r.attrs)),
+	}
+	for i, a := range r.attrs {
+		n.attrs[i] = Attribute{
+			Name:   strings.ToLower(a.Name),
+			Values: []string{normalizeRDNValue(a.Name, a.Values[0])},
+		}
+	}
+	sort.Slice(n.attrs, func(i, j int) bool {
+		if n.attrs[i].Name == n.attrs[j].Name {
+			return n.attrs[i].Values[0] < n.attrs[j].Values[0]
+		}
+		return n.attrs[i].Name < n.attrs[j].Name
+	})
+	return n
+}
+
+func normalizeRDNValue(attr, value string) string {
+	rule := MatchingRuleFor(attr)
+	switch str := rule.Canonical(value); rule {
+	case MatchCaseIgnore, MatchCaseExact:
+		return strings.Join(strings.Fields(str), " ")
+	default:
+		return str
+	}
+}
+
 func (r RDN) String() string {
 	var str strings.Builder
 	for i, a := range r.attrs {
@@ -62,14 +376,53 @@ func (r RDN) String() string {
 		}
 		str.WriteString(a.Name)
 		str.WriteRune(equal)
-		str.WriteString(a.Values[0])
+		str.WriteString(EscapeDN(a.Values[0]))
 	}
 	return str.String()
 }
 
+type DNBuilder struct {
+	dn  DN
+	err error
+}
+
+func NewDN(base string) *DNBuilder {
+	var b DNBuilder
+	if base != "" {
+		b.dn, b.err = Explode(base)
+	}
+	return &b
+}
+
+func (b *DNBuilder) Child(attr, value string) *DNBuilder {
+	if b.err == nil {
+		b.dn = b.dn.Child(attr, value)
+	}
+	return b
+}
+
+func (b *DNBuilder) DN() (DN, error) {
+	return b.dn, b.err
+}
+
+func (b *DNBuilder) String() string {
+	if b.err != nil {
+		return ""
+	}
+	return b.dn.String()
+}
+
 func Explode(dn string) (DN, error) {
 	if !utf8.ValidString(dn) {
-		return DN{}, fmt.Errorf("%s: not a valid DN", dn)
+		pos := 0
+		for pos < len(dn) {
+			r, z := utf8.DecodeRuneInString(dn[pos:])
+			if r == utf8.RuneError && z <= 1 {
+				break
+			}
+			pos += z
+		}
+		return DN{}, &DNSyntaxError{Offset: pos, Reason: "not valid utf-8"}
 	}
 	return explodeDN(strings.NewReader(dn))
 }
@@ -88,7 +441,10 @@ func explodeDN(str *strings.Reader) (DN, error) {
 				return dn, err
 			}
 			rdn.attrs = append(rdn.attrs, a)
-			if last == 0 || last == comma {
+			if last != 0 && str.Len() == 0 {
+				return dn, dnError(str, "trailing separator")
+			}
+			if last != plus {
 				break
 			}
 		}
@@ -102,25 +458,31 @@ func readAttrType(str *strings.Reader, a *Attribute) error {
 		buf    strings.Builder
 		accept func(rune) bool
 	)
-	switch r, _, _ := str.ReadRune(); {
+	r := skipSpaces(str)
+	switch {
 	case isDigit(r):
 		accept = acceptOID
 	case isLetter(r):
 		accept = acceptShortName
 	default:
-		return fmt.Errorf("unexpected character in attribute type")
+		return dnError(str, "unexpected character in attribute type")
 	}
 	str.UnreadRune()
 	for {
 		r, _, err := str.ReadRune()
 		if err != nil {
-			return err
+			return dnError(str, "missing equal sign after attribute type")
+		}
+		if r == space {
+			if r = skipSpaces(str); r != equal {
+				return dnError(str, "unexpected space in attribute type")
+			}
 		}
 		if r == equal {
 			break
 		}
 		if !accept(r) {
-			return fmt.Errorf("unexpected character in attribute type")
+			return dnError(str, "unexpected character in attribute type")
 		}
 		buf.WriteRune(r)
 	}
@@ -129,23 +491,91 @@ func readAttrType(str *strings.Reader, a *Attribute) error {
 }
 
 func readAttrValue(str *strings.Reader, a *Attribute) (rune, error) {
+	if r := skipSpaces(str); r == dquote {
+		return readQuotedValue(str, a)
+	} else if r != 0 {
+		str.UnreadRune()
+	}
 	var (
 		buf  strings.Builder
 		last rune
 	)
 	for str.Len() > 0 {
+		r, _, _ := str.ReadRune()
+		if r == comma || r == plus || r == semicolon {
+			last = r
+			if r == semicolon {
+				last = comma
+			}
+			break
+		}
+		switch r {
+		case dquote, langle, rangle:
+			return last, dnError(str, fmt.Sprintf("unescaped %q in attribute value", r))
+		case backslash:
+			next, _, err := str.ReadRune()
+			if err != nil {
+				return last, dnError(str, "unexpected end of value after escape")
+			}
+			buf.WriteRune(r)
+			buf.WriteRune(next)
+			continue
+		}
+		buf.WriteRune(r)
+	}
+	value, err := UnescapeDN(buf.String())
+	if err != nil {
+		return last, dnError(str, err.Error())
+	}
+	a.Values = append(a.Values, value)
+	return last, nil
+}
+
+func readQuotedValue(str *strings.Reader, a *Attribute) (rune, error) {
+	var buf strings.Builder
+	for {
 		r, _, err := str.ReadRune()
-		if err != nil && !errors.Is(err, io.EOF) {
-			return r, err
+		if err != nil {
+			return 0, dnError(str, "unterminated quoted value")
 		}
-		if r == comma || r == plus {
-			last = r
+		if r == dquote {
 			break
 		}
+		if r == backslash {
+			if r, _, err = str.ReadRune(); err != nil {
+				return 0, dnError(str, "unexpected end of value after escape")
+			}
+		}
 		buf.WriteRune(r)
 	}
 	a.Values = append(a.Values, buf.String())
-	return last, nil
+	switch r := skipSpaces(str); r {
+	case 0:
+		return 0, nil
+	case comma, semicolon:
+		return comma, nil
+	case plus:
+		return plus, nil
+	default:
+		return 0, dnError(str, "unexpected character after quoted value")
+	}
+}
+
+func skipSpaces(str *strings.Reader) rune {
+	for {
+		r, _, err := str.ReadRune()
+		if err != nil {
+			return 0
+		}
+		if r != space {
+			return r
+		}
+	}
+}
+
+func dnError(str *strings.Reader, msg string) error {
+	pos := str.Size() - int64(str.Len())
+	return &DNSyntaxError{Offset: int(pos), Reason: msg}
 }
 
 func acceptOID(r rune) bool {
@@ -153,5 +583,81 @@ func acceptOID(r rune) bool {
 }
 
 func acceptShortName(r rune) bool {
-	return isLetter(r) || r == minus
+	return isLetter(r) || isDigit(r) || r == minus
+}
+
+func EscapeDN(value string) string {
+	var str strings.Builder
+	for i := 0; i < len(value); i++ {
+		b := value[i]
+		switch {
+		case b == comma || b == plus || b == dquote || b == backslash || b == semicolon || b == langle || b == rangle:
+			str.WriteByte(backslash)
+			str.WriteByte(b)
+		case b == sharp && i == 0:
+			str.WriteByte(backslash)
+			str.WriteByte(b)
+		case b == space && (i == 0 || i == len(value)-1):
+			str.WriteByte(backslash)
+			str.WriteByte(b)
+		case b == null:
+			str.WriteString("\\00")
+		default:
+			str.WriteByte(b)
+		}
+	}
+	return str.String()
+}
+
+func UnescapeDN(value string) (string, error) {
+	if strings.HasPrefix(value, string(sharp)) {
+		return unescapeHexString(strings.TrimSpace(value[1:]))
+	}
+	var (
+		buf     []byte
+		spaces  int
+		started bool
+	)
+	for i := 0; i < len(value); i++ {
+		b := value[i]
+		switch {
+		case b == space:
+			if started {
+				spaces++
+			}
+			continue
+		case b == backslash:
+			if i+1 >= len(value) {
+				return "", fmt.Errorf("%s: unexpected end of value after escape", value)
+			}
+			if i+2 < len(value) && isHex(value[i+1]) && isHex(value[i+2]) {
+				x, _ := hex.DecodeString(value[i+1 : i+3])
+				b, i = x[0], i+2
+			} else {
+				b, i = value[i+1], i+1
+			}
+		}
+		for ; spaces > 0; spaces-- {
+			buf = append(buf, space)
+		}
+		started = true
+		buf = append(buf, b)
+	}
+	return string(buf), nil
+}
+
+func unescapeHexString(value string) (string, error) {
+	b, err := hex.DecodeString(value)
+	if err != nil {
+		return "", fmt.Errorf("#%s: invalid hex string (%w)", value, err)
+	}
+	_, body, rest, err := decodeElement(b)
+	if err != nil || len(rest) > 0 {
+		return string(b), nil
+	}
+	return string(body), nil
+}
+
+func isHex(b byte) bool {
+	return (b >= '0' && b <= '9') || (b >= 'a' && b <= 'f') || (b >= 'A' && b <= 'F')
 }
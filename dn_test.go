@@ -0,0 +1,60 @@
+package ldap
+
+import (
+	"testing"
+)
+
+func TestDNEqual(t *testing.T) {
+	data := []struct {
+		Left  string
+		Right string
+		Want  bool
+	}{
+		{Left: "cn=John Doe,dc=example,dc=org", Right: "CN=john doe,DC=Example,DC=ORG", Want: true},
+		{Left: "cn=John  Doe ,dc=example,dc=org", Right: "cn=john doe,dc=example,dc=org", Want: true},
+		{Left: `cn=John\20Doe,dc=example,dc=org`, Right: "cn=john doe,dc=example,dc=org", Want: true},
+		{Left: "cn=a+sn=b,dc=example,dc=org", Right: "SN=B+CN=A,dc=example,dc=org", Want: true},
+		{Left: "uidNumber=0100,dc=example,dc=org", Right: "uidNumber=100,dc=example,dc=org", Want: true},
+		{Left: `telephoneNumber=\+1 555-0100,dc=example,dc=org`, Right: `telephoneNumber=\+15550100,dc=example,dc=org`, Want: true},
+		{Left: "cn=John Doe,dc=example,dc=org", Right: "cn=JohnDoe,dc=example,dc=org", Want: false},
+		{Left: "cn=John Doe,dc=example,dc=org", Right: "cn=John Doe,dc=example,dc=com", Want: false},
+		{Left: "cn=a+sn=b,dc=example,dc=org", Right: "cn=a,dc=example,dc=org", Want: false},
+	}
+	for _, d := range data {
+		left, err := Explode(d.Left)
+		if err != nil {
+			t.Fatalf("%s: %s", d.Left, err)
+		}
+		right, err := Explode(d.Right)
+		if err != nil {
+			t.Fatalf("%s: %s", d.Right, err)
+		}
+		if got := left.Equal(right); got != d.Want {
+			t.Errorf("%s == %s: want %t, got %t", d.Left, d.Right, d.Want, got)
+		}
+	}
+}
+
+func TestDNNormalize(t *testing.T) {
+	data := []struct {
+		Input string
+		Want  string
+	}{
+		{Input: "CN=John Doe,DC=Example,DC=ORG", Want: "cn=john doe,dc=example,dc=org"},
+		{Input: "cn=John  Doe ,dc=example,dc=org", Want: "cn=john doe,dc=example,dc=org"},
+		{Input: `cn=\ foo\ ,dc=example`, Want: "cn=foo,dc=example"},
+		{Input: "cn=foo,dc=example", Want: "cn=foo,dc=example"},
+		{Input: "SN=B+CN=A,dc=example", Want: "cn=a+sn=b,dc=example"},
+		{Input: "uidNumber=0100,dc=example", Want: "uidnumber=100,dc=example"},
+		{Input: `cn=a\,b,dc=example`, Want: `cn=a\,b,dc=example`},
+	}
+	for _, d := range data {
+		dn, err := Explode(d.Input)
+		if err != nil {
+			t.Fatalf("%s: %s", d.Input, err)
+		}
+		if got := dn.Normalize().String(); got != d.Want {
+			t.Errorf("%s: normalize mismatch: want %s, got %s", d.Input, d.Want, got)
+		}
+	}
+}
@@ -0,0 +1,287 @@
+package ldap
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+var ErrMissingAttribute = errors.New("attribute not found")
+
+func (e Entry) Has(name string) bool {
+	return hasAttribute(e.Attrs, name)
+}
+
+func (e Entry) GetValue(name string) string {
+	values := e.GetValues(name)
+	if len(values) == 0 {
+		return ""
+	}
+	return values[0]
+}
+
+func (e Entry) GetValues(name string) []string {
+	var values []string
+	for _, a := range e.Attrs {
+		if !matchAttribute(a, name) {
+			continue
+		}
+		values = append(values, a.Values...)
+	}
+	dec, ok := decoders[strings.ToLower(ParseAttributeDescription(name).Type)]
+	if !ok {
+		return values
+	}
+	for i := range values {
+		if v, err := dec([]byte(values[i])); err == nil {
+			values[i] = v
+		}
+	}
+	return values
+}
+
+func (e Entry) GetBytes(name string) []byte {
+	for _, a := range e.Attrs {
+		if matchAttribute(a, name) && len(a.Values) > 0 {
+			return []byte(a.Values[0])
+		}
+	}
+	return nil
+}
+
+func (e Entry) GetInt(name string) (int64, error) {
+	str, err := e.lookup(name)
+	if err != nil {
+		return 0, err
+	}
+	return strconv.ParseInt(strings.TrimSpace(str), 10, 64)
+}
+
+func (e Entry) GetBool(name string) (bool, error) {
+	str, err := e.lookup(name)
+	if err != nil {
+		return false, err
+	}
+	switch strings.ToUpper(strings.TrimSpace(str)) {
+	case "TRUE":
+		return true, nil
+	case "FALSE":
+		return false, nil
+	default:
+		return false, fmt.Errorf("%s: invalid boolean value %q", name, str)
+	}
+}
+
+func (e Entry) GetTime(name string) (time.Time, error) {
+	str, err := e.lookup(name)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return ParseGeneralizedTime(str)
+}
+
+func (e Entry) lookup(name string) (string, error) {
+	values := e.GetValues(name)
+	if len(values) == 0 {
+		return "", fmt.Errorf("%s: %w", name, ErrMissingAttribute)
+	}
+	return values[0], nil
+}
+
+func ParseGeneralizedTime(str string) (time.Time, error) {
+	var (
+		zone *time.Location
+		body = str
+	)
+	switch {
+	case strings.HasSuffix(body, "Z"):
+		zone, body = time.UTC, body[:len(body)-1]
+	case len(body) > 5 && (body[len(body)-5] == plus || body[len(body)-5] == minus):
+		off := body[len(body)-5:]
+		hh, err1 := strconv.Atoi(off[1:3])
+		mm, err2 := strconv.Atoi(off[3:])
+		if err1 != nil || err2 != nil {
+			return time.Time{}, fmt.Errorf("%s: invalid time zone", str)
+		}
+		secs := hh*3600 + mm*60
+		if off[0] == minus {
+			secs = -secs
+		}
+		zone, body = time.FixedZone(off, secs), body[:len(body)-5]
+	default:
+		zone = time.Local
+	}
+	var frac string
+	if x := strings.IndexAny(body, ".,"); x >= 0 {
+		body, frac = body[:x], body[x+1:]
+	}
+	layout := "2006010215"
+	switch len(body) {
+	case 10:
+	case 12:
+		layout += "04"
+	case 14:
+		layout += "0405"
+	default:
+		return time.Time{}, fmt.Errorf("%s: invalid generalized time", str)
+	}
+	t, err := time.ParseInLocation(layout, body, zone)
+	if err != nil {
+		return t, fmt.Errorf("%s: invalid generalized time (%w)", str, err)
+	}
+	if frac != "" {
+		f, err := strconv.ParseFloat("0."+frac, 64)
+		if err != nil {
+			return t, fmt.Errorf("%s: invalid fraction", str)
+		}
+		var unit time.Duration
+		switch len(body) {
+		case 10:
+			unit = time.Hour
+		case 12:
+			unit = time.Minute
+		default:
+			unit = time.Second
+		}
+		t = t.Add(time.Duration(f * float64(unit)))
+	}
+	return t, nil
+}
+
+func FormatGeneralizedTime(t time.Time) string {
+	return t.UTC().Format(generalizedTime)
+}
+
+type jsonEntry struct {
+	Name   string              `json:"dn"`
+	Attrs  map[string][]string `json:"attrs"`
+	Binary []string            `json:"binary,omitempty"`
+}
+
+func (e Entry) MarshalJSON() ([]byte, error) {
+	je := jsonEntry{
+		Name:  e.Name,
+		Attrs: make(map[string][]string),
+	}
+	for _, a := range groupAttributes(e.Attrs).attrs {
+		if !isBinaryJSON(a) {
+			je.Attrs[a.Name] = a.Values
+			continue
+		}
+		values := make([]string, len(a.Values))
+		for i := range a.Values {
+			values[i] = base64.StdEncoding.EncodeToString([]byte(a.Values[i]))
+		}
+		je.Attrs[a.Name] = values
+		je.Binary = append(je.Binary, a.Name)
+	}
+	sort.Strings(je.Binary)
+	return json.Marshal(je)
+}
+
+func (e *Entry) UnmarshalJSON(b []byte) error {
+	var je jsonEntry
+	if err := json.Unmarshal(b, &je); err != nil {
+		return err
+	}
+	binary := make(map[string]struct{})
+	for _, n := range je.Binary {
+		binary[n] = struct{}{}
+	}
+	names := make([]string, 0, len(je.Attrs))
+	for n := range je.Attrs {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+
+	e.Name = je.Name
+	e.Attrs = e.Attrs[:0]
+	for _, n := range names {
+		a := Attribute{
+			Name:   n,
+			Values: je.Attrs[n],
+		}
+		if _, ok := binary[n]; ok {
+			for i := range a.Values {
+				v, err := base64.StdEncoding.DecodeString(a.Values[i])
+				if err != nil {
+					return fmt.Errorf("%s: invalid base64 value (%w)", n, err)
+				}
+				a.Values[i] = string(v)
+			}
+		}
+		e.Attrs = append(e.Attrs, a)
+	}
+	return nil
+}
+
+func isBinaryJSON(a Attribute) bool {
+	desc := a.Description()
+	if _, ok := decoders[strings.ToLower(desc.Type)]; ok || desc.Binary() {
+		return true
+	}
+	for _, v := range a.Values {
+		if !utf8.ValidString(v) {
+			return true
+		}
+	}
+	return false
+}
+
+type MergePolicy uint8
+
+const (
+	MergeKeep MergePolicy = iota
+	MergeOverwrite
+	MergeUnion
+)
+
+func (e Entry) Clone() Entry {
+	c := Entry{
+		Name:  e.Name,
+		Attrs: make([]Attribute, len(e.Attrs)),
+	}
+	for i, a := range e.Attrs {
+		c.Attrs[i] = Attribute{
+			Name:      a.Name,
+			Values:    append([]string{}, a.Values...),
+			Truncated: a.Truncated,
+		}
+	}
+	return c
+}
+
+func Merge(dst, src Entry, policy MergePolicy) Entry {
+	var (
+		set    = groupAttributes(dst.Attrs)
+		merged = Entry{Name: dst.Name}
+	)
+	if merged.Name == "" {
+		merged.Name = src.Name
+	}
+	for _, a := range groupAttributes(src.Attrs).attrs {
+		key := strings.ToLower(a.Name)
+		x, ok := set.index[key]
+		if !ok {
+			set.index[key] = len(set.attrs)
+			set.attrs = append(set.attrs, a)
+			continue
+		}
+		switch policy {
+		case MergeOverwrite:
+			set.attrs[x].Values = a.Values
+		case MergeUnion:
+			values := subtractValues(a.Name, a.Values, set.attrs[x].Values)
+			set.attrs[x].Values = append(set.attrs[x].Values, values...)
+		default:
+		}
+	}
+	merged.Attrs = set.attrs
+	return merged
+}
@@ -0,0 +1,158 @@
+package ldap
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+func Match(f Filter, e Entry) (bool, error) {
+	switch f := f.(type) {
+	case relational:
+		for _, i := range f.filters {
+			ok, err := Match(i, e)
+			if err != nil {
+				return false, err
+			}
+			if f.tag == tagFilterAnd && !ok {
+				return false, nil
+			}
+			if f.tag == tagFilterOr && ok {
+				return true, nil
+			}
+		}
+		return f.tag == tagFilterAnd, nil
+	case not:
+		ok, err := Match(f.inner, e)
+		return !ok, err
+	case present:
+		return hasAttribute(e.Attrs, f.attr), nil
+	case compare:
+		return evalCompare(f, e), nil
+	case substring:
+		return evalSubstring(f, e), nil
+	case extensible:
+		return evalExtensible(f, e), nil
+	case nil:
+		return false, fmt.Errorf("%w: no filter given", ErrSyntax)
+	default:
+		return false, fmt.Errorf("%s: unsupported filter", f)
+	}
+}
+
+func evalCompare(f compare, e Entry) bool {
+	rule := MatchingRuleFor(f.left)
+	for _, v := range e.GetValues(f.left) {
+		var cmp int
+		if rule == MatchInteger {
+			left, err1 := strconv.ParseInt(strings.TrimSpace(v), 10, 64)
+			right, err2 := strconv.ParseInt(strings.TrimSpace(f.right), 10, 64)
+			if err1 != nil || err2 != nil {
+				continue
+			}
+			switch {
+			case left < right:
+				cmp = -1
+			case left > right:
+				cmp = 1
+			}
+		} else {
+			cmp = strings.Compare(rule.Canonical(v), rule.Canonical(f.right))
+		}
+		switch f.tag {
+		case tagFilterEquality, tagFilterApprox:
+			if cmp == 0 {
+				return true
+			}
+		case tagFilterGreaterEq:
+			if cmp >= 0 {
+				return true
+			}
+		case tagFilterLesserEq:
+			if cmp <= 0 {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func evalSubstring(f substring, e Entry) bool {
+	rule := MatchingRuleFor(f.attr)
+	for _, v := range e.GetValues(f.attr) {
+		v = rule.Canonical(v)
+		if f.pre != "" {
+			pre := substringValue(rule, f.pre, subInitial)
+			if !strings.HasPrefix(v, pre) {
+				continue
+			}
+			v = v[len(pre):]
+		}
+		if f.post != "" {
+			post := substringValue(rule, f.post, subFinal)
+			if !strings.HasSuffix(v, post) {
+				continue
+			}
+			v = v[:len(v)-len(post)]
+		}
+		ok := true
+		for _, a := range f.any {
+			a = substringValue(rule, a, subAny)
+			x := strings.Index(v, a)
+			if x < 0 {
+				ok = false
+				break
+			}
+			v = v[x+len(a):]
+		}
+		if ok {
+			return true
+		}
+	}
+	return false
+}
+
+func substringValue(rule MatchingRule, value string, pos uint64) string {
+	var prep Preparation
+	switch rule {
+	case MatchCaseIgnore:
+		prep = PrepCaseIgnore
+	case MatchCaseExact:
+		prep = PrepExact
+	case MatchNumeric:
+		prep = PrepNumeric
+	case MatchTelephone:
+		prep = PrepTelephone
+	default:
+		return rule.Canonical(value)
+	}
+	str, err := prepareString(value, prep, pos)
+	if err != nil {
+		return value
+	}
+	return str
+}
+
+func evalExtensible(f extensible, e Entry) bool {
+	var values []string
+	if f.attr != "" {
+		values = e.GetValues(f.attr)
+	}
+	if f.dn {
+		if dn, err := Explode(e.Name); err == nil {
+			for _, rdn := range dn.parts {
+				for _, a := range rdn.attrs {
+					if f.attr == "" || strings.EqualFold(a.Name, f.attr) {
+						values = append(values, a.Values[0])
+					}
+				}
+			}
+		}
+	}
+	for _, v := range values {
+		if MatchValues(f.attr, v, f.value) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,73 @@
+package ldap
+
+import (
+	"testing"
+)
+
+func TestMatch(t *testing.T) {
+	e := Entry{
+		Name: "cn=John Doe,ou=people,dc=example,dc=org",
+		Attrs: []Attribute{
+			{Name: "objectClass", Values: []string{"top", "person", "inetOrgPerson"}},
+			{Name: "cn", Values: []string{"John Doe"}},
+			{Name: "sn", Values: []string{"Doe"}},
+			{Name: "mail", Values: []string{"john.doe@example.org"}},
+			{Name: "uidNumber", Values: []string{"1000"}},
+			{Name: "telephoneNumber", Values: []string{"+1 555-0100"}},
+		},
+	}
+	data := []struct {
+		Filter string
+		Want   bool
+	}{
+		{Filter: "(cn=John Doe)", Want: true},
+		{Filter: "(cn=john doe)", Want: true},
+		{Filter: "(cn=  John   Doe )", Want: true},
+		{Filter: "(cn=Jane Doe)", Want: false},
+		{Filter: "(uidNumber=1000)", Want: true},
+		{Filter: "(uidNumber=01000)", Want: true},
+		{Filter: "(telephoneNumber=+15550100)", Want: true},
+		{Filter: "(cn=jo*)", Want: true},
+		{Filter: "(cn=John D*)", Want: true},
+		{Filter: "(cn=*oh*)", Want: true},
+		{Filter: "(cn=*Do*)", Want: true},
+		{Filter: "(cn=*doe)", Want: true},
+		{Filter: "(cn=j*n*e)", Want: true},
+		{Filter: "(cn=*ohn d*)", Want: true},
+		{Filter: "(cn=*xyz*)", Want: false},
+		{Filter: "(cn=doe*)", Want: false},
+		{Filter: "(cn=*john)", Want: false},
+		{Filter: "(cn=john*john)", Want: false},
+		{Filter: "(mail=*@example.org)", Want: true},
+		{Filter: "(cn=*)", Want: true},
+		{Filter: "(mail=*)", Want: true},
+		{Filter: "(description=*)", Want: false},
+		{Filter: "(uidNumber>=999)", Want: true},
+		{Filter: "(uidNumber>=1000)", Want: true},
+		{Filter: "(uidNumber>=1001)", Want: false},
+		{Filter: "(uidNumber<=1000)", Want: true},
+		{Filter: "(uidNumber<=999)", Want: false},
+		{Filter: "(uidNumber<=10000)", Want: true},
+		{Filter: "(sn>=Doa)", Want: true},
+		{Filter: "(sn<=Doa)", Want: false},
+		{Filter: "(&(objectClass=person)(cn=jo*))", Want: true},
+		{Filter: "(&(objectClass=person)(cn=jane*))", Want: false},
+		{Filter: "(|(cn=jane*)(sn=doe))", Want: true},
+		{Filter: "(!(cn=jane*))", Want: true},
+	}
+	for _, d := range data {
+		f, err := ParseFilter(d.Filter)
+		if err != nil {
+			t.Errorf("%s: unexpected error parsing filter: %s", d.Filter, err)
+			continue
+		}
+		got, err := Match(f, e)
+		if err != nil {
+			t.Errorf("%s: unexpected error matching entry: %s", d.Filter, err)
+			continue
+		}
+		if got != d.Want {
+			t.Errorf("%s: match mismatch: want %t, got %t", d.Filter, d.Want, got)
+		}
+	}
+}
@@ -0,0 +1,192 @@
+package ldap
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+const (
+	exportMarker    = "ldap-export:"
+	defaultPageSize = 500
+)
+
+var ErrResume = errors.New("resume point not found")
+
+type Checkpoint struct {
+	Base   string `json:"base"`
+	Filter string `json:"filter"`
+	Cookie []byte `json:"cookie,omitempty"`
+	Last   string `json:"last,omitempty"`
+	Count  int    `json:"count"`
+	Offset int64  `json:"offset"`
+	Done   bool   `json:"done,omitempty"`
+}
+
+func ReadCheckpoint(file string) (Checkpoint, error) {
+	var cp Checkpoint
+	r, err := os.Open(file)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			err = nil
+		}
+		return cp, err
+	}
+	defer r.Close()
+	if err := json.NewDecoder(r).Decode(&cp); err != nil {
+		return cp, fmt.Errorf("%s: invalid checkpoint (%w)", file, err)
+	}
+	return cp, nil
+}
+
+func (cp Checkpoint) WriteFile(file string) error {
+	buf, err := json.Marshal(cp)
+	if err != nil {
+		return err
+	}
+	tmp := file + ".tmp"
+	if err := os.WriteFile(tmp, buf, 0o600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, file)
+}
+
+type Export struct {
+	Filter   Filter
+	Page     int
+	State    string
+	Options  []SearchOption
+	Progress func(Checkpoint)
+}
+
+func (c *Client) Export(w io.Writer, base string, ex Export) (Checkpoint, error) {
+	filter := ex.Filter
+	if filter == nil {
+		filter = Present(attrObjectClass)
+	}
+	cp := Checkpoint{
+		Base:   base,
+		Filter: filter.String(),
+	}
+	if ex.State != "" {
+		prev, err := ReadCheckpoint(ex.State)
+		if err != nil {
+			return cp, err
+		}
+		if prev.Base != "" {
+			if !strings.EqualFold(prev.Base, cp.Base) || prev.Filter != cp.Filter {
+				return cp, fmt.Errorf("%s: checkpoint does not match export of %s %s", ex.State, base, cp.Filter)
+			}
+			cp = prev
+		}
+	}
+	if cp.Done {
+		return cp, nil
+	}
+	size := ex.Page
+	if size <= 0 {
+		size = defaultPageSize
+	}
+	ew := exportWriter{
+		Writer: w,
+		offset: cp.Offset,
+	}
+	lw := NewLDIFWriter(&ew)
+	if cp.Offset > 0 {
+		lw.version = true
+	} else {
+		lw.WriteComment(fmt.Sprintf("%s base=%s filter=%s", exportMarker, cp.Base, cp.Filter))
+	}
+	save := func(done bool) error {
+		cp.Done = done
+		if done {
+			lw.WriteComment(fmt.Sprintf("%s complete entries=%d", exportMarker, cp.Count))
+		} else {
+			lw.WriteComment(fmt.Sprintf("%s checkpoint entries=%d", exportMarker, cp.Count))
+		}
+		if ew.err != nil {
+			return ew.err
+		}
+		cp.Offset = ew.offset
+		if ex.Progress != nil {
+			ex.Progress(cp)
+		}
+		if ex.State == "" {
+			return nil
+		}
+		return cp.WriteFile(ex.State)
+	}
+
+	skip := len(cp.Cookie) == 0 && cp.Last != ""
+	for {
+		var (
+			count   int
+			options = append([]SearchOption{WithScope(ScopeWhole)}, ex.Options...)
+		)
+		options = append(options, WithFilter(filter), WithControl(Paginate(size, cp.Cookie)))
+		values, err := c.Stream(base, func(e Entry) error {
+			count++
+			if skip {
+				skip = !strings.EqualFold(e.Name, cp.Last)
+				return nil
+			}
+			if err := lw.WriteEntry(e); err != nil {
+				return err
+			}
+			cp.Count++
+			cp.Last = e.Name
+			if count > size && count%size == 0 {
+				cp.Cookie = nil
+				return save(false)
+			}
+			return nil
+		}, options...)
+		if err != nil {
+			if len(cp.Cookie) > 0 && count == 0 {
+				cp.Cookie, skip = nil, cp.Last != ""
+				continue
+			}
+			return cp, err
+		}
+		cp.Cookie = nil
+		for _, v := range values {
+			if v.OID != CtrlPaginateOID {
+				continue
+			}
+			if p, err := v.AsPaginate(); err == nil {
+				cp.Cookie = p.Cookie
+			}
+		}
+		if len(cp.Cookie) == 0 {
+			if skip {
+				return cp, fmt.Errorf("%s: %w", cp.Last, ErrResume)
+			}
+			return cp, save(true)
+		}
+		if skip {
+			continue
+		}
+		if err := save(false); err != nil {
+			return cp, err
+		}
+	}
+}
+
+type exportWriter struct {
+	io.Writer
+	offset int64
+	err    error
+}
+
+func (w *exportWriter) Write(b []byte) (int, error) {
+	if w.err != nil {
+		return 0, w.err
+	}
+	n, err := w.Writer.Write(b)
+	w.offset += int64(n)
+	w.err = err
+	return n, err
+}
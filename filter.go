@@ -30,12 +30,67 @@ const (
 	tagFilterExtensible
 )
 
+type FilterKind uint64
+
+const (
+	FilterAnd FilterKind = iota
+	FilterOr
+	FilterNot
+	FilterEqual
+	FilterSubstring
+	FilterGreaterEq
+	FilterLessEq
+	FilterPresent
+	FilterApprox
+	FilterExtensible
+)
+
+func (k FilterKind) String() string {
+	switch k {
+	case FilterAnd:
+		return "and"
+	case FilterOr:
+		return "or"
+	case FilterNot:
+		return "not"
+	case FilterEqual:
+		return "equal"
+	case FilterSubstring:
+		return "substring"
+	case FilterGreaterEq:
+		return "greater-or-equal"
+	case FilterLessEq:
+		return "less-or-equal"
+	case FilterPresent:
+		return "present"
+	case FilterApprox:
+		return "approx"
+	case FilterExtensible:
+		return "extensible"
+	default:
+		return "unknown"
+	}
+}
+
 type Filter interface {
 	Not() Filter
+	Kind() FilterKind
+	Attribute() string
+	Value() string
+	Children() []Filter
 	ber.Marshaler
 	fmt.Stringer
 }
 
+func Walk(f Filter, fn func(Filter) bool) {
+	if f == nil || !fn(f) {
+		return
+	}
+	for _, c := range f.Children() {
+		Walk(c, fn)
+	}
+}
+
 func ParseFilter(str string) (Filter, error) {
 	if str == "" {
 		return Present("objectClass"), nil
@@ -43,7 +98,50 @@ func ParseFilter(str string) (Filter, error) {
 	if !utf8.ValidString(str) {
 		return nil, fmt.Errorf("%s: invalid utf8 string", str)
 	}
-	return parseFilter(scan(str))
+	s := scan(str)
+	filter, err := parseFilter(s)
+	if err == nil && s.next < len(s.input) {
+		s.Next()
+		err = expected(syntaxError("unexpected characters after filter"))
+	}
+	if err != nil {
+		return nil, s.filterError(err)
+	}
+	return filter, nil
+}
+
+type FilterError struct {
+	Input    string
+	Offset   int
+	Token    string
+	Expected []string
+	Err      error
+}
+
+func (e *FilterError) Error() string {
+	var str strings.Builder
+	str.WriteString(e.Err.Error())
+	if e.Token == "" {
+		str.WriteString(" at end of filter")
+	} else {
+		str.WriteString(fmt.Sprintf(" at offset %d (%q)", e.Offset, e.Token))
+	}
+	if len(e.Expected) > 0 {
+		str.WriteString(", expected ")
+		str.WriteString(strings.Join(e.Expected, " or "))
+	}
+	return str.String()
+}
+
+func (e *FilterError) Unwrap() error {
+	return e.Err
+}
+
+func expected(err error, tokens ...string) error {
+	return &FilterError{
+		Err:      err,
+		Expected: tokens,
+	}
 }
 
 type compare struct {
@@ -78,23 +176,18 @@ func createCompareFilter(attr, value string, tag uint64) Filter {
 
 func (c compare) String() string {
 	var str strings.Builder
+	str.WriteRune(lparen)
+	str.WriteString(c.left)
 	switch c.tag {
-	default:
-		str.WriteString("other")
-	case tagFilterEquality:
-		str.WriteString("eq")
 	case tagFilterLesserEq:
-		str.WriteString("le")
+		str.WriteRune(langle)
 	case tagFilterGreaterEq:
-		str.WriteString("ge")
+		str.WriteRune(rangle)
 	case tagFilterApprox:
-		str.WriteString("approx")
+		str.WriteRune(tilde)
 	}
-	str.WriteRune(lparen)
-	str.WriteString(c.left)
-	str.WriteRune(colon)
-	str.WriteRune(space)
-	str.WriteString(c.right)
+	str.WriteRune(equal)
+	str.WriteString(EscapeFilterValue(c.right))
 	str.WriteRune(rparen)
 	return str.String()
 }
@@ -122,6 +215,22 @@ func (c compare) Not() Filter {
 	return Not(c)
 }
 
+func (c compare) Kind() FilterKind {
+	return FilterKind(c.tag)
+}
+
+func (c compare) Attribute() string {
+	return c.left
+}
+
+func (c compare) Value() string {
+	return c.right
+}
+
+func (c compare) Children() []Filter {
+	return nil
+}
+
 type relational struct {
 	filters []Filter `ber:"set"`
 	tag     uint64
@@ -143,19 +252,13 @@ func Or(filters ...Filter) Filter {
 
 func (r relational) String() string {
 	var str strings.Builder
-	switch r.tag {
-	case tagFilterAnd:
-		str.WriteString("and")
-	case tagFilterOr:
-		str.WriteString("or")
-	default:
-		str.WriteString("other")
-	}
 	str.WriteRune(lparen)
+	if r.tag == tagFilterAnd {
+		str.WriteRune(ampersand)
+	} else {
+		str.WriteRune(pipe)
+	}
 	for i := range r.filters {
-		if i > 0 {
-			str.WriteString(", ")
-		}
 		str.WriteString(r.filters[i].String())
 	}
 	str.WriteRune(rparen)
@@ -175,6 +278,22 @@ func (r relational) Marshal() ([]byte, error) {
 	return e.As(ber.NewConstructed(r.tag).Context())
 }
 
+func (r relational) Kind() FilterKind {
+	return FilterKind(r.tag)
+}
+
+func (r relational) Attribute() string {
+	return ""
+}
+
+func (r relational) Value() string {
+	return ""
+}
+
+func (r relational) Children() []Filter {
+	return append([]Filter{}, r.filters...)
+}
+
 func (r relational) Not() Filter {
 	return Not(r)
 }
@@ -188,7 +307,7 @@ func Not(f Filter) Filter {
 }
 
 func (n not) String() string {
-	return fmt.Sprintf("not(%s)", n.inner)
+	return fmt.Sprintf("(!%s)", n.inner)
 }
 
 func (n not) Marshal() ([]byte, error) {
@@ -201,6 +320,22 @@ func (n not) Not() Filter {
 	return n.inner
 }
 
+func (n not) Kind() FilterKind {
+	return FilterNot
+}
+
+func (n not) Attribute() string {
+	return ""
+}
+
+func (n not) Value() string {
+	return ""
+}
+
+func (n not) Children() []Filter {
+	return []Filter{n.inner}
+}
+
 type present struct {
 	attr string
 }
@@ -210,7 +345,7 @@ func Present(attr string) Filter {
 }
 
 func (p present) String() string {
-	return fmt.Sprintf("present(%s)", p.attr)
+	return fmt.Sprintf("(%s=*)", p.attr)
 }
 
 func (p present) Marshal() ([]byte, error) {
@@ -229,6 +364,22 @@ func (p present) Not() Filter {
 	return Not(p)
 }
 
+func (p present) Kind() FilterKind {
+	return FilterPresent
+}
+
+func (p present) Attribute() string {
+	return p.attr
+}
+
+func (p present) Value() string {
+	return ""
+}
+
+func (p present) Children() []Filter {
+	return nil
+}
+
 type substring struct {
 	attr string
 	pre  string
@@ -243,22 +394,32 @@ func Substring(attr string, values []string) Filter {
 	if len(values) == 0 {
 		return s
 	}
-	n := len(values) - 1
-	if values[0] != "" {
-		s.pre = values[0]
-	}
-	values = values[1:]
-	n--
-	if n > 0 && values[n] != "" {
+	s.pre = values[0]
+	if n := len(values) - 1; n > 0 {
 		s.post = values[n]
-		values = values[:n]
+		for _, v := range values[1:n] {
+			if v != "" {
+				s.any = append(s.any, v)
+			}
+		}
 	}
-	s.any = append(s.any, values...)
 	return s
 }
 
 func (s substring) String() string {
-	return fmt.Sprintf("sub(%s, pre: %s, post: %s, any: %s)", s.attr, s.pre, s.post, s.any)
+	var str strings.Builder
+	str.WriteRune(lparen)
+	str.WriteString(s.attr)
+	str.WriteRune(equal)
+	str.WriteString(EscapeFilterValue(s.pre))
+	str.WriteRune(star)
+	for _, a := range s.any {
+		str.WriteString(EscapeFilterValue(a))
+		str.WriteRune(star)
+	}
+	str.WriteString(EscapeFilterValue(s.post))
+	str.WriteRune(rparen)
+	return str.String()
 }
 
 func (s substring) Marshal() ([]byte, error) {
@@ -289,6 +450,23 @@ func (s substring) Not() Filter {
 	return Not(s)
 }
 
+func (s substring) Kind() FilterKind {
+	return FilterSubstring
+}
+
+func (s substring) Attribute() string {
+	return s.attr
+}
+
+func (s substring) Value() string {
+	str := s.String()
+	return str[len(s.attr)+2 : len(str)-1]
+}
+
+func (s substring) Children() []Filter {
+	return nil
+}
+
 const (
 	subInitial uint64 = iota
 	subAny
@@ -339,7 +517,21 @@ func ExtensibleMatch(attr, rule, value string, dn bool) Filter {
 }
 
 func (e extensible) String() string {
-	return fmt.Sprintf("%s(%s)", e.rule, e.attr)
+	var str strings.Builder
+	str.WriteRune(lparen)
+	str.WriteString(e.attr)
+	if e.dn {
+		str.WriteString(":dn")
+	}
+	if e.rule != "" {
+		str.WriteRune(colon)
+		str.WriteString(e.rule)
+	}
+	str.WriteRune(colon)
+	str.WriteRune(equal)
+	str.WriteString(EscapeFilterValue(e.value))
+	str.WriteRune(rparen)
+	return str.String()
 }
 
 func (e extensible) Marshal() ([]byte, error) {
@@ -347,7 +539,7 @@ func (e extensible) Marshal() ([]byte, error) {
 		Rule  string `ber:"omitempty,class:0x2,tag:0x1"`
 		Name  string `ber:"omitempty,class:0x2,tag:0x2"`
 		Value string `ber:"class:0x2,tag:0x3"`
-		DN    bool   `ber:"class:0x2,tag:0x4"`
+		DN    bool   `ber:"omitempty,class:0x2,tag:0x4"`
 	}{
 		Rule:  e.rule,
 		Name:  e.attr,
@@ -369,13 +561,145 @@ func (e extensible) Not() Filter {
 	return Not(e)
 }
 
+func (e extensible) Kind() FilterKind {
+	return FilterExtensible
+}
+
+func (e extensible) Attribute() string {
+	return e.attr
+}
+
+func (e extensible) Value() string {
+	return e.value
+}
+
+func (e extensible) Children() []Filter {
+	return nil
+}
+
+func UnmarshalFilter(b []byte) (Filter, error) {
+	f, rest, err := decodeFilter(b)
+	if err != nil {
+		return nil, err
+	}
+	if len(rest) > 0 {
+		return nil, syntaxError("trailing bytes after filter")
+	}
+	return f, nil
+}
+
+func decodeFilter(b []byte) (Filter, []byte, error) {
+	tag, body, rest, err := decodeElement(b)
+	if err != nil {
+		return nil, nil, err
+	}
+	if tag&berClassContext == 0 {
+		return nil, nil, syntaxError(fmt.Sprintf("unexpected filter tag %02x", tag))
+	}
+	var filter Filter
+	switch ft := uint64(tag & 0x1f); ft {
+	case tagFilterAnd, tagFilterOr:
+		var all []Filter
+		for len(body) > 0 {
+			var f Filter
+			if f, body, err = decodeFilter(body); err != nil {
+				return nil, nil, err
+			}
+			all = append(all, f)
+		}
+		filter = relational{filters: all, tag: ft}
+	case tagFilterNot:
+		inner, tail, err := decodeFilter(body)
+		if err != nil {
+			return nil, nil, err
+		}
+		if len(tail) > 0 {
+			return nil, nil, syntaxError("not filter with more than one operand")
+		}
+		filter = Not(inner)
+	case tagFilterEquality, tagFilterGreaterEq, tagFilterLesserEq, tagFilterApprox:
+		attr, tail, err := decodeOctetString(body)
+		if err != nil {
+			return nil, nil, err
+		}
+		value, _, err := decodeOctetString(tail)
+		if err != nil {
+			return nil, nil, err
+		}
+		filter = createCompareFilter(attr, value, ft)
+	case tagFilterPresent:
+		filter = Present(string(body))
+	case tagFilterSubstrings:
+		attr, tail, err := decodeOctetString(body)
+		if err != nil {
+			return nil, nil, err
+		}
+		_, items, _, err := decodeElement(tail)
+		if err != nil {
+			return nil, nil, err
+		}
+		s := substring{attr: attr}
+		for len(items) > 0 {
+			var (
+				t     byte
+				value []byte
+			)
+			if t, value, items, err = decodeElement(items); err != nil {
+				return nil, nil, err
+			}
+			switch uint64(t & 0x1f) {
+			case subInitial:
+				s.pre = string(value)
+			case subAny:
+				s.any = append(s.any, string(value))
+			case subFinal:
+				s.post = string(value)
+			}
+		}
+		filter = s
+	case tagFilterExtensible:
+		var e extensible
+		for len(body) > 0 {
+			var (
+				t     byte
+				value []byte
+			)
+			if t, value, body, err = decodeElement(body); err != nil {
+				return nil, nil, err
+			}
+			switch t & 0x1f {
+			case 1:
+				e.rule = string(value)
+			case 2:
+				e.attr = string(value)
+			case 3:
+				e.value = string(value)
+			case 4:
+				e.dn = len(value) > 0 && value[0] != 0
+			}
+		}
+		filter = e
+	default:
+		return nil, nil, syntaxError(fmt.Sprintf("unknown filter type %d", ft))
+	}
+	return filter, rest, nil
+}
+
+func decodeOctetString(b []byte) (string, []byte, error) {
+	_, body, rest, err := decodeElement(b)
+	if err != nil {
+		return "", nil, err
+	}
+	return string(body), rest, nil
+}
+
 func parseFilter(str *scanner) (Filter, error) {
 	r, err := str.Next()
 	if err != nil {
 		return nil, err
 	}
 	if r != lparen {
-		return nil, syntaxError("parenthese expected")
+		return nil, expected(syntaxError("parenthese expected"), "(")
 	}
 	if r, err = str.Next(); err != nil {
 		return nil, err
@@ -399,8 +723,16 @@ func parseFilter(str *scanner) (Filter, error) {
 		if err != nil {
 			return nil, err
 		}
+		r, err := str.Next()
+		if err != nil {
+			return nil, err
+		}
+		if r != rparen {
+			return nil, expected(syntaxError("parenthese expected"), ")")
+		}
 		filter = Not(not)
 	case rparen:
+		return nil, expected(syntaxError("empty filter"), "&", "|", "!", "attribute")
 	default:
 		str.Back()
 		return parseItem(str)
@@ -462,29 +794,23 @@ func (fp *filterParser) parseAttribute(str *scanner) error {
 		return err
 	}
 	if r == colon {
-		if err := fp.parseRule(str); err != nil {
-			return err
-		}
-		fp.Type = tagFilterExtensible
-		if str.Curr() == colon {
-			r, _ = str.Next()
-		}
+		return fp.parseExtensible(str)
+	}
+	if fp.Name == "" {
+		return expected(syntaxError("attribute expected"), "attribute")
 	}
 	switch r {
 	case langle:
 		if r, _ = str.Next(); r != equal {
-			return invalidOperator(langle, r)
+			return expected(invalidOperator(langle, r), "=")
 		}
 		fp.Type = tagFilterLesserEq
 	case rangle:
 		if r, _ = str.Next(); r != equal {
-			return invalidOperator(rangle, r)
+			return expected(invalidOperator(rangle, r), "=")
 		}
 		fp.Type = tagFilterGreaterEq
 	case equal:
-		if fp.Type == tagFilterExtensible {
-			break
-		}
 		fp.Type = tagFilterEquality
 
 		if r, _ := str.Next(); r == star {
@@ -497,18 +823,18 @@ func (fp *filterParser) parseAttribute(str *scanner) error {
 		str.Back()
 	case tilde:
 		if r, _ = str.Next(); r != equal {
-			return invalidOperator(tilde, r)
+			return expected(invalidOperator(tilde, r), "=")
 		}
 		fp.Type = tagFilterApprox
 	default:
-		return illegalCharacter(r)
+		return expected(illegalCharacter(r), "=", "~=", ">=", "<=", ":")
 	}
 	return nil
 }
 
 func (fp *filterParser) parseAttr(str *scanner) error {
 	accept := func(r rune) bool {
-		return isDigit(r) || isLetter(r) || r == dot || r == minus
+		return isDigit(r) || isLetter(r) || r == dot || r == minus || r == semicolon
 	}
 	attr, err := str.ScanUntil(accept, isOperator)
 	if err == nil {
@@ -518,32 +844,45 @@ func (fp *filterParser) parseAttr(str *scanner) error {
 	return err
 }
 
-func (fp *filterParser) parseRule(str *scanner) error {
+func (fp *filterParser) parseExtensible(str *scanner) error {
 	accept := func(r rune) bool {
 		return isDigit(r) || isLetter(r) || r == dot || r == minus
 	}
 	delim := func(r rune) bool {
-		return r == colon
+		return r == colon || r == equal
 	}
-	rule, err := str.ScanUntil(accept, delim)
-	if err != nil {
-		return err
-	}
-	if strings.ToLower(rule) != "dn" {
-		fp.Rule = rule
-		return nil
+	fp.Type = tagFilterExtensible
+	for {
+		part, err := str.ScanUntil(accept, delim)
+		if err != nil {
+			return err
+		}
+		if str.Curr() == equal {
+			if part != "" {
+				return expected(syntaxError("colon expected before equal"), ":")
+			}
+			break
+		}
+		switch {
+		case part == "":
+			return expected(syntaxError("empty matching rule"), "dn", "matching rule")
+		case strings.EqualFold(part, "dn") && !fp.DN && fp.Rule == "":
+			fp.DN = true
+		case fp.Rule == "":
+			fp.Rule = part
+		default:
+			return expected(syntaxError(fmt.Sprintf("unexpected %s after matching rule", part)), ":=")
+		}
 	}
-	fp.DN = true
-	if isOperator(str.Peek()) && str.Peek() != colon {
-		return nil
+	if fp.Name == "" && fp.Rule == "" {
+		return expected(syntaxError("matching rule expected without attribute"), "matching rule")
 	}
-	fp.Rule, err = str.ScanUntil(accept, delim)
-	return err
+	return nil
 }
 
 func (fp *filterParser) parseValue(str *scanner) error {
-	accept := func(_ rune) bool {
-		return true
+	accept := func(r rune) bool {
+		return r != lparen
 	}
 	delim := func(r rune) bool {
 		return r == star || r == rparen
@@ -557,11 +896,14 @@ func (fp *filterParser) parseValue(str *scanner) error {
 		if str.Curr() == rparen {
 			break
 		}
+		if value == "" && len(fp.Values) > 1 {
+			return expected(syntaxError("consecutive wildcards"), "value")
+		}
 	}
 	switch n := len(fp.Values); fp.Type {
 	case tagFilterGreaterEq, tagFilterLesserEq, tagFilterApprox, tagFilterExtensible:
 		if n > 1 {
-			return syntaxError("")
+			return expected(syntaxError("wildcard not allowed"), ")")
 		}
 	case tagFilterEquality:
 		if n > 1 {
@@ -641,14 +983,22 @@ func (s *scanner) Next() (rune, error) {
 func (s *scanner) ScanUntil(accept, delim func(rune) bool) (string, error) {
 	var buf bytes.Buffer
 	for {
-		r, _ := s.Next()
+		r, err := s.Next()
+		if err != nil {
+			return "", err
+		}
 		if delim(r) {
 			break
 		}
 		var escaped bool
 		if r == backslash {
+			if b, ok := s.scanHex(); ok {
+				buf.WriteByte(b)
+				continue
+			}
 			if !isEscaped(s.Peek()) {
-				return "", fmt.Errorf("invalid escape sequence")
+				s.Next()
+				return "", expected(fmt.Errorf("invalid escape sequence"), "hex pair")
 			}
 			escaped = true
 			r, _ = s.Next()
@@ -661,6 +1011,40 @@ func (s *scanner) ScanUntil(accept, delim func(rune) bool) (string, error) {
 	return buf.String(), nil
 }
 
+func (s *scanner) scanHex() (byte, bool) {
+	if s.next+2 > len(s.input) {
+		return 0, false
+	}
+	hi, lo := s.input[s.next], s.input[s.next+1]
+	if !isHex(hi) || !isHex(lo) {
+		return 0, false
+	}
+	s.curr = s.next + 1
+	s.next += 2
+	return unhex(hi)<<4 | unhex(lo), true
+}
+
+func (s *scanner) filterError(err error) error {
+	fe, ok := err.(*FilterError)
+	if !ok {
+		fe = &FilterError{Err: err}
+	}
+	fe.Input = string(s.input)
+	fe.Offset = s.curr
+	if errors.Is(err, io.EOF) {
+		fe.Err = syntaxError("incomplete filter")
+		fe.Offset = len(s.input)
+		if len(fe.Expected) == 0 {
+			fe.Expected = []string{")"}
+		}
+	}
+	if fe.Offset < len(s.input) {
+		r, _ := utf8.DecodeRune(s.input[fe.Offset:])
+		fe.Token = string(r)
+	}
+	return fe
+}
+
 func (s *scanner) String() string {
 	if s.curr >= len(s.input) {
 		return ""
@@ -668,12 +1052,60 @@ func (s *scanner) String() string {
 	return string(s.input[s.curr:])
 }
 
+func EscapeFilterValue(value string) string {
+	var str strings.Builder
+	for i := 0; i < len(value); i++ {
+		switch b := value[i]; {
+		case b == star || b == lparen || b == rparen || b == backslash || b == null:
+			str.WriteString(fmt.Sprintf("\\%02x", b))
+		case b >= utf8.RuneSelf:
+			r, z := utf8.DecodeRuneInString(value[i:])
+			if r == utf8.RuneError && z <= 1 {
+				str.WriteString(fmt.Sprintf("\\%02x", b))
+				continue
+			}
+			str.WriteString(value[i : i+z])
+			i += z - 1
+		default:
+			str.WriteByte(b)
+		}
+	}
+	return str.String()
+}
+
+func UnescapeFilterValue(value string) (string, error) {
+	var str strings.Builder
+	for i := 0; i < len(value); i++ {
+		if value[i] != backslash {
+			str.WriteByte(value[i])
+			continue
+		}
+		if i+2 >= len(value) || !isHex(value[i+1]) || !isHex(value[i+2]) {
+			return "", fmt.Errorf("%s: invalid escape sequence at position %d", value, i)
+		}
+		str.WriteByte(unhex(value[i+1])<<4 | unhex(value[i+2]))
+		i += 2
+	}
+	return str.String(), nil
+}
+
+func unhex(b byte) byte {
+	switch {
+	case b >= '0' && b <= '9':
+		return b - '0'
+	case b >= 'a' && b <= 'f':
+		return b - 'a' + 10
+	default:
+		return b - 'A' + 10
+	}
+}
+
 func isEscaped(r rune) bool {
 	return r == star || r == lparen || r == rparen || r == backslash || r == null
 }
 
 func invalidOperator(prev, curr rune) error {
-	return fmt.Errorf("%w: %c%c", prev, curr)
+	return fmt.Errorf("%w: %c%c", ErrOperator, prev, curr)
 }
 
 func illegalCharacter(curr rune) error {
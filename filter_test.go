@@ -0,0 +1,83 @@
+package ldap
+
+import (
+	"testing"
+)
+
+func TestFilters(t *testing.T) {
+	data := []struct {
+		Name    string
+		Input   string
+		Want    string
+		Invalid bool
+	}{
+		{Name: "rfc4515/equality", Input: "(cn=Babs Jensen)", Want: "(cn=Babs Jensen)"},
+		{Name: "rfc4515/not", Input: "(!(cn=Tim Howes))", Want: "(!(cn=Tim Howes))"},
+		{Name: "rfc4515/and-or", Input: "(&(objectClass=Person)(|(sn=Jensen)(cn=Babs J*)))", Want: "(&(objectClass=Person)(|(sn=Jensen)(cn=Babs J*)))"},
+		{Name: "rfc4515/substring", Input: "(o=univ*of*mich*)", Want: "(o=univ*of*mich*)"},
+		{Name: "rfc4515/empty-value", Input: "(seeAlso=)", Want: "(seeAlso=)"},
+		{Name: "rfc4515/extensible-rule", Input: "(cn:caseExactMatch:=Fred Flintstone)", Want: "(cn:caseExactMatch:=Fred Flintstone)"},
+		{Name: "rfc4515/extensible-attr", Input: "(cn:=Betty Rubble)", Want: "(cn:=Betty Rubble)"},
+		{Name: "rfc4515/extensible-dn-oid", Input: "(sn:dn:2.4.6.8.10:=Barney Rubble)", Want: "(sn:dn:2.4.6.8.10:=Barney Rubble)"},
+		{Name: "rfc4515/extensible-dn", Input: "(o:dn:=Ace Industry)", Want: "(o:dn:=Ace Industry)"},
+		{Name: "rfc4515/extensible-no-attr", Input: "(:1.2.3:=Wilma Flintstone)", Want: "(:1.2.3:=Wilma Flintstone)"},
+		{Name: "rfc4515/extensible-dn-no-attr", Input: "(:DN:2.4.6.8.10:=Dino)", Want: "(:dn:2.4.6.8.10:=Dino)"},
+		{Name: "rfc4515/escaped-parens", Input: `(o=Parens R Us \28for all your parenthetical needs\29)`, Want: `(o=Parens R Us \28for all your parenthetical needs\29)`},
+		{Name: "rfc4515/escaped-star", Input: `(cn=*\2A*)`, Want: `(cn=*\2a*)`},
+		{Name: "rfc4515/escaped-backslash", Input: `(filename=C:\5cMyFile)`, Want: `(filename=C:\5cMyFile)`},
+		{Name: "rfc4515/utf8", Input: `(sn=Lu\c4\8di\c4\87)`, Want: "(sn=Lučić)"},
+		{Name: "rfc4515/oid-attr", Input: "(2.5.4.3=foo)", Want: "(2.5.4.3=foo)"},
+		{Name: "extensible/rule-only", Input: "(:caseExactMatch:=foo)", Want: "(:caseExactMatch:=foo)"},
+		{Name: "extensible/dn-only", Input: "(cn:dn:=x)", Want: "(cn:dn:=x)"},
+		{Name: "extensible/oid-attr", Input: "(2.5.4.3:dn:2.5.13.5:=foo)", Want: "(2.5.4.3:dn:2.5.13.5:=foo)"},
+		{Name: "attr/options", Input: "(cn;lang-en=x)", Want: "(cn;lang-en=x)"},
+		{Name: "present", Input: "(objectClass=*)", Want: "(objectClass=*)"},
+		{Name: "substring/initial", Input: "(cn=a*)", Want: "(cn=a*)"},
+		{Name: "substring/final", Input: "(cn=*a)", Want: "(cn=*a)"},
+		{Name: "substring/any", Input: "(cn=*a*b*)", Want: "(cn=*a*b*)"},
+		{Name: "invalid/no-rule-no-attr", Input: "(:=x)", Invalid: true},
+		{Name: "invalid/dn-without-rule", Input: "(:dn:=x)", Invalid: true},
+		{Name: "invalid/empty-rule", Input: "(cn::=x)", Invalid: true},
+		{Name: "invalid/missing-value", Input: "(cn:dn:)", Invalid: true},
+		{Name: "invalid/rule-before-dn", Input: "(cn:1.2.3:dn:=x)", Invalid: true},
+		{Name: "invalid/missing-attr", Input: "(=x)", Invalid: true},
+		{Name: "invalid/escape", Input: `(cn=\zz)`, Invalid: true},
+		{Name: "invalid/unescaped-lparen", Input: "(cn=a(b)", Invalid: true},
+		{Name: "invalid/unescaped-lparen-nested", Input: "(&(cn=a(b))(sn=c))", Invalid: true},
+		{Name: "invalid/double-star", Input: "(cn=a**b)", Invalid: true},
+		{Name: "invalid/double-star-leading", Input: "(cn=**b)", Invalid: true},
+		{Name: "invalid/double-star-trailing", Input: "(cn=a**)", Invalid: true},
+		{Name: "invalid/only-stars", Input: "(cn=**)", Invalid: true},
+		{Name: "invalid/wildcard-ordering", Input: "(uidNumber>=1*)", Invalid: true},
+	}
+	for _, d := range data {
+		f, err := ParseFilter(d.Input)
+		if d.Invalid {
+			if err == nil {
+				t.Errorf("%s: %s: expected error, got %s", d.Name, d.Input, f)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %s: unexpected error: %s", d.Name, d.Input, err)
+			continue
+		}
+		if got := f.String(); got != d.Want {
+			t.Errorf("%s: %s: want %s, got %s", d.Name, d.Input, d.Want, got)
+			continue
+		}
+		b, err := f.Marshal()
+		if err != nil {
+			t.Errorf("%s: %s: marshal: %s", d.Name, d.Input, err)
+			continue
+		}
+		g, err := UnmarshalFilter(b)
+		if err != nil {
+			t.Errorf("%s: %s: unmarshal: %s", d.Name, d.Input, err)
+			continue
+		}
+		if got := g.String(); got != d.Want {
+			t.Errorf("%s: %s: round trip: want %s, got %s", d.Name, d.Input, d.Want, got)
+		}
+	}
+}
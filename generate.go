@@ -0,0 +1,133 @@
+package ldap
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"io"
+	"strings"
+	"unicode"
+)
+
+const (
+	syntaxBoolean  = "1.3.6.1.4.1.1466.115.121.1.7"
+	syntaxInteger  = "1.3.6.1.4.1.1466.115.121.1.27"
+	syntaxTime     = "1.3.6.1.4.1.1466.115.121.1.24"
+	syntaxOctet    = "1.3.6.1.4.1.1466.115.121.1.40"
+	syntaxBinary   = "1.3.6.1.4.1.1466.115.121.1.5"
+	syntaxJPEG     = "1.3.6.1.4.1.1466.115.121.1.28"
+	syntaxCert     = "1.3.6.1.4.1.1466.115.121.1.8"
+	syntaxCertList = "1.3.6.1.4.1.1466.115.121.1.9"
+)
+
+func GenerateStructs(w io.Writer, s *Schema, pkg string, classes ...string) error {
+	var (
+		buf  bytes.Buffer
+		body bytes.Buffer
+		imp  bool
+	)
+	for _, c := range classes {
+		oc, ok := s.ObjectClass(c)
+		if !ok {
+			return fmt.Errorf("%s: undefined object class", c)
+		}
+		var (
+			fields []string
+			seen   = make(map[string]struct{})
+		)
+		fields = append(fields, fmt.Sprintf("DN string `%s:%q`", structTag, structDN))
+		for _, x := range s.expandClasses([]string{c}) {
+			sup, ok := s.ObjectClass(x)
+			if !ok {
+				return fmt.Errorf("%s: undefined object class", x)
+			}
+			for _, a := range append(append([]string{}, sup.Must...), sup.May...) {
+				at, ok := s.AttributeType(a)
+				if !ok {
+					return fmt.Errorf("%s: undefined attribute type", a)
+				}
+				if _, ok := seen[at.OID]; ok {
+					continue
+				}
+				seen[at.OID] = struct{}{}
+				typ := s.goType(at)
+				imp = imp || strings.HasSuffix(typ, "time.Time")
+				fields = append(fields, fmt.Sprintf("%s %s `%s:%q`", goName(at.Name()), typ, structTag, at.Name()))
+			}
+		}
+		fmt.Fprintf(&body, "type %s struct {\n", goName(oc.Name()))
+		for _, f := range fields {
+			fmt.Fprintf(&body, "\t%s\n", f)
+		}
+		fmt.Fprintln(&body, "}")
+		fmt.Fprintln(&body)
+	}
+	fmt.Fprintln(&buf, "// Code generated by ldap generate; DO NOT EDIT.")
+	fmt.Fprintln(&buf)
+	fmt.Fprintf(&buf, "package %s\n\n", pkg)
+	if imp {
+		fmt.Fprintf(&buf, "import \"time\"\n\n")
+	}
+	buf.Write(body.Bytes())
+
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(src)
+	return err
+}
+
+func (s *Schema) goType(at AttributeType) string {
+	var (
+		syntax = at.Syntax
+		single = at.SingleValue
+	)
+	for i := 0; syntax == "" && at.Sup != "" && i < len(s.AttributeTypes); i++ {
+		sup, ok := s.AttributeType(at.Sup)
+		if !ok {
+			break
+		}
+		at, syntax = sup, sup.Syntax
+	}
+	var typ string
+	switch syntax {
+	case syntaxBoolean:
+		typ = "bool"
+	case syntaxInteger:
+		typ = "int64"
+	case syntaxTime:
+		typ = "time.Time"
+	case syntaxOctet, syntaxBinary, syntaxJPEG, syntaxCert, syntaxCertList:
+		typ = "[]byte"
+	default:
+		typ = "string"
+	}
+	if !single {
+		typ = "[]" + typ
+	}
+	return typ
+}
+
+func goName(name string) string {
+	var (
+		str   strings.Builder
+		upper = true
+	)
+	for _, r := range name {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			upper = true
+			continue
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		str.WriteRune(r)
+	}
+	res := str.String()
+	if res == "" || unicode.IsDigit(rune(res[0])) {
+		res = "X" + res
+	}
+	return res
+}
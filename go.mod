@@ -0,0 +1,8 @@
+module github.com/midbel/ldap
+
+go 1.26.0
+
+require (
+	golang.org/x/crypto v0.57.0
+	golang.org/x/text v0.42.0
+)
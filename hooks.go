@@ -0,0 +1,113 @@
+package ldap
+
+import (
+	"fmt"
+	"strings"
+)
+
+type OpType uint8
+
+const (
+	OpBind OpType = iota
+	OpUnbind
+	OpSearch
+	OpModify
+	OpAdd
+	OpDelete
+	OpModDN
+	OpCompare
+	OpExtended
+	OpAbandon
+)
+
+func (o OpType) String() string {
+	switch o {
+	case OpBind:
+		return "bind"
+	case OpUnbind:
+		return "unbind"
+	case OpSearch:
+		return "search"
+	case OpModify:
+		return "modify"
+	case OpAdd:
+		return "add"
+	case OpDelete:
+		return "delete"
+	case OpModDN:
+		return "moddn"
+	case OpCompare:
+		return "compare"
+	case OpExtended:
+		return "extended"
+	case OpAbandon:
+		return "abandon"
+	default:
+		return "unknown"
+	}
+}
+
+func (o OpType) MarshalText() ([]byte, error) {
+	return []byte(o.String()), nil
+}
+
+func (o *OpType) UnmarshalText(b []byte) error {
+	str := strings.ToLower(string(b))
+	for op := OpBind; op <= OpAbandon; op++ {
+		if op.String() == str {
+			*o = op
+			return nil
+		}
+	}
+	return fmt.Errorf("%s: unknown operation", str)
+}
+
+type OperationInfo struct {
+	Type        OpType
+	DN          string
+	NewRDN      string
+	NewSuperior string
+	OID         string
+	Filter      Filter
+	Attrs       []string
+	Controls    []Control
+}
+
+type BeforeHook func(*OperationInfo) error
+
+type AfterHook func(OperationInfo, error)
+
+func (c *Client) BeforeOp(fn BeforeHook) {
+	c.hooks.before = append(c.hooks.before, fn)
+}
+
+func (c *Client) AfterOp(fn AfterHook) {
+	c.hooks.after = append(c.hooks.after, fn)
+}
+
+type hooks struct {
+	before []BeforeHook
+	after  []AfterHook
+}
+
+func (c *Client) before(info OperationInfo) (OperationInfo, error) {
+	if err := c.Flush(); err != nil {
+		return info, err
+	}
+	return c.runHooks(info)
+}
+
+func (c *Client) runHooks(info OperationInfo) (OperationInfo, error) {
+	for _, fn := range c.hooks.before {
+		if err := fn(&info); err != nil {
+			return info, err
+		}
+	}
+	return info, nil
+}
+
+func (c *Client) after(info OperationInfo, err error) {
+	for _, fn := range c.hooks.after {
+		fn(info, err)
+	}
+}
@@ -0,0 +1,60 @@
+package ldap
+
+import (
+	"crypto/rand"
+	"fmt"
+	"io"
+)
+
+const (
+	// UUID-derived arc (ITU-T X.667) owned by this package, from
+	// 7b73d4f3-c977-47bf-80f9-bed4252ca3e4. Private controls live below it.
+	oidPrivateArc = "2.25.164096476805906310632079758174022575076"
+
+	CtrlOperationKeyOID = oidPrivateArc + ".1"
+)
+
+func OperationKey(key string) Control {
+	return CreateControl(CtrlOperationKeyOID, []byte(key), false)
+}
+
+func OperationKeyOf(controls []Control) (string, bool) {
+	for _, c := range controls {
+		if c.OID == CtrlOperationKeyOID {
+			return string(c.Value), true
+		}
+	}
+	return "", false
+}
+
+func (c *Client) TrackOperations(log io.Writer) {
+	c.BeforeOp(func(info *OperationInfo) error {
+		switch info.Type {
+		case OpAdd, OpModify, OpDelete, OpModDN:
+		default:
+			return nil
+		}
+		key, ok := OperationKeyOf(info.Controls)
+		if !ok {
+			var err error
+			if key, err = NewOperationKey(); err != nil {
+				return err
+			}
+			info.Controls = append(info.Controls, OperationKey(key))
+		}
+		if log != nil {
+			fmt.Fprintf(log, "%s %s %s\n", key, info.Type, info.DN)
+		}
+		return nil
+	})
+}
+
+func NewOperationKey() (string, error) {
+	var b [16]byte
+	if _, err := io.ReadFull(rand.Reader, b[:]); err != nil {
+		return "", err
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:]), nil
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"strconv"
 	"strings"
+	"unicode/utf8"
 
 	"github.com/midbel/ber"
 )
@@ -169,27 +170,6 @@ func unexpectedType(id ber.Ident) error {
 	return fmt.Errorf("unexpected response type (class: %d, type: %d, tag: %d)", id.Class(), id.Type(), id.Tag())
 }
 
-type extendedRequest struct {
-	OID  string      `ber:"class:0x2,tag:0x0"`
-	Body interface{} `ber:"class:0x2,tag:0x1,omitempty"`
-}
-
-func createExtendedRequest(oid string, body interface{}) extendedRequest {
-	e := extendedRequest{OID: oid}
-	if body != nil {
-		e.setBody(body)
-	}
-	return e
-}
-
-func (e *extendedRequest) setBody(msg interface{}) {
-	e.Body = struct {
-		Body interface{}
-	}{
-		Body: msg,
-	}
-}
-
 type extendedResponse struct {
 	Result
 	Name  string
@@ -219,6 +199,38 @@ func (e *extendedResponse) Unmarshal(b []byte) error {
 	return nil
 }
 
+type ExtendedResult struct {
+	Name          string
+	Value         []byte
+	Intermediates []IntermediateResponse
+	Controls      []ControlValue
+}
+
+type IntermediateResponse struct {
+	Name  string
+	Value []byte
+}
+
+func (i *IntermediateResponse) Unmarshal(b []byte) error {
+	var (
+		dec = ber.NewDecoder(b)
+		err error
+	)
+	if id, err1 := dec.Peek(); err1 == nil && id.Tag() == 0 {
+		i.Name, err = dec.DecodeString()
+		if err != nil {
+			return err
+		}
+	}
+	if id, err1 := dec.Peek(); err1 == nil && id.Tag() == 1 {
+		i.Value, err = dec.DecodeBytes()
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 func unmarshalResult(d *ber.Decoder, r *Result) error {
 	var err error
 	if r.Code, err = d.DecodeInt(); err != nil {
@@ -237,8 +249,9 @@ func unmarshalResult(d *ber.Decoder, r *Result) error {
 }
 
 type Attribute struct {
-	Name   string   `ber:"octetstr"`
-	Values []string `ber:"set"`
+	Name      string   `ber:"octetstr"`
+	Values    []string `ber:"set"`
+	Truncated bool     `ber:"-"`
 }
 
 func createAttribute(name, value string) Attribute {
@@ -252,6 +265,52 @@ func createAttribute(name, value string) Attribute {
 	}
 }
 
+func NewBinaryAttribute(name string, values ...[]byte) Attribute {
+	a := Attribute{
+		Name: name,
+	}
+	for _, v := range values {
+		a.Values = append(a.Values, string(v))
+	}
+	return a
+}
+
+func (a Attribute) Bytes() [][]byte {
+	values := make([][]byte, len(a.Values))
+	for i := range a.Values {
+		values[i] = []byte(a.Values[i])
+	}
+	return values
+}
+
+func (a *Attribute) truncate(limit int) {
+	for i, v := range a.Values {
+		if len(v) <= limit {
+			continue
+		}
+		n := limit
+		if utf8.ValidString(v) {
+			for n > 0 && !utf8.RuneStart(v[n]) {
+				n--
+			}
+		}
+		a.Values[i] = v[:n]
+		a.Truncated = true
+	}
+}
+
+func (a Attribute) Binary() bool {
+	if a.Description().Binary() {
+		return true
+	}
+	for _, v := range a.Values {
+		if !isSafeString(v) {
+			return true
+		}
+	}
+	return false
+}
+
 type AttributeAssertion struct {
 	Desc string `ber:"tag:0x4"`
 	Attr string `ber:"tag:0x4"`
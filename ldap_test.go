@@ -0,0 +1,32 @@
+package ldap
+
+import (
+	"testing"
+)
+
+func TestAttributeTruncate(t *testing.T) {
+	data := []struct {
+		Value string
+		Limit int
+		Want  string
+	}{
+		{Value: "hello", Limit: 10, Want: "hello"},
+		{Value: "hello", Limit: 5, Want: "hello"},
+		{Value: "hello", Limit: 3, Want: "hel"},
+		{Value: "héllo", Limit: 2, Want: "h"},
+		{Value: "héllo", Limit: 3, Want: "hé"},
+		{Value: "日本語", Limit: 4, Want: "日"},
+		{Value: "日本語", Limit: 2, Want: ""},
+		{Value: "\xff\xd8\xff\xe0", Limit: 3, Want: "\xff\xd8\xff"},
+	}
+	for _, d := range data {
+		a := Attribute{Name: "description", Values: []string{d.Value}}
+		a.truncate(d.Limit)
+		if got := a.Values[0]; got != d.Want {
+			t.Errorf("%q (%d): truncate mismatch: want %q, got %q", d.Value, d.Limit, d.Want, got)
+		}
+		if want := len(d.Value) > d.Limit; a.Truncated != want {
+			t.Errorf("%q (%d): truncated flag mismatch: want %t, got %t", d.Value, d.Limit, want, a.Truncated)
+		}
+	}
+}
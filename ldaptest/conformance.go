@@ -0,0 +1,216 @@
+package ldaptest
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/midbel/ldap"
+)
+
+const (
+	fixtureAdmin = "cn=admin,dc=example,dc=com"
+	fixtureUser  = "cn=john,ou=people,dc=example,dc=com"
+)
+
+type Exchange struct {
+	Name     string
+	Request  string
+	Response []string
+	Run      func(*ldap.Client) error
+}
+
+var Corpus = []Exchange{
+	{
+		Name:     "bind/simple",
+		Request:  "30 2c 02 01 01 60 27 02 01 03 04 1a 63 6e 3d 61 64 6d 69 6e 2c 64 63 3d 65 78 61 6d 70 6c 65 2c 64 63 3d 63 6f 6d 80 06 73 65 63 72 65 74",
+		Response: []string{"30 0c 02 01 01 61 07 0a 01 00 04 00 04 00"},
+		Run: func(c *ldap.Client) error {
+			_, err := c.Bind(fixtureAdmin, "secret")
+			return err
+		},
+	},
+	{
+		Name:     "bind/invalid-credentials",
+		Request:  "30 2c 02 01 01 60 27 02 01 03 04 1a 63 6e 3d 61 64 6d 69 6e 2c 64 63 3d 65 78 61 6d 70 6c 65 2c 64 63 3d 63 6f 6d 80 06 73 65 63 72 65 74",
+		Response: []string{"30 1f 02 01 01 61 1a 0a 01 31 04 00 04 13 69 6e 76 61 6c 69 64 20 63 72 65 64 65 6e 74 69 61 6c 73"},
+		Run: func(c *ldap.Client) error {
+			_, err := c.Bind(fixtureAdmin, "secret")
+			return expectCode(err, ldap.InvalidCredentials)
+		},
+	},
+	{
+		Name:     "add",
+		Request:  "30 60 02 01 01 68 5b 04 23 63 6e 3d 6a 6f 68 6e 2c 6f 75 3d 70 65 6f 70 6c 65 2c 64 63 3d 65 78 61 6d 70 6c 65 2c 64 63 3d 63 6f 6d 30 34 30 17 04 0b 6f 62 6a 65 63 74 43 6c 61 73 73 31 08 04 06 70 65 72 73 6f 6e 30 0c 04 02 63 6e 31 06 04 04 6a 6f 68 6e 30 0b 04 02 73 6e 31 05 04 03 64 6f 65",
+		Response: []string{"30 0c 02 01 01 69 07 0a 01 00 04 00 04 00"},
+		Run: func(c *ldap.Client) error {
+			attrs := []ldap.Attribute{
+				ldap.Attribute{Name: "objectClass", Values: []string{"person"}},
+				ldap.Attribute{Name: "cn", Values: []string{"john"}},
+				ldap.Attribute{Name: "sn", Values: []string{"doe"}},
+			}
+			_, err := c.Add(fixtureUser, attrs)
+			return err
+		},
+	},
+	{
+		Name:     "delete",
+		Request:  "30 28 02 01 01 4a 23 63 6e 3d 6a 6f 68 6e 2c 6f 75 3d 70 65 6f 70 6c 65 2c 64 63 3d 65 78 61 6d 70 6c 65 2c 64 63 3d 63 6f 6d",
+		Response: []string{"30 0c 02 01 01 6b 07 0a 01 00 04 00 04 00"},
+		Run: func(c *ldap.Client) error {
+			_, err := c.Delete(fixtureUser)
+			return err
+		},
+	},
+	{
+		Name:     "modify/replace",
+		Request:  "30 4d 02 01 01 66 48 04 23 63 6e 3d 6a 6f 68 6e 2c 6f 75 3d 70 65 6f 70 6c 65 2c 64 63 3d 65 78 61 6d 70 6c 65 2c 64 63 3d 63 6f 6d 30 21 30 1f 0a 01 02 30 1a 04 04 6d 61 69 6c 31 12 04 10 6a 6f 68 6e 40 65 78 61 6d 70 6c 65 2e 63 6f 6d",
+		Response: []string{"30 0c 02 01 01 67 07 0a 01 00 04 00 04 00"},
+		Run: func(c *ldap.Client) error {
+			pa := ldap.PartialAttribute{
+				Mod:       ldap.ModReplace,
+				Attribute: ldap.Attribute{Name: "mail", Values: []string{"john@example.com"}},
+			}
+			_, err := c.Modify(fixtureUser, []ldap.PartialAttribute{pa})
+			return err
+		},
+	},
+	{
+		Name:     "moddn/rename",
+		Request:  "30 36 02 01 01 6c 31 04 23 63 6e 3d 6a 6f 68 6e 2c 6f 75 3d 70 65 6f 70 6c 65 2c 64 63 3d 65 78 61 6d 70 6c 65 2c 64 63 3d 63 6f 6d 04 07 63 6e 3d 6a 61 6e 65 01 01 ff",
+		Response: []string{"30 0c 02 01 01 6d 07 0a 01 00 04 00 04 00"},
+		Run: func(c *ldap.Client) error {
+			_, err := c.Rename(fixtureUser, "cn=jane", false)
+			return err
+		},
+	},
+	{
+		Name:     "moddn/move",
+		Request:  "30 52 02 01 01 6c 4d 04 23 63 6e 3d 6a 6f 68 6e 2c 6f 75 3d 70 65 6f 70 6c 65 2c 64 63 3d 65 78 61 6d 70 6c 65 2c 64 63 3d 63 6f 6d 04 07 63 6e 3d 6a 6f 68 6e 01 01 00 80 1a 6f 75 3d 73 74 61 66 66 2c 64 63 3d 65 78 61 6d 70 6c 65 2c 64 63 3d 63 6f 6d",
+		Response: []string{"30 0c 02 01 01 6d 07 0a 01 00 04 00 04 00"},
+		Run: func(c *ldap.Client) error {
+			_, err := c.Move(fixtureUser, "ou=staff,dc=example,dc=com")
+			return err
+		},
+	},
+	{
+		Name:     "compare/true",
+		Request:  "30 35 02 01 01 6e 30 04 23 63 6e 3d 6a 6f 68 6e 2c 6f 75 3d 70 65 6f 70 6c 65 2c 64 63 3d 65 78 61 6d 70 6c 65 2c 64 63 3d 63 6f 6d 30 09 04 02 73 6e 04 03 64 6f 65",
+		Response: []string{"30 0c 02 01 01 6f 07 0a 01 06 04 00 04 00"},
+		Run: func(c *ldap.Client) error {
+			ok, _, err := c.Compare(fixtureUser, ldap.AttributeAssertion{Desc: "sn", Attr: "doe"})
+			if err == nil && !ok {
+				err = fmt.Errorf("compare: expected true")
+			}
+			return err
+		},
+	},
+	{
+		Name:     "extended/whoami",
+		Request:  "30 1e 02 01 01 77 19 80 17 31 2e 33 2e 36 2e 31 2e 34 2e 31 2e 34 32 30 33 2e 31 2e 31 31 2e 33",
+		Response: []string{"30 2b 02 01 01 78 26 0a 01 00 04 00 04 00 8b 1d 64 6e 3a 63 6e 3d 61 64 6d 69 6e 2c 64 63 3d 65 78 61 6d 70 6c 65 2c 64 63 3d 63 6f 6d"},
+		Run: func(c *ldap.Client) error {
+			who, _, err := c.Whoami()
+			if err == nil && who != "dn:"+fixtureAdmin {
+				err = fmt.Errorf("whoami: unexpected authzid %q", who)
+			}
+			return err
+		},
+	},
+	{
+		Name:     "search/base",
+		Request:  "30 36 02 01 01 63 31 04 11 64 63 3d 65 78 61 6d 70 6c 65 2c 64 63 3d 63 6f 6d 0a 01 00 0a 01 00 02 01 00 02 01 00 01 01 00 87 0b 6f 62 6a 65 63 74 43 6c 61 73 73 30 00",
+		Response: []string{"30 49 02 01 01 64 44 04 11 64 63 3d 65 78 61 6d 70 6c 65 2c 64 63 3d 63 6f 6d 30 2f 30 1c 04 0b 6f 62 6a 65 63 74 43 6c 61 73 73 31 0d 04 03 74 6f 70 04 06 64 6f 6d 61 69 6e 30 0f 04 02 64 63 31 09 04 07 65 78 61 6d 70 6c 65", "30 0c 02 01 01 65 07 0a 01 00 04 00 04 00"},
+		Run: func(c *ldap.Client) error {
+			es, _, err := c.Search("dc=example,dc=com")
+			if err != nil {
+				return err
+			}
+			if len(es) != 1 || es[0].Name != "dc=example,dc=com" || es[0].GetValue("dc") != "example" {
+				return fmt.Errorf("search: unexpected entries %v", es)
+			}
+			return nil
+		},
+	},
+}
+
+func Conformance(t *testing.T, connect func(net.Conn) *ldap.Client) {
+	t.Helper()
+	if connect == nil {
+		connect = ldap.NewClient
+	}
+	for _, x := range Corpus {
+		x := x
+		t.Run(x.Name, func(t *testing.T) {
+			if err := x.check(connect); err != nil {
+				t.Error(err)
+			}
+		})
+	}
+}
+
+func (x Exchange) check(connect func(net.Conn) *ldap.Client) error {
+	server, conn := net.Pipe()
+	defer server.Close()
+	defer conn.Close()
+
+	errs := make(chan error, 1)
+	go func() {
+		err := x.serve(server)
+		server.Close()
+		errs <- err
+	}()
+	err := x.Run(connect(conn))
+	conn.Close()
+	if e := <-errs; e != nil {
+		return e
+	}
+	return err
+}
+
+func (x Exchange) serve(conn net.Conn) error {
+	conn.SetDeadline(time.Now().Add(time.Second * 5))
+
+	want, err := decodeFixture(x.Request)
+	if err != nil {
+		return err
+	}
+	got := make([]byte, len(want))
+	if _, err := io.ReadFull(conn, got); err != nil {
+		return fmt.Errorf("request: %w", err)
+	}
+	if !bytes.Equal(got, want) {
+		return fmt.Errorf("request mismatch\nwant: % x\n got: % x", want, got)
+	}
+	for _, r := range x.Response {
+		body, err := decodeFixture(r)
+		if err != nil {
+			return err
+		}
+		if _, err := conn.Write(body); err != nil {
+			return fmt.Errorf("response: %w", err)
+		}
+	}
+	return nil
+}
+
+func decodeFixture(str string) ([]byte, error) {
+	return hex.DecodeString(strings.ReplaceAll(str, " ", ""))
+}
+
+func expectCode(err error, code int64) error {
+	var res ldap.Result
+	if !errors.As(err, &res) {
+		return fmt.Errorf("expected result code %d, got %v", code, err)
+	}
+	if res.Code != code {
+		return fmt.Errorf("expected result code %d, got %d", code, res.Code)
+	}
+	return nil
+}
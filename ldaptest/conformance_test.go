@@ -0,0 +1,93 @@
+package ldaptest
+
+import (
+	"bytes"
+	"flag"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+var (
+	captureAddr   = flag.String("capture", "", "address of the server to capture transcripts from")
+	captureSource = flag.String("source", "", "server name and version recorded in the transcript")
+	captureFile   = flag.String("out", "", "file where the captured transcript is written")
+)
+
+func TestConformance(t *testing.T) {
+	Conformance(t, nil)
+}
+
+func TestTranscriptFormat(t *testing.T) {
+	tr := Transcript{Source: "hand-written fixtures"}
+	for _, n := range Session {
+		x, ok := lookupExchange(n)
+		if !ok {
+			t.Fatalf("%s: unknown exchange", n)
+		}
+		tr.Exchanges = append(tr.Exchanges, x)
+	}
+	var buf bytes.Buffer
+	if err := WriteTranscript(&buf, tr); err != nil {
+		t.Fatal(err)
+	}
+	got, err := ReadTranscript(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Source != tr.Source || len(got.Exchanges) != len(tr.Exchanges) {
+		t.Fatalf("transcript mismatch: want %s (%d), got %s (%d)", tr.Source, len(tr.Exchanges), got.Source, len(got.Exchanges))
+	}
+	for i, x := range tr.Exchanges {
+		other := got.Exchanges[i]
+		if x.Name != other.Name || x.Request != other.Request || !reflect.DeepEqual(x.Response, other.Response) {
+			t.Errorf("%s: exchange mismatch", x.Name)
+		}
+	}
+}
+
+func TestSplitMessages(t *testing.T) {
+	x, _ := lookupExchange("search/base")
+	body, err := decodeFixture(strings.Join(x.Response, " "))
+	if err != nil {
+		t.Fatal(err)
+	}
+	list, err := splitMessages(body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) != len(x.Response) {
+		t.Fatalf("messages mismatch: want %d, got %d", len(x.Response), len(list))
+	}
+	for i := range list {
+		if got := encodeFixture(list[i]); got != x.Response[i] {
+			t.Errorf("message %d mismatch\nwant: %s\n got: %s", i, x.Response[i], got)
+		}
+	}
+	if _, err := splitMessages(body[:len(body)-1]); err == nil {
+		t.Errorf("truncated message accepted")
+	}
+}
+
+func TestCapture(t *testing.T) {
+	if *captureAddr == "" {
+		t.Skip("no server given with -capture")
+	}
+	if *captureSource == "" || *captureFile == "" {
+		t.Fatal("-source and -out should be given with -capture")
+	}
+	tr, err := Capture(*captureAddr, *captureSource)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w, err := os.Create(*captureFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Close()
+	if err := WriteTranscript(w, tr); err != nil {
+		t.Fatal(err)
+	}
+	Replay(t, tr, nil)
+}
@@ -0,0 +1,142 @@
+package ldaptest
+
+import (
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/midbel/ldap"
+)
+
+type Search struct {
+	Base   string
+	Filter ldap.Filter
+	Attrs  []string
+}
+
+type Recorder struct {
+	mu       sync.Mutex
+	searches []Search
+	compares []string
+}
+
+func Record(c *ldap.Client) *Recorder {
+	var r Recorder
+	c.AfterOp(func(info ldap.OperationInfo, _ error) {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+		switch info.Type {
+		case ldap.OpSearch:
+			r.searches = append(r.searches, Search{
+				Base:   info.DN,
+				Filter: info.Filter,
+				Attrs:  info.Attrs,
+			})
+		case ldap.OpCompare:
+			r.compares = append(r.compares, info.Attrs...)
+		}
+	})
+	return &r
+}
+
+func (r *Recorder) Searches() []Search {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]Search{}, r.searches...)
+}
+
+func (r *Recorder) Filters() []string {
+	var list []string
+	for _, s := range r.Searches() {
+		if s.Filter != nil {
+			list = append(list, s.Filter.String())
+		}
+	}
+	return list
+}
+
+func (r *Recorder) Attributes() []string {
+	set := make(map[string]struct{})
+	for _, s := range r.Searches() {
+		ldap.Walk(s.Filter, func(f ldap.Filter) bool {
+			if a := f.Attribute(); a != "" {
+				set[strings.ToLower(a)] = struct{}{}
+			}
+			return true
+		})
+		for _, a := range s.Attrs {
+			set[strings.ToLower(a)] = struct{}{}
+		}
+	}
+	r.mu.Lock()
+	for _, a := range r.compares {
+		set[strings.ToLower(a)] = struct{}{}
+	}
+	r.mu.Unlock()
+
+	var list []string
+	for a := range set {
+		list = append(list, a)
+	}
+	sort.Strings(list)
+	return list
+}
+
+func (r *Recorder) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.searches = r.searches[:0]
+	r.compares = r.compares[:0]
+}
+
+func (r *Recorder) AssertIndexed(t testing.TB, kind ldap.FilterKind, indexed ...string) {
+	t.Helper()
+	for _, s := range r.Searches() {
+		ldap.Walk(s.Filter, func(f ldap.Filter) bool {
+			if f.Kind() == kind && !contains(indexed, f.Attribute()) {
+				t.Errorf("%s: %s filter on unindexed attribute %s (%s)", s.Base, kind, f.Attribute(), s.Filter)
+			}
+			return true
+		})
+	}
+}
+
+func (r *Recorder) AssertNeverRequested(t testing.TB, attrs ...string) {
+	t.Helper()
+	for _, s := range r.Searches() {
+		for _, a := range s.Attrs {
+			if contains(attrs, a) {
+				t.Errorf("%s: attribute %s requested", s.Base, a)
+			}
+		}
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, a := range r.compares {
+		if contains(attrs, a) {
+			t.Errorf("attribute %s compared", a)
+		}
+	}
+}
+
+func (r *Recorder) AssertNeverFiltered(t testing.TB, attrs ...string) {
+	t.Helper()
+	for _, s := range r.Searches() {
+		ldap.Walk(s.Filter, func(f ldap.Filter) bool {
+			if a := f.Attribute(); a != "" && contains(attrs, a) {
+				t.Errorf("%s: attribute %s used in filter %s", s.Base, a, s.Filter)
+			}
+			return true
+		})
+	}
+}
+
+func contains(list []string, name string) bool {
+	for _, n := range list {
+		if strings.EqualFold(n, name) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,189 @@
+package ldaptest
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/midbel/ldap"
+)
+
+const (
+	defaultImage    = "osixia/openldap:1.5.0"
+	defaultDomain   = "example.org"
+	defaultPassword = "admin"
+	defaultTimeout  = 30 * time.Second
+	ldapPort        = "389/tcp"
+)
+
+type Runtime interface {
+	Start(image string, env map[string]string, port string) (Container, error)
+}
+
+type Container interface {
+	Addr() string
+	Stop() error
+}
+
+type DockerOptions struct {
+	Image    string
+	Domain   string
+	Password string
+	Fixtures []string
+	Timeout  time.Duration
+	Runtime  Runtime
+}
+
+func (o DockerOptions) Base() string {
+	parts := strings.Split(o.Domain, ".")
+	for i := range parts {
+		parts[i] = "dc=" + parts[i]
+	}
+	return strings.Join(parts, ",")
+}
+
+func (o DockerOptions) Admin() string {
+	return "cn=admin," + o.Base()
+}
+
+func (o *DockerOptions) setDefaults() {
+	if o.Image == "" {
+		o.Image = defaultImage
+	}
+	if o.Domain == "" {
+		o.Domain = defaultDomain
+	}
+	if o.Password == "" {
+		o.Password = defaultPassword
+	}
+	if o.Timeout <= 0 {
+		o.Timeout = defaultTimeout
+	}
+	if o.Runtime == nil {
+		o.Runtime = DockerCLI{}
+	}
+}
+
+func Docker(t testing.TB, opts DockerOptions) *ldap.Client {
+	t.Helper()
+
+	opts.setDefaults()
+	if cli, ok := opts.Runtime.(DockerCLI); ok && !cli.Available() {
+		t.Skip("docker not available")
+	}
+	env := map[string]string{
+		"LDAP_DOMAIN":         opts.Domain,
+		"LDAP_ORGANISATION":   opts.Domain,
+		"LDAP_ADMIN_PASSWORD": opts.Password,
+	}
+	ctr, err := opts.Runtime.Start(opts.Image, env, ldapPort)
+	if err != nil {
+		t.Fatalf("fail to start %s: %s", opts.Image, err)
+	}
+	t.Cleanup(func() {
+		if err := ctr.Stop(); err != nil {
+			t.Logf("fail to stop container: %s", err)
+		}
+	})
+
+	client, err := waitReady(ctr.Addr(), opts.Admin(), opts.Password, opts.Timeout)
+	if err != nil {
+		t.Fatalf("%s: server not ready: %s", ctr.Addr(), err)
+	}
+	t.Cleanup(func() {
+		client.Unbind()
+	})
+	for _, f := range opts.Fixtures {
+		Load(t, client, f)
+	}
+	return client
+}
+
+func waitReady(addr, user, pass string, timeout time.Duration) (*ldap.Client, error) {
+	var (
+		deadline = time.Now().Add(timeout)
+		err      error
+	)
+	for time.Now().Before(deadline) {
+		var client *ldap.Client
+		if client, err = ldap.Bind(addr, user, pass); err == nil {
+			return client, nil
+		}
+		time.Sleep(250 * time.Millisecond)
+	}
+	return nil, err
+}
+
+type DockerCLI struct {
+	Bin string
+}
+
+func (d DockerCLI) Available() bool {
+	_, err := exec.LookPath(d.bin())
+	return err == nil
+}
+
+func (d DockerCLI) Start(image string, env map[string]string, port string) (Container, error) {
+	args := []string{"run", "-d", "-p", port}
+	for k, v := range env {
+		args = append(args, "-e", k+"="+v)
+	}
+	args = append(args, image)
+	id, err := d.exec(args...)
+	if err != nil {
+		return nil, err
+	}
+	ctr := dockerContainer{
+		id:  id,
+		cli: d,
+	}
+	addr, err := d.exec("port", id, port)
+	if err != nil {
+		ctr.Stop()
+		return nil, err
+	}
+	if x := strings.IndexByte(addr, '\n'); x >= 0 {
+		addr = addr[:x]
+	}
+	ctr.addr = strings.Replace(addr, "0.0.0.0", "127.0.0.1", 1)
+	return ctr, nil
+}
+
+func (d DockerCLI) bin() string {
+	if d.Bin == "" {
+		return "docker"
+	}
+	return d.Bin
+}
+
+func (d DockerCLI) exec(args ...string) (string, error) {
+	var (
+		stdout bytes.Buffer
+		stderr bytes.Buffer
+		cmd    = exec.Command(d.bin(), args...)
+	)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("%s %s: %w (%s)", d.bin(), args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+type dockerContainer struct {
+	id   string
+	addr string
+	cli  DockerCLI
+}
+
+func (c dockerContainer) Addr() string {
+	return c.addr
+}
+
+func (c dockerContainer) Stop() error {
+	_, err := c.cli.exec("rm", "-f", "-v", c.id)
+	return err
+}
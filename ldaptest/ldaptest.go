@@ -0,0 +1,56 @@
+package ldaptest
+
+import (
+	"fmt"
+	"os"
+	"testing"
+
+	"github.com/midbel/ldap"
+)
+
+type Directory interface {
+	Add(string, []ldap.Attribute, ...ldap.Control) ([]ldap.ControlValue, error)
+	Delete(string, ...ldap.Control) ([]ldap.ControlValue, error)
+}
+
+func Load(t testing.TB, dir Directory, file string) []string {
+	t.Helper()
+
+	r, err := os.Open(file)
+	if err != nil {
+		t.Fatalf("%s: %s", file, err)
+	}
+	defer r.Close()
+
+	var dns []string
+	err = ldap.ReadLDIF(r, func(ct ldap.ChangeType, cg ldap.Change) error {
+		if ct != ldap.ModAdd {
+			return fmt.Errorf("%s: only add records are supported in fixtures", cg.Name)
+		}
+		attrs := make([]ldap.Attribute, len(cg.Attrs))
+		for i := range cg.Attrs {
+			attrs[i] = cg.Attrs[i].Attribute
+		}
+		if _, err := dir.Add(cg.Name, attrs); err != nil {
+			return fmt.Errorf("%s: %w", cg.Name, err)
+		}
+		dns = append(dns, cg.Name)
+		return nil
+	})
+	Cleanup(t, dir, dns...)
+	if err != nil {
+		t.Fatalf("%s: %s", file, err)
+	}
+	return dns
+}
+
+func Cleanup(t testing.TB, dir Directory, dns ...string) {
+	t.Helper()
+	t.Cleanup(func() {
+		for i := len(dns) - 1; i >= 0; i-- {
+			if _, err := dir.Delete(dns[i]); err != nil {
+				t.Logf("%s: fail to remove fixture: %s", dns[i], err)
+			}
+		}
+	})
+}
@@ -0,0 +1,248 @@
+package ldaptest
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/midbel/ldap"
+)
+
+const (
+	transcriptSource   = "# source:"
+	transcriptExchange = "## "
+	transcriptRequest  = "> "
+	transcriptResponse = "< "
+)
+
+var Session = []string{
+	"bind/simple",
+	"search/base",
+	"add",
+	"modify/replace",
+	"compare/true",
+	"extended/whoami",
+	"delete",
+}
+
+type Transcript struct {
+	Source    string
+	Exchanges []Exchange
+}
+
+func Capture(addr, source string, names ...string) (Transcript, error) {
+	if len(names) == 0 {
+		names = Session
+	}
+	tr := Transcript{Source: source}
+	conn, err := net.DialTimeout("tcp", addr, time.Second*5)
+	if err != nil {
+		return tr, err
+	}
+	defer conn.Close()
+
+	var (
+		rec    = recorder{Conn: conn}
+		client = ldap.NewClient(&rec)
+	)
+	for _, n := range names {
+		x, ok := lookupExchange(n)
+		if !ok {
+			return tr, fmt.Errorf("%s: unknown exchange", n)
+		}
+		rec.reset()
+		if err := x.Run(client); err != nil {
+			return tr, fmt.Errorf("%s: %w", n, err)
+		}
+		if x.Request, x.Response, err = rec.exchange(); err != nil {
+			return tr, fmt.Errorf("%s: %w", n, err)
+		}
+		tr.Exchanges = append(tr.Exchanges, x)
+	}
+	return tr, nil
+}
+
+func ReadTranscript(r io.Reader) (Transcript, error) {
+	var (
+		tr   Transcript
+		scan = bufio.NewScanner(r)
+		curr *Exchange
+	)
+	scan.Buffer(make([]byte, 0, 1<<16), 1<<20)
+	for scan.Scan() {
+		line := strings.TrimSpace(scan.Text())
+		switch {
+		case line == "":
+		case strings.HasPrefix(line, transcriptSource):
+			tr.Source = strings.TrimSpace(line[len(transcriptSource):])
+		case strings.HasPrefix(line, transcriptExchange):
+			name := strings.TrimSpace(line[len(transcriptExchange):])
+			x, ok := lookupExchange(name)
+			if !ok {
+				return tr, fmt.Errorf("%s: unknown exchange", name)
+			}
+			x.Request, x.Response = "", nil
+			tr.Exchanges = append(tr.Exchanges, x)
+			curr = &tr.Exchanges[len(tr.Exchanges)-1]
+		case strings.HasPrefix(line, "#"):
+		case curr == nil:
+			return tr, fmt.Errorf("%s: data outside of exchange", line)
+		case strings.HasPrefix(line, transcriptRequest):
+			if curr.Request != "" {
+				return tr, fmt.Errorf("%s: multiple requests", curr.Name)
+			}
+			curr.Request = strings.TrimSpace(line[len(transcriptRequest):])
+		case strings.HasPrefix(line, transcriptResponse):
+			curr.Response = append(curr.Response, strings.TrimSpace(line[len(transcriptResponse):]))
+		default:
+			return tr, fmt.Errorf("%s: unexpected line", line)
+		}
+	}
+	if err := scan.Err(); err != nil {
+		return tr, err
+	}
+	if tr.Source == "" {
+		return tr, fmt.Errorf("transcript without source")
+	}
+	return tr, nil
+}
+
+func WriteTranscript(w io.Writer, tr Transcript) error {
+	ws := bufio.NewWriter(w)
+	fmt.Fprintf(ws, "%s %s\n", transcriptSource, tr.Source)
+	for _, x := range tr.Exchanges {
+		fmt.Fprintln(ws)
+		fmt.Fprintf(ws, "%s%s\n", transcriptExchange, x.Name)
+		fmt.Fprintf(ws, "%s%s\n", transcriptRequest, x.Request)
+		for _, r := range x.Response {
+			fmt.Fprintf(ws, "%s%s\n", transcriptResponse, r)
+		}
+	}
+	return ws.Flush()
+}
+
+func Replay(t *testing.T, tr Transcript, connect func(net.Conn) *ldap.Client) {
+	t.Helper()
+	if connect == nil {
+		connect = ldap.NewClient
+	}
+	server, conn := net.Pipe()
+	defer server.Close()
+	defer conn.Close()
+
+	errs := make(chan error, 1)
+	go func() {
+		defer server.Close()
+		for _, x := range tr.Exchanges {
+			if err := x.serve(server); err != nil {
+				errs <- fmt.Errorf("%s: %w", x.Name, err)
+				return
+			}
+		}
+		errs <- nil
+	}()
+	client := connect(conn)
+	for _, x := range tr.Exchanges {
+		if err := x.Run(client); err != nil {
+			t.Errorf("%s: %s: %s", tr.Source, x.Name, err)
+			break
+		}
+	}
+	conn.Close()
+	if err := <-errs; err != nil {
+		t.Errorf("%s: %s", tr.Source, err)
+	}
+}
+
+func lookupExchange(name string) (Exchange, bool) {
+	for _, x := range Corpus {
+		if x.Name == name {
+			return x, true
+		}
+	}
+	return Exchange{}, false
+}
+
+type recorder struct {
+	net.Conn
+	sent bytes.Buffer
+	recv bytes.Buffer
+}
+
+func (r *recorder) Read(b []byte) (int, error) {
+	n, err := r.Conn.Read(b)
+	r.recv.Write(b[:n])
+	return n, err
+}
+
+func (r *recorder) Write(b []byte) (int, error) {
+	n, err := r.Conn.Write(b)
+	r.sent.Write(b[:n])
+	return n, err
+}
+
+func (r *recorder) reset() {
+	r.sent.Reset()
+	r.recv.Reset()
+}
+
+func (r *recorder) exchange() (string, []string, error) {
+	reqs, err := splitMessages(r.sent.Bytes())
+	if err != nil {
+		return "", nil, err
+	}
+	if len(reqs) != 1 {
+		return "", nil, fmt.Errorf("expected one request, got %d", len(reqs))
+	}
+	resps, err := splitMessages(r.recv.Bytes())
+	if err != nil {
+		return "", nil, err
+	}
+	list := make([]string, len(resps))
+	for i := range resps {
+		list[i] = encodeFixture(resps[i])
+	}
+	return encodeFixture(reqs[0]), list, nil
+}
+
+func splitMessages(b []byte) ([][]byte, error) {
+	var list [][]byte
+	for len(b) > 0 {
+		if len(b) < 2 {
+			return nil, fmt.Errorf("truncated message")
+		}
+		size, head := int(b[1]), 2
+		if size&0x80 != 0 {
+			n := size & 0x7f
+			if n == 0 || n > 4 || len(b) < head+n {
+				return nil, fmt.Errorf("invalid message length")
+			}
+			size = 0
+			for _, x := range b[head : head+n] {
+				size = size<<8 | int(x)
+			}
+			head += n
+		}
+		if len(b) < head+size {
+			return nil, fmt.Errorf("truncated message")
+		}
+		list = append(list, b[:head+size])
+		b = b[head+size:]
+	}
+	return list, nil
+}
+
+func encodeFixture(b []byte) string {
+	str := hex.EncodeToString(b)
+	parts := make([]string, 0, len(b))
+	for i := 0; i < len(str); i += 2 {
+		parts = append(parts, str[i:i+2])
+	}
+	return strings.Join(parts, " ")
+}
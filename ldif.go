@@ -0,0 +1,181 @@
+package ldap
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"strings"
+)
+
+const (
+	ldifVersion     = "version"
+	ldifVersionLine = "version: 1\n"
+)
+
+type LDIFWriter struct {
+	w       io.Writer
+	width   int
+	version bool
+}
+
+func NewLDIFWriter(w io.Writer) *LDIFWriter {
+	return &LDIFWriter{
+		w:     w,
+		width: ldifWidth,
+	}
+}
+
+func (w *LDIFWriter) SetWidth(width int) {
+	w.width = width
+}
+
+func (w *LDIFWriter) WriteEntry(e Entry) error {
+	var str strings.Builder
+	appendEntry(&str, e)
+	return w.write(str.String())
+}
+
+func (w *LDIFWriter) WriteChange(ct ChangeType, c Change) error {
+	var str strings.Builder
+	switch ct {
+	case ModAdd:
+		writeLine(&str, ldifDN, c.Name)
+		writeLine(&str, ldifChange, ldifAdd)
+		for _, a := range c.Attrs {
+			appendAttribute(&str, a.Attribute)
+		}
+		str.WriteRune(newline)
+	case ModDelete:
+		writeLine(&str, ldifDN, c.Name)
+		writeLine(&str, ldifChange, ldifDel)
+		str.WriteRune(newline)
+	case ModReplace:
+		if err := appendModify(&str, c.Name, c.Attrs); err != nil {
+			return err
+		}
+	case ModRDN:
+		return w.WriteModDN(c.Name, c.NewRDN, !c.DeleteOld, c.NewSuperior)
+	default:
+		return fmt.Errorf("%d: unsupported change type", ct)
+	}
+	return w.write(str.String())
+}
+
+func (w *LDIFWriter) WriteModDN(dn, rdn string, keep bool, parent string) error {
+	var str strings.Builder
+	writeLine(&str, ldifDN, dn)
+	writeLine(&str, ldifChange, ldifModRDN)
+	writeLine(&str, ldifNewRDN, rdn)
+	if keep {
+		writeLine(&str, ldifDeleteOld, "0")
+	} else {
+		writeLine(&str, ldifDeleteOld, "1")
+	}
+	if parent != "" {
+		writeLine(&str, ldifNewSuperior, parent)
+	}
+	str.WriteRune(newline)
+	return w.write(str.String())
+}
+
+func (w *LDIFWriter) WriteComment(comment string) error {
+	var str strings.Builder
+	for _, line := range strings.Split(comment, "\n") {
+		str.WriteRune(sharp)
+		str.WriteRune(space)
+		str.WriteString(line)
+		str.WriteRune(newline)
+	}
+	return w.write(str.String())
+}
+
+func (w *LDIFWriter) write(str string) error {
+	if !w.version {
+		w.version = true
+		str = ldifVersionLine + string(newline) + str
+	}
+	if w.width != ldifWidth {
+		str = refold(str, w.width)
+	}
+	_, err := io.WriteString(w.w, str)
+	return err
+}
+
+func refold(str string, width int) string {
+	var (
+		buf   strings.Builder
+		lines = strings.Split(strings.ReplaceAll(str, "\n ", ""), "\n")
+	)
+	for i, line := range lines {
+		if i == len(lines)-1 {
+			buf.WriteString(line)
+			break
+		}
+		if strings.HasPrefix(line, string(sharp)) {
+			buf.WriteString(line)
+			buf.WriteRune(newline)
+			continue
+		}
+		foldLineAt(&buf, line, width)
+	}
+	return buf.String()
+}
+
+func appendAttribute(str *strings.Builder, a Attribute) {
+	binary := a.Description().Binary()
+	for _, v := range a.Values {
+		writeValue(str, a.Name, v, binary || !isSafeString(v))
+	}
+}
+
+func appendModify(str *strings.Builder, dn string, attrs []PartialAttribute) error {
+	writeLine(str, ldifDN, dn)
+	writeLine(str, ldifChange, ldifMod)
+	for _, a := range attrs {
+		var op string
+		switch a.Mod {
+		case ModAdd:
+			op = ldifAdd
+		case ModDelete:
+			op = ldifDel
+		case ModReplace:
+			op = ldifRep
+		case ModIncrement:
+			op = ldifInc
+		default:
+			return fmt.Errorf("%d: unknown operation", a.Mod)
+		}
+		writeLine(str, op, a.Name)
+		appendAttribute(str, a.Attribute)
+		str.WriteRune(minus)
+		str.WriteRune(newline)
+	}
+	str.WriteRune(newline)
+	return nil
+}
+
+func readVersion(rs *bufio.Reader) error {
+	prefix := []byte(ldifVersion + string(colon))
+	if buf, _ := rs.Peek(len(prefix)); !bytes.Equal(buf, prefix) {
+		return nil
+	}
+	line, err := rs.ReadString(newline)
+	if err != nil {
+		return err
+	}
+	if v := strings.TrimSpace(line[len(prefix):]); v != "1" {
+		return fmt.Errorf("%s: unsupported ldif version", v)
+	}
+	for {
+		b, err := rs.ReadByte()
+		if err != nil {
+			break
+		}
+		if b != carriage && b != newline {
+			rs.UnreadByte()
+			break
+		}
+	}
+	return nil
+}
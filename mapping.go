@@ -0,0 +1,245 @@
+package ldap
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const (
+	ProfileSCIM  = "inetorgperson-scim"
+	ProfilePosix = "ad-posix"
+)
+
+type Transform func(string) (string, error)
+
+type MapRule struct {
+	From      string `json:"from"`
+	To        string `json:"to"`
+	Transform string `json:"transform,omitempty"`
+	Single    bool   `json:"single,omitempty"`
+}
+
+type Profile struct {
+	Name  string    `json:"name"`
+	Rules []MapRule `json:"rules"`
+	Keep  bool      `json:"keep,omitempty"`
+}
+
+func (p Profile) Apply(e Entry) (Entry, error) {
+	var (
+		res  = Entry{Name: e.Name}
+		used = make(map[int]struct{})
+	)
+	for _, r := range p.Rules {
+		var values []string
+		for i, a := range e.Attrs {
+			if !matchAttribute(a, r.From) {
+				continue
+			}
+			used[i] = struct{}{}
+			for _, v := range a.Values {
+				v, err := applyTransform(r.Transform, v)
+				if err != nil {
+					return res, fmt.Errorf("%s: %w", r.From, err)
+				}
+				values = append(values, v)
+			}
+		}
+		if len(values) == 0 {
+			continue
+		}
+		if r.Single {
+			values = values[:1]
+		}
+		res.Attrs = appendValues(res.Attrs, r.To, values)
+	}
+	if !p.Keep {
+		return res, nil
+	}
+	for i, a := range e.Attrs {
+		if _, ok := used[i]; ok {
+			continue
+		}
+		res.Attrs = appendValues(res.Attrs, a.Name, a.Values)
+	}
+	return res, nil
+}
+
+func (p Profile) Reverse() Profile {
+	rev := Profile{
+		Name: p.Name,
+		Keep: p.Keep,
+	}
+	for _, r := range p.Rules {
+		if r.Transform != "" {
+			continue
+		}
+		rev.Rules = append(rev.Rules, MapRule{
+			From:   r.To,
+			To:     r.From,
+			Single: r.Single,
+		})
+	}
+	return rev
+}
+
+func appendValues(attrs []Attribute, name string, values []string) []Attribute {
+	for i := range attrs {
+		if strings.EqualFold(attrs[i].Name, name) {
+			attrs[i].Values = append(attrs[i].Values, values...)
+			return attrs
+		}
+	}
+	return append(attrs, Attribute{
+		Name:   name,
+		Values: append([]string{}, values...),
+	})
+}
+
+var transforms = struct {
+	mu  sync.RWMutex
+	set map[string]Transform
+}{
+	set: map[string]Transform{
+		"lower": func(v string) (string, error) { return strings.ToLower(v), nil },
+		"upper": func(v string) (string, error) { return strings.ToUpper(v), nil },
+		"trim":  func(v string) (string, error) { return strings.TrimSpace(v), nil },
+		"rdn":   rdnValue,
+		"guid":  func(v string) (string, error) { return DecodeGUID([]byte(v)) },
+		"sid":   func(v string) (string, error) { return DecodeSID([]byte(v)) },
+	},
+}
+
+func RegisterTransform(name string, fn Transform) {
+	transforms.mu.Lock()
+	defer transforms.mu.Unlock()
+
+	name = strings.ToLower(name)
+	if fn == nil {
+		delete(transforms.set, name)
+		return
+	}
+	transforms.set[name] = fn
+}
+
+func applyTransform(name, value string) (string, error) {
+	if name == "" {
+		return value, nil
+	}
+	transforms.mu.RLock()
+	defer transforms.mu.RUnlock()
+
+	fn, ok := transforms.set[strings.ToLower(name)]
+	if !ok {
+		return "", fmt.Errorf("%s: unknown transform", name)
+	}
+	return fn(value)
+}
+
+func rdnValue(value string) (string, error) {
+	dn, err := Explode(value)
+	if err != nil {
+		return "", err
+	}
+	rdn := dn.RDN()
+	if len(rdn.attrs) == 0 {
+		return "", fmt.Errorf("%s: empty DN", value)
+	}
+	return rdn.attrs[0].Values[0], nil
+}
+
+var profiles = struct {
+	mu  sync.RWMutex
+	set map[string]Profile
+}{
+	set: map[string]Profile{
+		ProfileSCIM: {
+			Name: ProfileSCIM,
+			Rules: []MapRule{
+				{From: "uid", To: "userName", Single: true},
+				{From: "cn", To: "name.formatted", Single: true},
+				{From: "givenName", To: "name.givenName", Single: true},
+				{From: "sn", To: "name.familyName", Single: true},
+				{From: "displayName", To: "displayName", Single: true},
+				{From: "title", To: "title", Single: true},
+				{From: "preferredLanguage", To: "preferredLanguage", Single: true},
+				{From: "mail", To: "emails"},
+				{From: "telephoneNumber", To: "phoneNumbers"},
+				{From: "mobile", To: "phoneNumbers"},
+				{From: "employeeNumber", To: "employeeNumber", Single: true},
+				{From: "departmentNumber", To: "department", Single: true},
+				{From: "o", To: "organization", Single: true},
+				{From: "manager", To: "manager", Single: true},
+			},
+		},
+		ProfilePosix: {
+			Name: ProfilePosix,
+			Rules: []MapRule{
+				{From: "sAMAccountName", To: "uid", Single: true},
+				{From: "cn", To: "cn"},
+				{From: "sn", To: "sn"},
+				{From: "givenName", To: "givenName"},
+				{From: "displayName", To: "gecos", Single: true},
+				{From: "mail", To: "mail"},
+				{From: "uidNumber", To: "uidNumber", Single: true},
+				{From: "gidNumber", To: "gidNumber", Single: true},
+				{From: "unixHomeDirectory", To: "homeDirectory", Single: true},
+				{From: "loginShell", To: "loginShell", Single: true},
+				{From: "member", To: "memberUid", Transform: "rdn"},
+			},
+		},
+	},
+}
+
+func RegisterProfile(p Profile) {
+	profiles.mu.Lock()
+	defer profiles.mu.Unlock()
+
+	profiles.set[strings.ToLower(p.Name)] = p
+}
+
+func ProfileByName(name string) (Profile, error) {
+	profiles.mu.RLock()
+	defer profiles.mu.RUnlock()
+
+	p, ok := profiles.set[strings.ToLower(name)]
+	if !ok {
+		return p, fmt.Errorf("%s: unknown mapping profile", name)
+	}
+	return p, nil
+}
+
+func Profiles() []string {
+	profiles.mu.RLock()
+	defer profiles.mu.RUnlock()
+
+	var names []string
+	for n := range profiles.set {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
+
+func LoadProfiles(r io.Reader) ([]Profile, error) {
+	var list []Profile
+	if err := json.NewDecoder(r).Decode(&list); err != nil {
+		return nil, err
+	}
+	for _, p := range list {
+		if p.Name == "" {
+			return nil, fmt.Errorf("mapping profile without name")
+		}
+		for _, r := range p.Rules {
+			if r.From == "" || r.To == "" {
+				return nil, fmt.Errorf("%s: rule without source or target attribute", p.Name)
+			}
+		}
+		RegisterProfile(p)
+	}
+	return list, nil
+}
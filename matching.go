@@ -0,0 +1,194 @@
+package ldap
+
+import (
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const canonicalTime = "20060102150405.000000000Z"
+
+type MatchingRule uint8
+
+const (
+	MatchCaseIgnore MatchingRule = iota
+	MatchCaseExact
+	MatchNumeric
+	MatchTelephone
+	MatchDN
+	MatchOctet
+	MatchBoolean
+	MatchInteger
+	MatchGeneralizedTime
+)
+
+func (m MatchingRule) String() string {
+	switch m {
+	case MatchCaseIgnore:
+		return "caseIgnoreMatch"
+	case MatchCaseExact:
+		return "caseExactMatch"
+	case MatchNumeric:
+		return "numericStringMatch"
+	case MatchTelephone:
+		return "telephoneNumberMatch"
+	case MatchDN:
+		return "distinguishedNameMatch"
+	case MatchOctet:
+		return "octetStringMatch"
+	case MatchBoolean:
+		return "booleanMatch"
+	case MatchInteger:
+		return "integerMatch"
+	case MatchGeneralizedTime:
+		return "generalizedTimeMatch"
+	default:
+		return "unknown"
+	}
+}
+
+var matchingRuleNames = map[string]MatchingRule{
+	"caseignorematch":            MatchCaseIgnore,
+	"2.5.13.2":                   MatchCaseIgnore,
+	"caseignoreia5match":         MatchCaseIgnore,
+	"1.3.6.1.4.1.1466.109.114.2": MatchCaseIgnore,
+	"objectidentifiermatch":      MatchCaseIgnore,
+	"2.5.13.0":                   MatchCaseIgnore,
+	"caseexactmatch":             MatchCaseExact,
+	"2.5.13.5":                   MatchCaseExact,
+	"caseexactia5match":          MatchCaseExact,
+	"1.3.6.1.4.1.1466.109.114.1": MatchCaseExact,
+	"numericstringmatch":         MatchNumeric,
+	"2.5.13.8":                   MatchNumeric,
+	"telephonenumbermatch":       MatchTelephone,
+	"2.5.13.20":                  MatchTelephone,
+	"distinguishednamematch":     MatchDN,
+	"2.5.13.1":                   MatchDN,
+	"octetstringmatch":           MatchOctet,
+	"2.5.13.17":                  MatchOctet,
+	"booleanmatch":               MatchBoolean,
+	"2.5.13.13":                  MatchBoolean,
+	"integermatch":               MatchInteger,
+	"2.5.13.14":                  MatchInteger,
+	"generalizedtimematch":       MatchGeneralizedTime,
+	"2.5.13.27":                  MatchGeneralizedTime,
+}
+
+func MatchingRuleNamed(name string) (MatchingRule, bool) {
+	rule, ok := matchingRuleNames[strings.ToLower(name)]
+	return rule, ok
+}
+
+var matchingRules = struct {
+	mu  sync.RWMutex
+	set map[string]MatchingRule
+}{
+	set: map[string]MatchingRule{
+		"telephonenumber":          MatchTelephone,
+		"facsimiletelephonenumber": MatchTelephone,
+		"mobile":                   MatchTelephone,
+		"homephone":                MatchTelephone,
+		"pager":                    MatchTelephone,
+		"member":                   MatchDN,
+		"uniquemember":             MatchDN,
+		"memberof":                 MatchDN,
+		"owner":                    MatchDN,
+		"manager":                  MatchDN,
+		"secretary":                MatchDN,
+		"seealso":                  MatchDN,
+		"roleoccupant":             MatchDN,
+		"distinguishedname":        MatchDN,
+		"creatorsname":             MatchDN,
+		"modifiersname":            MatchDN,
+		"uidnumber":                MatchInteger,
+		"gidnumber":                MatchInteger,
+		"createtimestamp":          MatchGeneralizedTime,
+		"modifytimestamp":          MatchGeneralizedTime,
+		"pwdchangedtime":           MatchGeneralizedTime,
+		"userpassword":             MatchOctet,
+		"usercertificate":          MatchOctet,
+		"cacertificate":            MatchOctet,
+		"jpegphoto":                MatchOctet,
+		attrObjectGUID:             MatchOctet,
+		attrObjectSID:              MatchOctet,
+	},
+}
+
+func RegisterMatchingRule(attr string, rule MatchingRule) {
+	matchingRules.mu.Lock()
+	defer matchingRules.mu.Unlock()
+
+	matchingRules.set[strings.ToLower(attr)] = rule
+}
+
+func MatchingRuleFor(attr string) MatchingRule {
+	desc := ParseAttributeDescription(attr)
+	if desc.Binary() {
+		return MatchOctet
+	}
+	matchingRules.mu.RLock()
+	defer matchingRules.mu.RUnlock()
+
+	if rule, ok := matchingRules.set[strings.ToLower(desc.Type)]; ok {
+		return rule
+	}
+	return MatchCaseIgnore
+}
+
+func (m MatchingRule) Canonical(value string) string {
+	var (
+		str string
+		err error
+	)
+	switch m {
+	case MatchCaseIgnore:
+		str, err = Prepare(value, PrepCaseIgnore)
+	case MatchCaseExact:
+		str, err = Prepare(value, PrepExact)
+	case MatchNumeric:
+		str, err = Prepare(value, PrepNumeric)
+	case MatchTelephone:
+		str, err = Prepare(value, PrepTelephone)
+	case MatchDN:
+		str, err = canonicalDN(value)
+	case MatchBoolean:
+		str = strings.ToUpper(strings.TrimSpace(value))
+	case MatchInteger:
+		var n int64
+		if n, err = strconv.ParseInt(strings.TrimSpace(value), 10, 64); err == nil {
+			str = strconv.FormatInt(n, 10)
+		}
+	case MatchGeneralizedTime:
+		var t time.Time
+		if t, err = ParseGeneralizedTime(strings.TrimSpace(value)); err == nil {
+			str = t.UTC().Format(canonicalTime)
+		}
+	default:
+		str = value
+	}
+	if err != nil {
+		return value
+	}
+	return str
+}
+
+func (m MatchingRule) Match(left, right string) bool {
+	return m.Canonical(left) == m.Canonical(right)
+}
+
+func MatchValues(attr, left, right string) bool {
+	return canonicalValue(attr, left) == canonicalValue(attr, right)
+}
+
+func canonicalValue(attr, value string) string {
+	return MatchingRuleFor(attr).Canonical(Normalize(attr, value))
+}
+
+func canonicalDN(value string) (string, error) {
+	dn, err := Explode(value)
+	if err != nil {
+		return "", err
+	}
+	return dn.Normalize().String(), nil
+}
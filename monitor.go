@@ -0,0 +1,120 @@
+package ldap
+
+import (
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	monitorBase        = "cn=Monitor"
+	monitorConnections = "cn=connections"
+	monitorOperations  = "cn=operations"
+	monitorWaiters     = "cn=waiters"
+	monitorStatistics  = "cn=statistics"
+	monitorTime        = "cn=time"
+	monitorUptime      = "cn=uptime"
+
+	attrMonitorCounter   = "monitorCounter"
+	attrMonitorInitiated = "monitorOpInitiated"
+	attrMonitorCompleted = "monitorOpCompleted"
+	attrMonitoredInfo    = "monitoredInfo"
+	attrCommonName       = "cn"
+)
+
+type MonitorCounter struct {
+	Name  string `json:"name"`
+	Value int64  `json:"value"`
+}
+
+type MonitorOperation struct {
+	Name      string `json:"name"`
+	Initiated int64  `json:"initiated"`
+	Completed int64  `json:"completed"`
+}
+
+type Monitor struct {
+	When        time.Time          `json:"when"`
+	Uptime      time.Duration      `json:"uptime"`
+	Connections []MonitorCounter   `json:"connections"`
+	Operations  []MonitorOperation `json:"operations"`
+	Waiters     []MonitorCounter   `json:"waiters"`
+	Statistics  []MonitorCounter   `json:"statistics"`
+}
+
+func (m Monitor) Operation(name string) (MonitorOperation, bool) {
+	for _, o := range m.Operations {
+		if strings.EqualFold(o.Name, name) {
+			return o, true
+		}
+	}
+	return MonitorOperation{}, false
+}
+
+func (c *Client) Monitor() (Monitor, error) {
+	var (
+		mon   = Monitor{When: time.Now()}
+		attrs = []string{
+			attrCommonName,
+			attrMonitorCounter,
+			attrMonitorInitiated,
+			attrMonitorCompleted,
+			attrMonitoredInfo,
+		}
+	)
+	es, _, err := c.Search(monitorBase, WithScope(ScopeWhole), WithAttributes(attrs))
+	if err != nil {
+		return mon, err
+	}
+	for _, e := range es {
+		dn, err := Explode(e.Name)
+		if err != nil || dn.Len() < 2 {
+			continue
+		}
+		var (
+			name   = e.GetValue(attrCommonName)
+			parent = strings.ToLower(dn.At(1).String())
+		)
+		if name == "" {
+			continue
+		}
+		switch parent {
+		case monitorConnections:
+			if ct, ok := monitorCounter(name, e); ok {
+				mon.Connections = append(mon.Connections, ct)
+			}
+		case monitorWaiters:
+			if ct, ok := monitorCounter(name, e); ok {
+				mon.Waiters = append(mon.Waiters, ct)
+			}
+		case monitorStatistics:
+			if ct, ok := monitorCounter(name, e); ok {
+				mon.Statistics = append(mon.Statistics, ct)
+			}
+		case monitorOperations:
+			var op MonitorOperation
+			op.Name = name
+			op.Initiated, _ = strconv.ParseInt(e.GetValue(attrMonitorInitiated), 10, 64)
+			op.Completed, _ = strconv.ParseInt(e.GetValue(attrMonitorCompleted), 10, 64)
+			mon.Operations = append(mon.Operations, op)
+		case monitorTime:
+			if strings.EqualFold(dn.RDN().String(), monitorUptime) {
+				secs, _ := strconv.ParseInt(e.GetValue(attrMonitoredInfo), 10, 64)
+				mon.Uptime = time.Duration(secs) * time.Second
+			}
+		}
+	}
+	return mon, nil
+}
+
+func monitorCounter(name string, e Entry) (MonitorCounter, bool) {
+	str := e.GetValue(attrMonitorCounter)
+	if str == "" {
+		return MonitorCounter{}, false
+	}
+	n, err := strconv.ParseInt(str, 10, 64)
+	if err != nil {
+		return MonitorCounter{}, false
+	}
+	return MonitorCounter{Name: name, Value: n}, true
+}
@@ -0,0 +1,320 @@
+package ldap
+
+import (
+	"fmt"
+	"io"
+	"sort"
+)
+
+var referenceAttrs = []string{
+	"member",
+	"uniqueMember",
+	"owner",
+	"manager",
+	"secretary",
+	"seeAlso",
+	"roleOccupant",
+}
+
+type Relocation struct {
+	Subtree bool
+	Root    string
+	Attrs   []string
+
+	DryRun bool
+	Plan   io.Writer
+}
+
+type MoveStep struct {
+	Op      OpType
+	DN      string
+	Target  string
+	Attrs   []Attribute
+	Changes []PartialAttribute
+}
+
+func (s MoveStep) String() string {
+	switch s.Op {
+	case OpModDN:
+		return fmt.Sprintf("%s %s -> %s", s.Op, s.DN, s.Target)
+	case OpAdd:
+		return fmt.Sprintf("%s %s (from %s)", s.Op, s.Target, s.DN)
+	default:
+		return fmt.Sprintf("%s %s", s.Op, s.DN)
+	}
+}
+
+func (c *Client) MoveSubtree(oldBase, newBase string, rl Relocation) ([]MoveStep, error) {
+	from, err := Explode(oldBase)
+	if err != nil {
+		return nil, err
+	}
+	to, err := Explode(newBase)
+	if err != nil {
+		return nil, err
+	}
+	if to.Equal(from) || to.IsDescendantOf(from) {
+		return nil, fmt.Errorf("%s: can not move subtree %s below itself", newBase, oldBase)
+	}
+	var (
+		manage = ManageDsaIT()
+		es     []Entry
+	)
+	_, err = c.Stream(oldBase, func(e Entry) error {
+		es = append(es, e)
+		return nil
+	}, WithScope(ScopeWhole), WithDeref(DerefNever), WithControl(manage))
+	if err != nil {
+		return nil, err
+	}
+	steps, err := rl.plan(from, to, es)
+	if err != nil {
+		return nil, err
+	}
+	root := rl.Root
+	if root == "" {
+		root = from.CommonAncestor(to).String()
+	}
+	if root == "" {
+		root = oldBase
+	}
+	refs, err := c.references(root, from, to, rl.Attrs, manage)
+	if err != nil {
+		return nil, err
+	}
+	for _, s := range refs {
+		if dn, err := Explode(s.DN); err == nil {
+			if target, ok := relocate(dn, from, to); ok {
+				s.DN = target.String()
+			}
+		}
+		steps = append(steps, s)
+	}
+
+	var (
+		done []MoveStep
+		plan *LDIFWriter
+	)
+	if rl.Plan != nil {
+		plan = NewLDIFWriter(rl.Plan)
+	}
+	for _, s := range steps {
+		if plan != nil {
+			if err := writeStep(plan, s); err != nil {
+				return done, err
+			}
+		}
+		if !rl.DryRun {
+			if err := c.applyStep(s, manage); err != nil {
+				return done, fmt.Errorf("%s: %w", s.DN, err)
+			}
+		}
+		done = append(done, s)
+	}
+	return done, nil
+}
+
+func (r Relocation) plan(from, to DN, es []Entry) ([]MoveStep, error) {
+	if r.Subtree || len(es) <= 1 {
+		step := MoveStep{
+			Op:     OpModDN,
+			DN:     from.String(),
+			Target: to.String(),
+		}
+		return []MoveStep{step}, nil
+	}
+	sort.SliceStable(es, func(i, j int) bool {
+		return depthOf(es[i].Name) < depthOf(es[j].Name)
+	})
+	var (
+		names   = make([]DN, len(es))
+		parents = make(map[string]struct{})
+	)
+	for i, e := range es {
+		dn, err := Explode(e.Name)
+		if err != nil {
+			return nil, err
+		}
+		names[i] = dn
+		parents[dn.Parent(1).Normalize().String()] = struct{}{}
+	}
+	var adds, moves, dels []MoveStep
+	for i, e := range es {
+		target, _ := relocate(names[i], from, to)
+		if _, ok := parents[names[i].Normalize().String()]; !ok {
+			moves = append(moves, MoveStep{
+				Op:     OpModDN,
+				DN:     e.Name,
+				Target: target.String(),
+			})
+			continue
+		}
+		adds = append(adds, MoveStep{
+			Op:     OpAdd,
+			DN:     e.Name,
+			Target: target.String(),
+			Attrs:  renameAttrs(e.Attrs, names[i].RDN(), target.RDN()),
+		})
+		dels = append([]MoveStep{{Op: OpDelete, DN: e.Name}}, dels...)
+	}
+	steps := append(adds, moves...)
+	return append(steps, dels...), nil
+}
+
+func (c *Client) references(root string, from, to DN, attrs []string, controls ...Control) ([]MoveStep, error) {
+	if len(attrs) == 0 {
+		attrs = referenceAttrs
+	}
+	var fs []Filter
+	for _, a := range attrs {
+		fs = append(fs, Present(a))
+	}
+	options := []SearchOption{
+		WithScope(ScopeWhole),
+		WithDeref(DerefNever),
+		WithAttributes(attrs),
+		WithFilter(Or(fs...)),
+	}
+	for _, ctrl := range controls {
+		options = append(options, WithControl(ctrl))
+	}
+	var steps []MoveStep
+	_, err := c.Stream(root, func(e Entry) error {
+		var changes []PartialAttribute
+		for _, a := range e.Attrs {
+			var prev, next []string
+			for _, v := range a.Values {
+				dn, err := Explode(v)
+				if err != nil {
+					continue
+				}
+				if target, ok := relocate(dn, from, to); ok {
+					prev = append(prev, v)
+					next = append(next, target.String())
+				}
+			}
+			if len(prev) == 0 {
+				continue
+			}
+			changes = append(changes, createModification(ModDelete, a.Name, prev))
+			changes = append(changes, createModification(ModAdd, a.Name, next))
+		}
+		if len(changes) == 0 {
+			return nil
+		}
+		steps = append(steps, MoveStep{
+			Op:      OpModify,
+			DN:      e.Name,
+			Changes: changes,
+		})
+		return nil
+	}, options...)
+	return steps, err
+}
+
+func (c *Client) applyStep(s MoveStep, controls ...Control) error {
+	var err error
+	switch s.Op {
+	case OpModDN:
+		var target DN
+		if target, err = Explode(s.Target); err != nil {
+			break
+		}
+		msg := modDNRequest{
+			Name:      s.DN,
+			RDN:       target.RDN().String(),
+			DeleteOld: true,
+			Superior:  target.Parent(1).String(),
+		}
+		_, err = c.modDN(msg, controls)
+	case OpAdd:
+		_, err = c.Add(s.Target, s.Attrs, controls...)
+	case OpDelete:
+		_, err = c.Delete(s.DN, controls...)
+	case OpModify:
+		_, err = c.Modify(s.DN, s.Changes, controls...)
+	default:
+		err = fmt.Errorf("%s: unsupported operation", s.Op)
+	}
+	return err
+}
+
+func relocate(dn, from, to DN) (DN, bool) {
+	if dn.Equal(from) {
+		return to, true
+	}
+	if !dn.IsDescendantOf(from) {
+		return dn, false
+	}
+	rel := DN{parts: dn.parts[:dn.Len()-from.Len()]}
+	return rel.Append(to), true
+}
+
+func renameAttrs(attrs []Attribute, prev, next RDN) []Attribute {
+	list := make([]Attribute, 0, len(attrs))
+	for _, a := range attrs {
+		a.Values = append([]string{}, a.Values...)
+		for _, r := range prev.attrs {
+			if !matchAttribute(a, r.Name) {
+				continue
+			}
+			for i := 0; i < len(a.Values); i++ {
+				if MatchValues(a.Name, a.Values[i], r.Values[0]) {
+					a.Values = append(a.Values[:i], a.Values[i+1:]...)
+					i--
+				}
+			}
+		}
+		if len(a.Values) > 0 {
+			list = append(list, a)
+		}
+	}
+	for _, r := range next.attrs {
+		found := false
+		for i := range list {
+			if !matchAttribute(list[i], r.Name) {
+				continue
+			}
+			found = true
+			if !containsValue(list[i], r.Values[0]) {
+				list[i].Values = append(list[i].Values, r.Values[0])
+			}
+		}
+		if !found {
+			list = append(list, Attribute{Name: r.Name, Values: []string{r.Values[0]}})
+		}
+	}
+	return list
+}
+
+func containsValue(a Attribute, value string) bool {
+	for _, v := range a.Values {
+		if MatchValues(a.Name, v, value) {
+			return true
+		}
+	}
+	return false
+}
+
+func writeStep(w *LDIFWriter, s MoveStep) error {
+	switch s.Op {
+	case OpModDN:
+		target, err := Explode(s.Target)
+		if err != nil {
+			return err
+		}
+		return w.WriteModDN(s.DN, target.RDN().String(), false, target.Parent(1).String())
+	case OpAdd:
+		c := Change{Name: s.Target}
+		for _, a := range s.Attrs {
+			c.Attrs = append(c.Attrs, PartialAttribute{Mod: ModAdd, Attribute: a})
+		}
+		return w.WriteChange(ModAdd, c)
+	case OpDelete:
+		return w.WriteChange(ModDelete, Change{Name: s.DN})
+	case OpModify:
+		return w.WriteChange(ModReplace, Change{Name: s.DN, Attrs: s.Changes})
+	default:
+		return fmt.Errorf("%s: unsupported operation", s.Op)
+	}
+}
@@ -0,0 +1,119 @@
+package ldap
+
+import (
+	"fmt"
+	"strings"
+)
+
+type DNValidation struct {
+	Schema  *Schema
+	Classes []string
+}
+
+type DNViolation struct {
+	Index  int
+	Attr   string
+	Value  string
+	Reason string
+}
+
+func (v DNViolation) Error() string {
+	if v.Attr == "" {
+		return v.Reason
+	}
+	return fmt.Sprintf("rdn #%d (%s=%s): %s", v.Index, v.Attr, v.Value, v.Reason)
+}
+
+type DNError struct {
+	DN         string
+	Violations []DNViolation
+}
+
+func (e DNError) Error() string {
+	parts := make([]string, len(e.Violations))
+	for i, v := range e.Violations {
+		parts[i] = v.Error()
+	}
+	return fmt.Sprintf("%s: %s", e.DN, strings.Join(parts, "; "))
+}
+
+func (e DNError) Unwrap() error {
+	return ErrInvalidDN
+}
+
+func ValidateDN(dn string, opts DNValidation) error {
+	name, err := Explode(dn)
+	if err != nil {
+		return DNError{
+			DN:         dn,
+			Violations: []DNViolation{{Reason: err.Error()}},
+		}
+	}
+	var list []DNViolation
+	for i, rdn := range name.parts {
+		seen := make(map[string]struct{})
+		for _, a := range rdn.attrs {
+			violation := DNViolation{
+				Index: i,
+				Attr:  a.Name,
+				Value: a.Values[0],
+			}
+			if reason := checkAttrType(a.Name); reason != "" {
+				violation.Reason = reason
+				list = append(list, violation)
+			}
+			key := strings.ToLower(a.Name)
+			if _, ok := seen[key]; ok {
+				violation.Reason = "attribute type repeated in rdn"
+				list = append(list, violation)
+			}
+			seen[key] = struct{}{}
+			if opts.Schema != nil && len(opts.Schema.NameForms) > 0 && !opts.Schema.isNaming(a.Name) {
+				violation.Reason = "attribute type is not a naming attribute"
+				list = append(list, violation)
+			}
+		}
+	}
+	if opts.Schema != nil && len(opts.Classes) > 0 && len(list) == 0 {
+		if err := opts.Schema.CheckName(dn, opts.Classes, nil); err != nil {
+			list = append(list, DNViolation{Reason: err.Error()})
+		}
+	}
+	if len(list) == 0 {
+		return nil
+	}
+	return DNError{
+		DN:         dn,
+		Violations: list,
+	}
+}
+
+func checkAttrType(name string) string {
+	if name == "" {
+		return "empty attribute type"
+	}
+	if !isDigit(rune(name[0])) {
+		if !isLetter(rune(name[0])) {
+			return "descriptor should start with a letter"
+		}
+		return ""
+	}
+	for _, n := range strings.Split(name, string(dot)) {
+		switch {
+		case n == "":
+			return "empty component in numeric oid"
+		case len(n) > 1 && n[0] == '0':
+			return "leading zero in numeric oid"
+		}
+	}
+	return ""
+}
+
+func (s *Schema) isNaming(attr string) bool {
+	for _, nf := range s.NameForms {
+		if containsName(nf.Must, attr) || containsName(nf.May, attr) {
+			return true
+		}
+	}
+	return false
+}
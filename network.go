@@ -0,0 +1,86 @@
+package ldap
+
+import (
+	"errors"
+	"fmt"
+)
+
+var ErrNetwork = errors.New("network failure")
+
+type NetError struct {
+	Op   string
+	Addr string
+	Err  error
+}
+
+func (e *NetError) Error() string {
+	return fmt.Sprintf("%s %s: %s: %s", e.Op, e.Addr, ErrNetwork, e.Err)
+}
+
+func (e *NetError) Unwrap() error {
+	return e.Err
+}
+
+func (e *NetError) Is(target error) bool {
+	return target == ErrNetwork
+}
+
+func (c *Client) send(body []byte) error {
+	c.op = operationOf(body)
+	if _, err := c.conn.Write(body); err != nil {
+		return c.netError(err)
+	}
+	return nil
+}
+
+func (c *Client) recv(body []byte) (int, error) {
+	n, err := c.conn.Read(body)
+	if err != nil {
+		return n, c.netError(err)
+	}
+	return n, nil
+}
+
+func (c *Client) netError(err error) error {
+	return &NetError{
+		Op:   c.op,
+		Addr: c.addr,
+		Err:  err,
+	}
+}
+
+func operationOf(body []byte) string {
+	_, msg, _, err := decodeElement(body)
+	if err != nil {
+		return "unknown"
+	}
+	if _, _, msg, err = decodeElement(msg); err != nil || len(msg) == 0 {
+		return "unknown"
+	}
+	var op OpType
+	switch uint64(msg[0] & 0x1f) {
+	case ldapBindRequest:
+		op = OpBind
+	case ldapUnbindRequest:
+		op = OpUnbind
+	case ldapSearchRequest:
+		op = OpSearch
+	case ldapModifyRequest:
+		op = OpModify
+	case ldapAddRequest:
+		op = OpAdd
+	case ldapDelRequest:
+		op = OpDelete
+	case ldapModDNRequest:
+		op = OpModDN
+	case ldapCmpRequest:
+		op = OpCompare
+	case ldapAbandonRequest:
+		op = OpAbandon
+	case ldapExtendedRequest:
+		op = OpExtended
+	default:
+		return "unknown"
+	}
+	return op.String()
+}
@@ -0,0 +1,99 @@
+package ldap
+
+import (
+	"strings"
+	"sync"
+)
+
+type Normalizer func(string) string
+
+var normalizers = struct {
+	mu  sync.RWMutex
+	set map[string]Normalizer
+}{
+	set: map[string]Normalizer{
+		"telephonenumber":          NormalizeTelephone,
+		"facsimiletelephonenumber": NormalizeTelephone,
+		"mobile":                   NormalizeTelephone,
+		"homephone":                NormalizeTelephone,
+		"pager":                    NormalizeTelephone,
+		"mail":                     NormalizeMail,
+		"rfc822mailbox":            NormalizeMail,
+		"postaladdress":            NormalizePostalAddress,
+		"homepostaladdress":        NormalizePostalAddress,
+		"registeredaddress":        NormalizePostalAddress,
+	},
+}
+
+func RegisterNormalizer(attr string, fn Normalizer) {
+	normalizers.mu.Lock()
+	defer normalizers.mu.Unlock()
+
+	attr = strings.ToLower(attr)
+	if fn == nil {
+		delete(normalizers.set, attr)
+		return
+	}
+	normalizers.set[attr] = fn
+}
+
+func Normalize(attr, value string) string {
+	normalizers.mu.RLock()
+	defer normalizers.mu.RUnlock()
+
+	fn, ok := normalizers.set[strings.ToLower(attr)]
+	if !ok {
+		return value
+	}
+	return fn(value)
+}
+
+func NormalizeTelephone(value string) string {
+	value = strings.Join(strings.Fields(value), " ")
+	value = strings.ReplaceAll(value, " - ", "-")
+	return value
+}
+
+func NormalizeMail(value string) string {
+	return strings.ToLower(strings.TrimSpace(value))
+}
+
+func NormalizePostalAddress(value string) string {
+	return JoinPostalAddress(SplitPostalAddress(value))
+}
+
+func SplitPostalAddress(value string) []string {
+	var lines []string
+	for _, line := range strings.Split(value, "$") {
+		line = strings.ReplaceAll(line, `\24`, "$")
+		line = strings.ReplaceAll(line, `\5C`, `\`)
+		line = strings.ReplaceAll(line, `\5c`, `\`)
+		lines = append(lines, strings.Join(strings.Fields(line), " "))
+	}
+	return lines
+}
+
+func JoinPostalAddress(lines []string) string {
+	parts := make([]string, len(lines))
+	for i, line := range lines {
+		line = strings.ReplaceAll(line, `\`, `\5C`)
+		parts[i] = strings.ReplaceAll(line, "$", `\24`)
+	}
+	return strings.Join(parts, "$")
+}
+
+func (e Entry) GetNormalized(name string) []string {
+	values := e.GetValues(name)
+	for i := range values {
+		values[i] = Normalize(name, values[i])
+	}
+	return values
+}
+
+func (e Entry) GetPostalAddress(name string) [][]string {
+	var lines [][]string
+	for _, v := range e.GetValues(name) {
+		lines = append(lines, SplitPostalAddress(v))
+	}
+	return lines
+}
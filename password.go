@@ -0,0 +1,397 @@
+package ldap
+
+import (
+	"crypto/md5"
+	"crypto/rand"
+	"crypto/sha1"
+	"crypto/sha256"
+	"crypto/sha512"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"hash"
+	"strconv"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+	"golang.org/x/crypto/pbkdf2"
+)
+
+const attrUserPassword = "userPassword"
+
+const (
+	schemeCrypt  = "CRYPT"
+	schemeBcrypt = "BCRYPT"
+	schemePBKDF2 = "PBKDF2"
+
+	saltSize          = 8
+	pbkdf2SaltSize    = 16
+	pbkdf2Iterations  = 10000
+	cryptSaltSize     = 16
+	cryptRounds       = 5000
+	cryptMinRounds    = 1000
+	cryptMaxRounds    = 999999999
+	cryptPrefixSHA512 = "$6$"
+	cryptPrefixBcrypt = "$2"
+	cryptPrefixRounds = "rounds="
+	cryptAlphabet     = "./0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+)
+
+var ErrUnsupportedScheme = errors.New("unsupported password scheme")
+
+var passwordSchemes = map[string]func() hash.Hash{
+	"MD5":    md5.New,
+	"SHA":    sha1.New,
+	"SHA256": sha256.New,
+	"SHA512": sha512.New,
+}
+
+func VerifyPassword(stored, passwd string) (bool, error) {
+	if !strings.HasPrefix(stored, "{") {
+		return subtle.ConstantTimeCompare([]byte(stored), []byte(passwd)) == 1, nil
+	}
+	x := strings.Index(stored, "}")
+	if x < 0 {
+		return false, fmt.Errorf("%s: malformed password scheme", stored)
+	}
+	scheme, value := strings.ToUpper(stored[1:x]), stored[x+1:]
+	if scheme == "CLEARTEXT" || scheme == "PLAIN" {
+		return subtle.ConstantTimeCompare([]byte(value), []byte(passwd)) == 1, nil
+	}
+	switch {
+	case scheme == schemeCrypt:
+		return verifyCrypt(value, passwd)
+	case scheme == schemeBcrypt:
+		return bcrypt.CompareHashAndPassword([]byte(value), []byte(passwd)) == nil, nil
+	case strings.HasPrefix(scheme, schemePBKDF2):
+		return verifyPBKDF2(scheme, value, passwd)
+	}
+	salted := strings.HasPrefix(scheme, "S") && scheme != "SHA" && scheme != "SHA256" && scheme != "SHA512"
+	if salted {
+		scheme = scheme[1:]
+	}
+	fn, ok := passwordSchemes[scheme]
+	if !ok {
+		return false, fmt.Errorf("%w: %s", ErrUnsupportedScheme, stored[1:x])
+	}
+	raw, err := base64.StdEncoding.DecodeString(value)
+	if err != nil {
+		return false, fmt.Errorf("%s: invalid password encoding (%w)", stored[1:x], err)
+	}
+	h := fn()
+	size := h.Size()
+	if len(raw) < size || (!salted && len(raw) != size) {
+		return false, fmt.Errorf("%s: invalid digest length %d", stored[1:x], len(raw))
+	}
+	h.Write([]byte(passwd))
+	h.Write(raw[size:])
+	return subtle.ConstantTimeCompare(h.Sum(nil), raw[:size]) == 1, nil
+}
+
+type EntryReader interface {
+	GetEntry(dn string, attrs ...string) (Entry, error)
+}
+
+type PasswordAuthenticator struct {
+	Dir  EntryReader
+	Attr string
+}
+
+func (p PasswordAuthenticator) Authenticate(dn, passwd string) error {
+	attr := p.Attr
+	if attr == "" {
+		attr = attrUserPassword
+	}
+	if dn == "" || passwd == "" {
+		return invalidCredentials(dn)
+	}
+	e, err := p.Dir.GetEntry(dn, attr)
+	if err != nil {
+		return invalidCredentials(dn)
+	}
+	for _, v := range e.GetValues(attr) {
+		ok, err := VerifyPassword(v, passwd)
+		if err == nil && ok {
+			return nil
+		}
+	}
+	return invalidCredentials(dn)
+}
+
+func invalidCredentials(dn string) error {
+	return Result{
+		Code: InvalidCredentials,
+		Name: dn,
+	}
+}
+
+func HashPassword(scheme, passwd string, cost int) (string, error) {
+	scheme = strings.ToUpper(scheme)
+	switch {
+	case scheme == "" || scheme == "CLEARTEXT" || scheme == "PLAIN":
+		return passwd, nil
+	case scheme == schemeCrypt:
+		salt, err := cryptSalt(cryptSaltSize)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("{%s}%s", scheme, sha512Crypt(passwd, salt, cost, cost > 0)), nil
+	case scheme == schemeBcrypt:
+		if cost <= 0 {
+			cost = bcrypt.DefaultCost
+		}
+		buf, err := bcrypt.GenerateFromPassword([]byte(passwd), cost)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("{%s}%s", scheme, buf), nil
+	case strings.HasPrefix(scheme, schemePBKDF2):
+		fn, err := pbkdf2Hash(scheme)
+		if err != nil {
+			return "", err
+		}
+		if cost <= 0 {
+			cost = pbkdf2Iterations
+		}
+		salt, err := randomSalt(pbkdf2SaltSize)
+		if err != nil {
+			return "", err
+		}
+		key := pbkdf2.Key([]byte(passwd), salt, cost, fn().Size(), fn)
+		return fmt.Sprintf("{%s}%d$%s$%s", scheme, cost, ab64Encode(salt), ab64Encode(key)), nil
+	}
+	var (
+		name   = scheme
+		salted = strings.HasPrefix(scheme, "S") && scheme != "SHA" && scheme != "SHA256" && scheme != "SHA512"
+	)
+	if salted {
+		name = scheme[1:]
+	}
+	fn, ok := passwordSchemes[name]
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrUnsupportedScheme, scheme)
+	}
+	var salt []byte
+	if salted {
+		var err error
+		if salt, err = randomSalt(saltSize); err != nil {
+			return "", err
+		}
+	}
+	h := fn()
+	h.Write([]byte(passwd))
+	h.Write(salt)
+	sum := append(h.Sum(nil), salt...)
+	return fmt.Sprintf("{%s}%s", scheme, base64.StdEncoding.EncodeToString(sum)), nil
+}
+
+func verifyCrypt(value, passwd string) (bool, error) {
+	switch {
+	case strings.HasPrefix(value, cryptPrefixSHA512):
+		parts := strings.Split(value[len(cryptPrefixSHA512):], "$")
+		var (
+			rounds   = cryptRounds
+			explicit bool
+		)
+		if len(parts) == 3 && strings.HasPrefix(parts[0], cryptPrefixRounds) {
+			n, err := strconv.Atoi(strings.TrimPrefix(parts[0], cryptPrefixRounds))
+			if err != nil {
+				return false, fmt.Errorf("%s: invalid rounds", parts[0])
+			}
+			rounds, parts, explicit = n, parts[1:], true
+		}
+		if len(parts) != 2 {
+			return false, fmt.Errorf("%s: malformed crypt value", schemeCrypt)
+		}
+		other := sha512Crypt(passwd, parts[0], rounds, explicit)
+		return subtle.ConstantTimeCompare([]byte(other), []byte(value)) == 1, nil
+	case strings.HasPrefix(value, cryptPrefixBcrypt):
+		return bcrypt.CompareHashAndPassword([]byte(value), []byte(passwd)) == nil, nil
+	default:
+		return false, fmt.Errorf("%w: %s", ErrUnsupportedScheme, schemeCrypt)
+	}
+}
+
+func verifyPBKDF2(scheme, value, passwd string) (bool, error) {
+	fn, err := pbkdf2Hash(scheme)
+	if err != nil {
+		return false, err
+	}
+	parts := strings.Split(value, "$")
+	if len(parts) != 3 {
+		return false, fmt.Errorf("%s: malformed value", scheme)
+	}
+	iter, err := strconv.Atoi(parts[0])
+	if err != nil || iter <= 0 {
+		return false, fmt.Errorf("%s: invalid iterations %s", scheme, parts[0])
+	}
+	salt, err := ab64Decode(parts[1])
+	if err != nil {
+		return false, fmt.Errorf("%s: invalid salt (%w)", scheme, err)
+	}
+	key, err := ab64Decode(parts[2])
+	if err != nil {
+		return false, fmt.Errorf("%s: invalid hash (%w)", scheme, err)
+	}
+	other := pbkdf2.Key([]byte(passwd), salt, iter, len(key), fn)
+	return subtle.ConstantTimeCompare(other, key) == 1, nil
+}
+
+func pbkdf2Hash(scheme string) (func() hash.Hash, error) {
+	switch scheme {
+	case schemePBKDF2, schemePBKDF2 + "-SHA1":
+		return sha1.New, nil
+	case schemePBKDF2 + "-SHA256":
+		return sha256.New, nil
+	case schemePBKDF2 + "-SHA512":
+		return sha512.New, nil
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedScheme, scheme)
+	}
+}
+
+func ab64Encode(buf []byte) string {
+	str := base64.RawStdEncoding.EncodeToString(buf)
+	return strings.ReplaceAll(str, "+", ".")
+}
+
+func ab64Decode(str string) ([]byte, error) {
+	str = strings.TrimRight(strings.ReplaceAll(str, ".", "+"), "=")
+	return base64.RawStdEncoding.DecodeString(str)
+}
+
+func randomSalt(size int) ([]byte, error) {
+	salt := make([]byte, size)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	return salt, nil
+}
+
+func cryptSalt(size int) (string, error) {
+	buf, err := randomSalt(size)
+	if err != nil {
+		return "", err
+	}
+	for i := range buf {
+		buf[i] = cryptAlphabet[int(buf[i])%len(cryptAlphabet)]
+	}
+	return string(buf), nil
+}
+
+func sha512Crypt(passwd, salt string, rounds int, explicit bool) string {
+	switch {
+	case rounds <= 0:
+		rounds = cryptRounds
+	case rounds < cryptMinRounds:
+		rounds = cryptMinRounds
+	case rounds > cryptMaxRounds:
+		rounds = cryptMaxRounds
+	}
+	if len(salt) > cryptSaltSize {
+		salt = salt[:cryptSaltSize]
+	}
+	var (
+		key = []byte(passwd)
+		slt = []byte(salt)
+	)
+	alt := sha512.New()
+	alt.Write(key)
+	alt.Write(slt)
+	alt.Write(key)
+	sumB := alt.Sum(nil)
+
+	ctx := sha512.New()
+	ctx.Write(key)
+	ctx.Write(slt)
+	i := len(key)
+	for ; i > sha512.Size; i -= sha512.Size {
+		ctx.Write(sumB)
+	}
+	ctx.Write(sumB[:i])
+	for i = len(key); i > 0; i >>= 1 {
+		if i&1 != 0 {
+			ctx.Write(sumB)
+		} else {
+			ctx.Write(key)
+		}
+	}
+	sumA := ctx.Sum(nil)
+
+	dp := sha512.New()
+	for i = 0; i < len(key); i++ {
+		dp.Write(key)
+	}
+	seqP := repeatBytes(dp.Sum(nil), len(key))
+
+	ds := sha512.New()
+	for i = 0; i < 16+int(sumA[0]); i++ {
+		ds.Write(slt)
+	}
+	seqS := repeatBytes(ds.Sum(nil), len(slt))
+
+	sum := sumA
+	for r := 0; r < rounds; r++ {
+		h := sha512.New()
+		if r&1 != 0 {
+			h.Write(seqP)
+		} else {
+			h.Write(sum)
+		}
+		if r%3 != 0 {
+			h.Write(seqS)
+		}
+		if r%7 != 0 {
+			h.Write(seqP)
+		}
+		if r&1 != 0 {
+			h.Write(sum)
+		} else {
+			h.Write(seqP)
+		}
+		sum = h.Sum(nil)
+	}
+
+	var str strings.Builder
+	str.WriteString(cryptPrefixSHA512)
+	if explicit {
+		str.WriteString(cryptPrefixRounds)
+		str.WriteString(strconv.Itoa(rounds))
+		str.WriteString("$")
+	}
+	str.WriteString(salt)
+	str.WriteString("$")
+	for _, x := range sha512CryptOrder {
+		writeCrypt64(&str, uint(sum[x[0]])<<16|uint(sum[x[1]])<<8|uint(sum[x[2]]), 4)
+	}
+	writeCrypt64(&str, uint(sum[63]), 2)
+	return str.String()
+}
+
+var sha512CryptOrder = [][3]int{
+	{0, 21, 42}, {22, 43, 1}, {44, 2, 23}, {3, 24, 45}, {25, 46, 4},
+	{47, 5, 26}, {6, 27, 48}, {28, 49, 7}, {50, 8, 29}, {9, 30, 51},
+	{31, 52, 10}, {53, 11, 32}, {12, 33, 54}, {34, 55, 13}, {56, 14, 35},
+	{15, 36, 57}, {37, 58, 16}, {59, 17, 38}, {18, 39, 60}, {40, 61, 19},
+	{62, 20, 41},
+}
+
+func repeatBytes(src []byte, size int) []byte {
+	buf := make([]byte, 0, size)
+	for len(buf) < size {
+		n := size - len(buf)
+		if n > len(src) {
+			n = len(src)
+		}
+		buf = append(buf, src[:n]...)
+	}
+	return buf
+}
+
+func writeCrypt64(str *strings.Builder, w uint, n int) {
+	for ; n > 0; n-- {
+		str.WriteByte(cryptAlphabet[w&0x3f])
+		w >>= 6
+	}
+}
@@ -0,0 +1,146 @@
+package ldap
+
+import (
+	"encoding/hex"
+	"fmt"
+	"testing"
+)
+
+var sha512CryptVectors = []struct {
+	Passwd string
+	Want   string
+}{
+	{
+		Passwd: "Hello world!",
+		Want:   "$6$saltstring$svn8UoSVapNtMuq1ukKS4tPQd8iKwSMHWjl/O817G3uBnIFNjnQJuesI68u4OTLiBFdcbYEdFCoEOfaS35inz1",
+	},
+	{
+		Passwd: "Hello world!",
+		Want:   "$6$rounds=10000$saltstringsaltst$OW1/O6BYHV6BcXZu8QVeXbDWra3Oeqh0sbHbbMCVNSnCM/UrjmM0Dp8vOuZeHBy/YTBmSK6H9qs/y3RnOaw5v.",
+	},
+	{
+		Passwd: "This is just a test",
+		Want:   "$6$rounds=5000$toolongsaltstrin$lQ8jolhgVRVhY4b5pZKaysCLi0QBxGoNeKQzQ3glMhwllF7oGDZxUhx1yxdYcz/e1JSbq3y6JMxxl8audkUEm0",
+	},
+	{
+		Passwd: "a very much longer text to encrypt.  This one even stretches over morethan one line.",
+		Want:   "$6$rounds=1400$anotherlongsalts$POfYwTEok97VWcjxIiSOjiykti.o/pQs.wPvMxQ6Fm7I6IoYN3CmLs66x9t0oSwbtEW7o7UmJEiDwGqd8p4ur1",
+	},
+	{
+		Passwd: "we have a short salt string but not a short password",
+		Want:   "$6$rounds=77777$short$WuQyW2YR.hBNpjjRhpYD/ifIw05xdfeEyQoMxIXbkvr0gge1a1x3yRULJ5CCaUeOxFmtlcGZelFl5CxtgfiAc0",
+	},
+	{
+		Passwd: "a short string",
+		Want:   "$6$rounds=123456$asaltof16chars..$BtCwjqMJGx5hrJhZywWvt0RLE8uZ4oPwcelCjmw2kSYu.Ec6ycULevoBK25fs2xXgMNrCzIMVcgEJAstJeonj1",
+	},
+	{
+		Passwd: "the minimum number is still observed",
+		Want:   "$6$rounds=1000$roundstoolow$kUMsbe306n21p9R.FRkW3IGn.S9NPN0x50YhH1xhLsPuWGsUSklZt58jaTfF4ZEQpyUNGc0dqbpBYYBaHHrsX.",
+	},
+}
+
+func TestSHA512Crypt(t *testing.T) {
+	data := []struct {
+		Salt     string
+		Rounds   int
+		Explicit bool
+	}{
+		{Salt: "saltstring"},
+		{Salt: "saltstringsaltstring", Rounds: 10000, Explicit: true},
+		{Salt: "toolongsaltstring", Rounds: 5000, Explicit: true},
+		{Salt: "anotherlongsaltstring", Rounds: 1400, Explicit: true},
+		{Salt: "short", Rounds: 77777, Explicit: true},
+		{Salt: "asaltof16chars..", Rounds: 123456, Explicit: true},
+		{Salt: "roundstoolow", Rounds: 10, Explicit: true},
+	}
+	for i, d := range data {
+		v := sha512CryptVectors[i]
+		got := sha512Crypt(v.Passwd, d.Salt, d.Rounds, d.Explicit)
+		if got != v.Want {
+			t.Errorf("%s: crypt mismatch: want %s, got %s", d.Salt, v.Want, got)
+		}
+	}
+}
+
+func TestVerifyPasswordCrypt(t *testing.T) {
+	for _, v := range sha512CryptVectors {
+		ok, err := VerifyPassword("{CRYPT}"+v.Want, v.Passwd)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %s", v.Want, err)
+			continue
+		}
+		if !ok {
+			t.Errorf("%s: password %q not verified", v.Want, v.Passwd)
+		}
+		ok, _ = VerifyPassword("{CRYPT}"+v.Want, v.Passwd+"!")
+		if ok {
+			t.Errorf("%s: wrong password verified", v.Want)
+		}
+	}
+}
+
+func TestHashPassword(t *testing.T) {
+	schemes := []string{
+		"plain",
+		"md5",
+		"smd5",
+		"sha",
+		"ssha",
+		"sha256",
+		"ssha256",
+		"sha512",
+		"ssha512",
+		"crypt",
+		"pbkdf2",
+		"pbkdf2-sha256",
+		"pbkdf2-sha512",
+	}
+	for _, s := range schemes {
+		stored, err := HashPassword(s, "secret", 0)
+		if err != nil {
+			t.Errorf("%s: unexpected error hashing password: %s", s, err)
+			continue
+		}
+		ok, err := VerifyPassword(stored, "secret")
+		if err != nil || !ok {
+			t.Errorf("%s: %s not verified (%v)", s, stored, err)
+		}
+	}
+	stored, err := HashPassword("crypt", "secret", 5000)
+	if err != nil {
+		t.Fatalf("crypt: unexpected error: %s", err)
+	}
+	if ok, _ := VerifyPassword(stored, "secret"); !ok {
+		t.Errorf("crypt: %s not verified", stored)
+	}
+}
+
+func TestVerifyPasswordPBKDF2(t *testing.T) {
+	data := []struct {
+		Passwd string
+		Salt   string
+		Iter   int
+		Key    string
+	}{
+		{Passwd: "password", Salt: "salt", Iter: 1, Key: "0c60c80f961f0e71f3a9b524af6012062fe037a6"},
+		{Passwd: "password", Salt: "salt", Iter: 2, Key: "ea6c014dc72d6f8ccd1ed92ace1d41f0d8de8957"},
+		{Passwd: "password", Salt: "salt", Iter: 4096, Key: "4b007901b765489abead49d926f721d065a429c1"},
+		{Passwd: "passwordPASSWORDpassword", Salt: "saltSALTsaltSALTsaltSALTsaltSALTsalt", Iter: 4096, Key: "3d2eec4fe41c849b80c8d83662c0e44a8b291a964cf2f07038"},
+	}
+	for _, d := range data {
+		key, err := hex.DecodeString(d.Key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		stored := fmt.Sprintf("{PBKDF2}%d$%s$%s", d.Iter, ab64Encode([]byte(d.Salt)), ab64Encode(key))
+		ok, err := VerifyPassword(stored, d.Passwd)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %s", stored, err)
+			continue
+		}
+		if !ok {
+			t.Errorf("%s: password %q not verified", stored, d.Passwd)
+		}
+	}
+}
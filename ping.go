@@ -0,0 +1,71 @@
+package ldap
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"time"
+)
+
+const defaultPingTimeout = 5 * time.Second
+
+type PingResult struct {
+	Addr     string        `json:"addr"`
+	Connect  time.Duration `json:"connect,omitempty"`
+	Latency  time.Duration `json:"latency"`
+	StartTLS bool          `json:"starttls"`
+	TLS      bool          `json:"tls"`
+}
+
+func Ping(addr string, timeout time.Duration) (PingResult, error) {
+	if timeout <= 0 {
+		timeout = defaultPingTimeout
+	}
+	var (
+		res = PingResult{Addr: addr}
+		now = time.Now()
+	)
+	conn, err := net.DialTimeout("tcp", addr, timeout)
+	if err != nil {
+		return res, &NetError{Op: "dial", Addr: addr, Err: err}
+	}
+	res.Connect = time.Since(now)
+
+	c := NewClient(conn)
+	c.addr = addr
+	defer c.Unbind()
+
+	probe, err := c.Ping(timeout - res.Connect)
+	probe.Addr, probe.Connect = res.Addr, res.Connect
+	return probe, err
+}
+
+func (c *Client) Ping(timeout time.Duration) (PingResult, error) {
+	if timeout <= 0 {
+		timeout = defaultPingTimeout
+	}
+	res := PingResult{Addr: c.addr}
+	_, res.TLS = c.conn.(*tls.Conn)
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	var (
+		now   = time.Now()
+		attrs = []string{attrSupportedExt}
+	)
+	es, _, err := c.Search("", WithScope(ScopeBase), WithAttributes(attrs), WithContext(ctx))
+	res.Latency = time.Since(now)
+	if err != nil {
+		return res, err
+	}
+	if len(es) > 0 {
+		for _, oid := range es[0].GetValues(attrSupportedExt) {
+			if oid == oidStartTLS {
+				res.StartTLS = true
+				break
+			}
+		}
+	}
+	return res, nil
+}
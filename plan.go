@@ -0,0 +1,45 @@
+package ldap
+
+import (
+	"errors"
+	"sort"
+)
+
+func Plan(dir EntryReader, declared []Entry, fn func(ChangeType, Change) error) (int, error) {
+	es := append([]Entry{}, declared...)
+	sort.SliceStable(es, func(i, j int) bool {
+		return depthOf(es[i].Name) < depthOf(es[j].Name)
+	})
+	var count int
+	for _, e := range es {
+		var names []string
+		for _, a := range e.Attrs {
+			names = append(names, a.Name)
+		}
+		live, err := dir.GetEntry(e.Name, names...)
+		if err != nil {
+			var res Result
+			if !errors.As(err, &res) || res.Code != NoSuchObject {
+				return count, err
+			}
+			c := Change{Name: e.Name}
+			for _, a := range e.Attrs {
+				c.Attrs = append(c.Attrs, createModification(ModAdd, a.Name, a.Values))
+			}
+			count++
+			if err := fn(ModAdd, c); err != nil {
+				return count, err
+			}
+			continue
+		}
+		mods := Diff(live, e)
+		if len(mods) == 0 {
+			continue
+		}
+		count++
+		if err := fn(ModReplace, Change{Name: e.Name, Attrs: mods}); err != nil {
+			return count, err
+		}
+	}
+	return count, nil
+}
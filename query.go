@@ -0,0 +1,77 @@
+package ldap
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+type Query struct {
+	Base   string
+	Scope  Scope
+	Filter Filter
+	Attrs  []string
+}
+
+func ParseQuery(str string) (Query, error) {
+	q := Query{
+		Scope:  ScopeBase,
+		Filter: Present(attrObjectClass),
+	}
+	if x := strings.Index(str, "://"); x >= 0 {
+		str = str[x+3:]
+		if x = strings.IndexByte(str, '/'); x < 0 {
+			return q, nil
+		}
+		str = str[x+1:]
+	}
+	parts := strings.SplitN(str, "?", 5)
+	for i := range parts {
+		p, err := url.PathUnescape(parts[i])
+		if err != nil {
+			return q, fmt.Errorf("%s: invalid query (%w)", str, err)
+		}
+		parts[i] = p
+	}
+	if len(parts) > 4 && parts[4] != "" {
+		return q, fmt.Errorf("%s: extensions not supported", str)
+	}
+	for len(parts) < 4 {
+		parts = append(parts, "")
+	}
+	q.Base = parts[0]
+	for _, a := range strings.Split(parts[1], ",") {
+		if a = strings.TrimSpace(a); a != "" {
+			q.Attrs = append(q.Attrs, a)
+		}
+	}
+	if parts[2] != "" {
+		if err := q.Scope.UnmarshalText([]byte(parts[2])); err != nil {
+			return q, err
+		}
+	}
+	if parts[3] != "" {
+		f, err := ParseFilter(parts[3])
+		if err != nil {
+			return q, err
+		}
+		q.Filter = f
+	}
+	return q, nil
+}
+
+func (q Query) Options() []SearchOption {
+	return []SearchOption{
+		WithScope(q.Scope),
+		WithFilter(q.Filter),
+		WithAttributes(q.Attrs),
+	}
+}
+
+func (c *Client) Query(str string, options ...SearchOption) ([]Entry, []ControlValue, error) {
+	q, err := ParseQuery(str)
+	if err != nil {
+		return nil, nil, err
+	}
+	return c.Search(q.Base, append(q.Options(), options...)...)
+}
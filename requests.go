@@ -0,0 +1,238 @@
+package ldap
+
+import (
+	"fmt"
+)
+
+const (
+	berClassApplication = 0x40
+	berClassContext     = 0x80
+	berConstructed      = 0x20
+)
+
+const (
+	berBoolean     = 0x01
+	berInteger     = 0x02
+	berOctetString = 0x04
+	berEnumerated  = 0x0a
+	berSequence    = 0x10
+	berSet         = 0x11
+)
+
+type request interface {
+	operation() uint64
+	encode() []byte
+}
+
+type bindRequest struct {
+	Version  int
+	Name     string
+	Password string
+}
+
+func (r bindRequest) operation() uint64 {
+	return ldapBindRequest
+}
+
+func (r bindRequest) encode() []byte {
+	var body []byte
+	body = append(body, encodeInteger(berInteger, int64(r.Version))...)
+	body = append(body, encodeString(berOctetString, r.Name)...)
+	body = append(body, encodeString(berClassContext|0, r.Password)...)
+	return encodeElement(applicationTag(r.operation()), body)
+}
+
+type modifyRequest struct {
+	Name    string
+	Changes []PartialAttribute
+}
+
+func (r modifyRequest) operation() uint64 {
+	return ldapModifyRequest
+}
+
+func (r modifyRequest) encode() []byte {
+	var changes []byte
+	for _, c := range r.Changes {
+		var change []byte
+		change = append(change, encodeInteger(berEnumerated, int64(c.Mod))...)
+		change = append(change, encodeAttribute(c.Attribute)...)
+		changes = append(changes, encodeElement(berConstructed|berSequence, change)...)
+	}
+	var body []byte
+	body = append(body, encodeString(berOctetString, r.Name)...)
+	body = append(body, encodeElement(berConstructed|berSequence, changes)...)
+	return encodeElement(applicationTag(r.operation()), body)
+}
+
+type modDNRequest struct {
+	Name      string
+	RDN       string
+	DeleteOld bool
+	Superior  string
+}
+
+func (r modDNRequest) operation() uint64 {
+	return ldapModDNRequest
+}
+
+func (r modDNRequest) encode() []byte {
+	var body []byte
+	body = append(body, encodeString(berOctetString, r.Name)...)
+	body = append(body, encodeString(berOctetString, r.RDN)...)
+	body = append(body, encodeBool(berBoolean, r.DeleteOld)...)
+	if r.Superior != "" {
+		body = append(body, encodeString(berClassContext|0, r.Superior)...)
+	}
+	return encodeElement(applicationTag(r.operation()), body)
+}
+
+type extendedRequest struct {
+	OID   string
+	Value []byte
+}
+
+func createExtendedRequest(oid string, value []byte) extendedRequest {
+	return extendedRequest{
+		OID:   oid,
+		Value: value,
+	}
+}
+
+func (r extendedRequest) operation() uint64 {
+	return ldapExtendedRequest
+}
+
+func (r extendedRequest) encode() []byte {
+	var body []byte
+	body = append(body, encodeString(berClassContext|0, r.OID)...)
+	if r.Value != nil {
+		body = append(body, encodeElement(berClassContext|1, r.Value)...)
+	}
+	return encodeElement(applicationTag(r.operation()), body)
+}
+
+type unbindRequest struct{}
+
+func (r unbindRequest) operation() uint64 {
+	return ldapUnbindRequest
+}
+
+func (r unbindRequest) encode() []byte {
+	return encodeElement(berClassApplication|byte(r.operation()), nil)
+}
+
+type abandonRequest struct {
+	ID int
+}
+
+func (r abandonRequest) operation() uint64 {
+	return ldapAbandonRequest
+}
+
+func (r abandonRequest) encode() []byte {
+	return encodeInteger(berClassApplication|byte(r.operation()), int64(r.ID))
+}
+
+func applicationTag(op uint64) byte {
+	return berClassApplication | berConstructed | byte(op)
+}
+
+func encodeMessage(msgid uint32, req request, controls []Control) []byte {
+	var body []byte
+	body = append(body, encodeInteger(berInteger, int64(msgid))...)
+	body = append(body, req.encode()...)
+	if len(controls) > 0 {
+		var cs []byte
+		for _, c := range controls {
+			cs = append(cs, encodeControl(c)...)
+		}
+		body = append(body, encodeElement(berClassContext|berConstructed|0, cs)...)
+	}
+	return encodeElement(berConstructed|berSequence, body)
+}
+
+func encodeControl(c Control) []byte {
+	var body []byte
+	body = append(body, encodeString(berOctetString, c.OID)...)
+	if c.Critical {
+		body = append(body, encodeBool(berBoolean, c.Critical)...)
+	}
+	if len(c.Value) > 0 {
+		body = append(body, encodeElement(berOctetString, c.Value)...)
+	}
+	return encodeElement(berConstructed|berSequence, body)
+}
+
+func encodeAttribute(a Attribute) []byte {
+	var values []byte
+	for _, v := range a.Values {
+		values = append(values, encodeString(berOctetString, v)...)
+	}
+	var body []byte
+	body = append(body, encodeString(berOctetString, a.Name)...)
+	body = append(body, encodeElement(berConstructed|berSet, values)...)
+	return encodeElement(berConstructed|berSequence, body)
+}
+
+func encodeString(tag byte, str string) []byte {
+	return encodeElement(tag, []byte(str))
+}
+
+func encodeBool(tag byte, b bool) []byte {
+	if b {
+		return encodeElement(tag, []byte{0xff})
+	}
+	return encodeElement(tag, []byte{0x00})
+}
+
+func encodeInteger(tag byte, n int64) []byte {
+	var body []byte
+	for {
+		body = append([]byte{byte(n)}, body...)
+		n >>= 8
+		if (n == 0 && body[0]&0x80 == 0) || (n == -1 && body[0]&0x80 != 0) {
+			break
+		}
+	}
+	return encodeElement(tag, body)
+}
+
+func encodeElement(tag byte, body []byte) []byte {
+	buf := []byte{tag}
+	buf = append(buf, encodeLength(len(body))...)
+	return append(buf, body...)
+}
+
+func encodeLength(n int) []byte {
+	if n < 0x80 {
+		return []byte{byte(n)}
+	}
+	var size []byte
+	for ; n > 0; n >>= 8 {
+		size = append([]byte{byte(n)}, size...)
+	}
+	return append([]byte{0x80 | byte(len(size))}, size...)
+}
+
+func decodeElement(b []byte) (byte, []byte, []byte, error) {
+	if len(b) < 2 {
+		return 0, nil, nil, fmt.Errorf("ber: element too short")
+	}
+	tag, size, body := b[0], int(b[1]), b[2:]
+	if size&0x80 != 0 {
+		n := size & 0x7f
+		if n == 0 || n > 4 || n > len(body) {
+			return 0, nil, nil, fmt.Errorf("ber: invalid length")
+		}
+		size = 0
+		for _, x := range body[:n] {
+			size = size<<8 | int(x)
+		}
+		body = body[n:]
+	}
+	if size > len(body) {
+		return 0, nil, nil, fmt.Errorf("ber: element truncated (want %d, got %d)", size, len(body))
+	}
+	return tag, body[:size], body[size:], nil
+}
@@ -0,0 +1,160 @@
+package ldap
+
+import (
+	"bytes"
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+const (
+	fixtureAdmin = "cn=admin,dc=example,dc=com"
+	fixtureUser  = "cn=john,ou=people,dc=example,dc=com"
+)
+
+func TestEncodeRequests(t *testing.T) {
+	data := []struct {
+		Name     string
+		Req      request
+		Controls []Control
+		Want     string
+	}{
+		{
+			Name: "bind/simple",
+			Req:  bindRequest{Version: 3, Name: fixtureAdmin, Password: "secret"},
+			Want: "30 2c 02 01 01 60 27 02 01 03 04 1a 63 6e 3d 61 64 6d 69 6e 2c 64 63 3d 65 78 61 6d 70 6c 65 2c 64 63 3d 63 6f 6d 80 06 73 65 63 72 65 74",
+		},
+		{
+			Name: "modify/replace",
+			Req: modifyRequest{
+				Name: fixtureUser,
+				Changes: []PartialAttribute{
+					{
+						Mod:       ModReplace,
+						Attribute: Attribute{Name: "mail", Values: []string{"john@example.com"}},
+					},
+				},
+			},
+			Want: "30 4d 02 01 01 66 48 04 23 63 6e 3d 6a 6f 68 6e 2c 6f 75 3d 70 65 6f 70 6c 65 2c 64 63 3d 65 78 61 6d 70 6c 65 2c 64 63 3d 63 6f 6d 30 21 30 1f 0a 01 02 30 1a 04 04 6d 61 69 6c 31 12 04 10 6a 6f 68 6e 40 65 78 61 6d 70 6c 65 2e 63 6f 6d",
+		},
+		{
+			Name: "moddn/rename",
+			Req:  modDNRequest{Name: fixtureUser, RDN: "cn=jane", DeleteOld: true},
+			Want: "30 36 02 01 01 6c 31 04 23 63 6e 3d 6a 6f 68 6e 2c 6f 75 3d 70 65 6f 70 6c 65 2c 64 63 3d 65 78 61 6d 70 6c 65 2c 64 63 3d 63 6f 6d 04 07 63 6e 3d 6a 61 6e 65 01 01 ff",
+		},
+		{
+			Name: "moddn/move",
+			Req:  modDNRequest{Name: fixtureUser, RDN: "cn=john", Superior: "ou=staff,dc=example,dc=com"},
+			Want: "30 52 02 01 01 6c 4d 04 23 63 6e 3d 6a 6f 68 6e 2c 6f 75 3d 70 65 6f 70 6c 65 2c 64 63 3d 65 78 61 6d 70 6c 65 2c 64 63 3d 63 6f 6d 04 07 63 6e 3d 6a 6f 68 6e 01 01 00 80 1a 6f 75 3d 73 74 61 66 66 2c 64 63 3d 65 78 61 6d 70 6c 65 2c 64 63 3d 63 6f 6d",
+		},
+		{
+			Name: "extended/whoami",
+			Req:  createExtendedRequest("1.3.6.1.4.1.4203.1.11.3", nil),
+			Want: "30 1e 02 01 01 77 19 80 17 31 2e 33 2e 36 2e 31 2e 34 2e 31 2e 34 32 30 33 2e 31 2e 31 31 2e 33",
+		},
+		{
+			Name: "extended/value",
+			Req:  createExtendedRequest("1.3.6.1.4.1.4203.1.11.1", []byte{0x30, 0x00}),
+			Want: "30 22 02 01 01 77 1d 80 17 31 2e 33 2e 36 2e 31 2e 34 2e 31 2e 34 32 30 33 2e 31 2e 31 31 2e 31 81 02 30 00",
+		},
+		{
+			Name:     "extended/controls",
+			Req:      createExtendedRequest("1.3.6.1.4.1.4203.1.11.3", nil),
+			Controls: []Control{{OID: "2.16.840.1.113730.3.4.2"}},
+			Want:     "30 3b 02 01 01 77 19 80 17 31 2e 33 2e 36 2e 31 2e 34 2e 31 2e 34 32 30 33 2e 31 2e 31 31 2e 33 a0 1b 30 19 04 17 32 2e 31 36 2e 38 34 30 2e 31 2e 31 31 33 37 33 30 2e 33 2e 34 2e 32",
+		},
+	}
+	for _, d := range data {
+		want := decodeFixture(t, d.Want)
+		got := encodeMessage(1, d.Req, d.Controls)
+		if !bytes.Equal(got, want) {
+			t.Errorf("%s: bytes mismatch\nwant: % x\n got: % x", d.Name, want, got)
+		}
+	}
+}
+
+func TestEncodeControl(t *testing.T) {
+	data := []struct {
+		Control Control
+		Want    string
+	}{
+		{
+			Control: Control{OID: "2.16.840.1.113730.3.4.2"},
+			Want:    "30 19 04 17 32 2e 31 36 2e 38 34 30 2e 31 2e 31 31 33 37 33 30 2e 33 2e 34 2e 32",
+		},
+		{
+			Control: Control{
+				OID:      "1.2.840.113556.1.4.319",
+				Critical: true,
+				Value:    []byte{0x30, 0x05, 0x02, 0x01, 0x05, 0x04, 0x00},
+			},
+			Want: "30 24 04 16 31 2e 32 2e 38 34 30 2e 31 31 33 35 35 36 2e 31 2e 34 2e 33 31 39 01 01 ff 04 07 30 05 02 01 05 04 00",
+		},
+	}
+	for _, d := range data {
+		want := decodeFixture(t, d.Want)
+		got := encodeControl(d.Control)
+		if !bytes.Equal(got, want) {
+			t.Errorf("%s: bytes mismatch\nwant: % x\n got: % x", d.Control.OID, want, got)
+		}
+	}
+}
+
+func TestEncodeLength(t *testing.T) {
+	data := []struct {
+		Len  int
+		Want string
+	}{
+		{Len: 0, Want: "00"},
+		{Len: 1, Want: "01"},
+		{Len: 127, Want: "7f"},
+		{Len: 128, Want: "81 80"},
+		{Len: 255, Want: "81 ff"},
+		{Len: 256, Want: "82 01 00"},
+		{Len: 65535, Want: "82 ff ff"},
+		{Len: 65536, Want: "83 01 00 00"},
+	}
+	for _, d := range data {
+		want := decodeFixture(t, d.Want)
+		got := encodeLength(d.Len)
+		if !bytes.Equal(got, want) {
+			t.Errorf("%d: bytes mismatch: want % x, got % x", d.Len, want, got)
+		}
+	}
+	got := encodeString(berOctetString, strings.Repeat("x", 200))
+	if want := decodeFixture(t, "04 81 c8"); !bytes.HasPrefix(got, want) || len(got) != 203 {
+		t.Errorf("long string: unexpected header % x (%d bytes)", got[:3], len(got))
+	}
+}
+
+func TestEncodeInteger(t *testing.T) {
+	data := []struct {
+		Value int64
+		Want  string
+	}{
+		{Value: 0, Want: "02 01 00"},
+		{Value: 1, Want: "02 01 01"},
+		{Value: 127, Want: "02 01 7f"},
+		{Value: 128, Want: "02 02 00 80"},
+		{Value: 256, Want: "02 02 01 00"},
+		{Value: -1, Want: "02 01 ff"},
+		{Value: -128, Want: "02 01 80"},
+		{Value: -129, Want: "02 02 ff 7f"},
+	}
+	for _, d := range data {
+		want := decodeFixture(t, d.Want)
+		got := encodeInteger(berInteger, d.Value)
+		if !bytes.Equal(got, want) {
+			t.Errorf("%d: bytes mismatch: want % x, got % x", d.Value, want, got)
+		}
+	}
+}
+
+func decodeFixture(t *testing.T, str string) []byte {
+	t.Helper()
+	b, err := hex.DecodeString(strings.ReplaceAll(str, " ", ""))
+	if err != nil {
+		t.Fatalf("invalid fixture %q: %s", str, err)
+	}
+	return b
+}
@@ -0,0 +1,155 @@
+package ldap
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"time"
+)
+
+const generalizedTime = "20060102150405Z"
+
+const createTimestamp = "createTimestamp"
+
+type Retention struct {
+	Filter  Filter
+	Scope   Scope
+	Attr    string
+	Age     time.Duration
+	Disable []PartialAttribute
+
+	DryRun     bool
+	PauseEvery int
+	Pause      time.Duration
+	Rate       int
+	Undo       io.Writer
+}
+
+func (r Retention) filter() Filter {
+	attr := r.Attr
+	if attr == "" {
+		attr = createTimestamp
+	}
+	when := FormatGeneralizedTime(time.Now().Add(-r.Age))
+	if r.Filter == nil {
+		return LessEq(attr, when)
+	}
+	return And(LessEq(attr, when), r.Filter)
+}
+
+func (c *Client) Expire(base string, rt Retention) ([]string, error) {
+	options := []SearchOption{WithScope(rt.Scope), WithFilter(rt.filter())}
+	if len(rt.Disable) > 0 {
+		attrs := []string{"*"}
+		for _, d := range rt.Disable {
+			attrs = append(attrs, d.Name)
+		}
+		options = append(options, WithAttributes(attrs))
+	}
+	var es []Entry
+	_, err := c.Stream(base, func(e Entry) error {
+		es = append(es, e)
+		return nil
+	}, options...)
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(es, func(i, j int) bool {
+		return depthOf(es[i].Name) > depthOf(es[j].Name)
+	})
+
+	var (
+		done  []string
+		undo  []func(*LDIFWriter) error
+		limit <-chan time.Time
+	)
+	if rt.Rate > 0 {
+		tick := time.NewTicker(time.Second / time.Duration(rt.Rate))
+		defer tick.Stop()
+		limit = tick.C
+	}
+	defer func() {
+		if rt.Undo == nil || rt.DryRun {
+			return
+		}
+		lw := NewLDIFWriter(rt.Undo)
+		for i := len(undo) - 1; i >= 0; i-- {
+			undo[i](lw)
+		}
+	}()
+	for i, e := range es {
+		if i > 0 && rt.PauseEvery > 0 && i%rt.PauseEvery == 0 && rt.Pause > 0 {
+			time.Sleep(rt.Pause)
+		}
+		if limit != nil {
+			<-limit
+		}
+		if !rt.DryRun {
+			if len(rt.Disable) > 0 {
+				_, err = c.Modify(e.Name, rt.Disable)
+			} else {
+				_, err = c.Delete(e.Name)
+			}
+			if err != nil {
+				return done, fmt.Errorf("%s: %w", e.Name, err)
+			}
+		}
+		done = append(done, e.Name)
+		undo = append(undo, rt.revert(e))
+	}
+	return done, nil
+}
+
+func (r Retention) revert(e Entry) func(*LDIFWriter) error {
+	if len(r.Disable) == 0 {
+		c := Change{Name: e.Name}
+		for _, a := range e.Attrs {
+			c.Attrs = append(c.Attrs, PartialAttribute{Mod: ModAdd, Attribute: a})
+		}
+		return func(w *LDIFWriter) error {
+			return w.WriteChange(ModAdd, c)
+		}
+	}
+	var attrs []PartialAttribute
+	for _, d := range r.Disable {
+		pa := PartialAttribute{
+			Mod: ModReplace,
+		}
+		pa.Name = d.Name
+		for _, a := range e.Attrs {
+			if strings.EqualFold(a.Name, d.Name) {
+				pa.Values = append(pa.Values, a.Values...)
+			}
+		}
+		if len(pa.Values) == 0 {
+			if !d.sets() {
+				continue
+			}
+			pa.Mod = ModDelete
+		}
+		attrs = append(attrs, pa)
+	}
+	return func(w *LDIFWriter) error {
+		if len(attrs) == 0 {
+			return nil
+		}
+		return w.WriteChange(ModReplace, Change{Name: e.Name, Attrs: attrs})
+	}
+}
+
+func (p PartialAttribute) sets() bool {
+	switch p.Mod {
+	case ModAdd, ModReplace, ModIncrement:
+		return len(p.Values) > 0
+	default:
+		return false
+	}
+}
+
+func depthOf(dn string) int {
+	if d, err := Explode(dn); err == nil {
+		return d.Len()
+	}
+	return strings.Count(dn, string(comma)) + 1
+}
@@ -0,0 +1,66 @@
+package ldap
+
+import (
+	"bytes"
+	"testing"
+)
+
+func TestRetentionRevert(t *testing.T) {
+	e := Entry{
+		Name: "uid=john,ou=people,dc=example,dc=com",
+		Attrs: []Attribute{
+			{Name: "uid", Values: []string{"john"}},
+			{Name: "loginShell", Values: []string{"/bin/bash"}},
+		},
+	}
+	data := []struct {
+		Name    string
+		Disable []PartialAttribute
+		Want    string
+	}{
+		{
+			Name: "replace/present",
+			Disable: []PartialAttribute{
+				{Mod: ModReplace, Attribute: Attribute{Name: "loginShell", Values: []string{"/sbin/nologin"}}},
+			},
+			Want: "dn: uid=john,ou=people,dc=example,dc=com\nchangetype: modify\nreplace: loginShell\nloginShell: /bin/bash\n-\n\n",
+		},
+		{
+			Name: "add/absent",
+			Disable: []PartialAttribute{
+				{Mod: ModAdd, Attribute: Attribute{Name: "pwdAccountLockedTime", Values: []string{"000001010000Z"}}},
+			},
+			Want: "dn: uid=john,ou=people,dc=example,dc=com\nchangetype: modify\ndelete: pwdAccountLockedTime\n-\n\n",
+		},
+		{
+			Name: "delete/absent",
+			Disable: []PartialAttribute{
+				{Mod: ModDelete, Attribute: Attribute{Name: "mail"}},
+			},
+			Want: "",
+		},
+		{
+			Name: "replace/absent/empty",
+			Disable: []PartialAttribute{
+				{Mod: ModReplace, Attribute: Attribute{Name: "mail"}},
+				{Mod: ModReplace, Attribute: Attribute{Name: "loginShell", Values: []string{"/sbin/nologin"}}},
+			},
+			Want: "dn: uid=john,ou=people,dc=example,dc=com\nchangetype: modify\nreplace: loginShell\nloginShell: /bin/bash\n-\n\n",
+		},
+	}
+	for _, d := range data {
+		var (
+			buf bytes.Buffer
+			lw  = NewLDIFWriter(&buf)
+			rt  = Retention{Disable: d.Disable}
+		)
+		lw.version = true
+		if err := rt.revert(e)(lw); err != nil {
+			t.Errorf("%s: unexpected error: %s", d.Name, err)
+			continue
+		}
+		if got := buf.String(); got != d.Want {
+			t.Errorf("%s: undo mismatch\nwant: %q\n got: %q", d.Name, d.Want, got)
+		}
+	}
+}
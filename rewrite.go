@@ -0,0 +1,75 @@
+package ldap
+
+import (
+	"fmt"
+	"io"
+	"time"
+)
+
+type Rewrite struct {
+	Attrs []string
+
+	DryRun bool
+	Batch  int
+	Pause  time.Duration
+	Undo   io.Writer
+}
+
+func (c *Client) RewriteReferences(base, oldBase, newBase string, rw Rewrite) ([]string, error) {
+	from, err := Explode(oldBase)
+	if err != nil {
+		return nil, err
+	}
+	to, err := Explode(newBase)
+	if err != nil {
+		return nil, err
+	}
+	steps, err := c.references(base, from, to, rw.Attrs)
+	if err != nil {
+		return nil, err
+	}
+	var (
+		done []string
+		undo []MoveStep
+	)
+	defer func() {
+		if rw.Undo == nil {
+			return
+		}
+		lw := NewLDIFWriter(rw.Undo)
+		for i := len(undo) - 1; i >= 0; i-- {
+			writeStep(lw, undo[i])
+		}
+	}()
+	for i, s := range steps {
+		if i > 0 && rw.Batch > 0 && i%rw.Batch == 0 && rw.Pause > 0 {
+			time.Sleep(rw.Pause)
+		}
+		if !rw.DryRun {
+			if _, err := c.Modify(s.DN, s.Changes); err != nil {
+				return done, fmt.Errorf("%s: %w", s.DN, err)
+			}
+		}
+		done = append(done, s.DN)
+		undo = append(undo, invertStep(s))
+	}
+	return done, nil
+}
+
+func invertStep(s MoveStep) MoveStep {
+	inv := MoveStep{
+		Op: s.Op,
+		DN: s.DN,
+	}
+	for i := len(s.Changes) - 1; i >= 0; i-- {
+		c := s.Changes[i]
+		switch c.Mod {
+		case ModAdd:
+			c.Mod = ModDelete
+		case ModDelete:
+			c.Mod = ModAdd
+		}
+		inv.Changes = append(inv.Changes, c)
+	}
+	return inv
+}
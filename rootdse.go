@@ -0,0 +1,214 @@
+package ldap
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+const (
+	attrNamingContexts    = "namingContexts"
+	attrSupportedVersion  = "supportedLDAPVersion"
+	attrSupportedSASL     = "supportedSASLMechanisms"
+	attrSupportedControl  = "supportedControl"
+	attrSupportedExt      = "supportedExtension"
+	attrSupportedFeatures = "supportedFeatures"
+	attrVendorName        = "vendorName"
+	attrVendorVersion     = "vendorVersion"
+	attrSubschema         = "subschemaSubentry"
+	attrCapabilities      = "supportedCapabilities"
+	attrDCFunctionality   = "domainControllerFunctionality"
+	attrConfigContext     = "configContext"
+	attrDefaultContext    = "defaultNamingContext"
+)
+
+var ErrNoDefaultBase = errors.New("no default base")
+
+type RootDSE struct {
+	NamingContexts []string
+	Versions       []int
+	SASLMechanisms []string
+	Controls       []string
+	Extensions     []string
+	Features       []string
+	VendorName     string
+	VendorVersion  string
+	Subschema      string
+	DefaultContext string
+
+	Entry Entry
+}
+
+func (c *Client) RootDSE() (RootDSE, error) {
+	var (
+		dse   RootDSE
+		attrs = []string{
+			attrNamingContexts,
+			attrSupportedVersion,
+			attrSupportedSASL,
+			attrSupportedControl,
+			attrSupportedExt,
+			attrSupportedFeatures,
+			attrVendorName,
+			attrVendorVersion,
+			attrSubschema,
+			attrCapabilities,
+			attrDCFunctionality,
+			attrConfigContext,
+			attrDefaultContext,
+			attrObjectClass,
+		}
+	)
+	es, _, err := c.Search("", WithScope(ScopeBase), WithAttributes(attrs), WithLimit(1))
+	if err != nil {
+		return dse, err
+	}
+	if len(es) == 0 {
+		return dse, nil
+	}
+	e := es[0]
+	dse.Entry = e
+	dse.NamingContexts = e.GetValues(attrNamingContexts)
+	dse.SASLMechanisms = e.GetValues(attrSupportedSASL)
+	dse.Controls = e.GetValues(attrSupportedControl)
+	dse.Extensions = e.GetValues(attrSupportedExt)
+	dse.Features = e.GetValues(attrSupportedFeatures)
+	dse.VendorName = e.GetValue(attrVendorName)
+	dse.VendorVersion = e.GetValue(attrVendorVersion)
+	dse.Subschema = e.GetValue(attrSubschema)
+	dse.DefaultContext = e.GetValue(attrDefaultContext)
+	for _, v := range e.GetValues(attrSupportedVersion) {
+		if n, err := strconv.Atoi(v); err == nil {
+			dse.Versions = append(dse.Versions, n)
+		}
+	}
+	return dse, nil
+}
+
+func (c *Client) NamingContexts() ([]string, error) {
+	dse, err := c.RootDSE()
+	if err != nil {
+		return nil, err
+	}
+	return dse.NamingContexts, nil
+}
+
+func (c *Client) DefaultBase() (string, error) {
+	dse, err := c.RootDSE()
+	if err != nil {
+		return "", err
+	}
+	return dse.DefaultBase()
+}
+
+func (r RootDSE) DefaultBase() (string, error) {
+	if r.DefaultContext != "" {
+		return r.DefaultContext, nil
+	}
+	switch len(r.NamingContexts) {
+	case 0:
+		return "", fmt.Errorf("%w: server does not publish naming contexts", ErrNoDefaultBase)
+	case 1:
+		return r.NamingContexts[0], nil
+	default:
+		return "", fmt.Errorf("%w: choose one of %s", ErrNoDefaultBase, strings.Join(r.NamingContexts, "; "))
+	}
+}
+
+func (r RootDSE) SupportsControl(oid string) bool {
+	return containsName(r.Controls, oid)
+}
+
+func (r RootDSE) SupportsExtension(oid string) bool {
+	return containsName(r.Extensions, oid)
+}
+
+func (r RootDSE) SupportsFeature(oid string) bool {
+	return containsName(r.Features, oid)
+}
+
+func (r RootDSE) SupportsSASL(mech string) bool {
+	return containsName(r.SASLMechanisms, mech)
+}
+
+const (
+	capActiveDirectory = "1.2.840.113556.1.4.800"
+	classOpenLDAPRoot  = "OpenLDAProotDSE"
+)
+
+type Vendor uint8
+
+const (
+	VendorUnknown Vendor = iota
+	VendorOpenLDAP
+	VendorActiveDirectory
+	Vendor389DS
+	VendorApacheDS
+	VendorEDirectory
+)
+
+func (v Vendor) String() string {
+	switch v {
+	case VendorOpenLDAP:
+		return "OpenLDAP"
+	case VendorActiveDirectory:
+		return "Active Directory"
+	case Vendor389DS:
+		return "389-DS"
+	case VendorApacheDS:
+		return "ApacheDS"
+	case VendorEDirectory:
+		return "eDirectory"
+	default:
+		return "unknown"
+	}
+}
+
+func (c *Client) DetectVendor() (Vendor, string, error) {
+	dse, err := c.RootDSE()
+	if err != nil {
+		return VendorUnknown, "", err
+	}
+	v, version := dse.DetectVendor()
+	return v, version, nil
+}
+
+func (r RootDSE) DetectVendor() (Vendor, string) {
+	var (
+		name    = strings.ToLower(r.VendorName)
+		version = strings.ToLower(r.VendorVersion)
+	)
+	switch {
+	case containsName(r.Entry.GetValues(attrCapabilities), capActiveDirectory):
+		return VendorActiveDirectory, r.Entry.GetValue(attrDCFunctionality)
+	case strings.Contains(version, "389-directory") || strings.Contains(name, "389 project"):
+		return Vendor389DS, versionOf(r.VendorVersion)
+	case strings.Contains(name, "apache software foundation") || strings.Contains(version, "apacheds"):
+		return VendorApacheDS, versionOf(r.VendorVersion)
+	case strings.Contains(version, "edirectory") || strings.Contains(name, "novell") || strings.Contains(name, "netiq"):
+		return VendorEDirectory, versionOf(r.VendorVersion)
+	case containsName(r.Entry.GetValues(attrObjectClass), classOpenLDAPRoot) || strings.Contains(name, "openldap"):
+		return VendorOpenLDAP, versionOf(r.VendorVersion)
+	case r.Entry.Has(attrConfigContext) && r.VendorName == "":
+		return VendorOpenLDAP, versionOf(r.VendorVersion)
+	default:
+		return VendorUnknown, r.VendorVersion
+	}
+}
+
+func versionOf(str string) string {
+	for _, f := range strings.FieldsFunc(str, func(r rune) bool {
+		return unicode.IsSpace(r) || r == '/' || r == '(' || r == ')'
+	}) {
+		x := strings.IndexFunc(f, func(r rune) bool {
+			return !unicode.IsDigit(r)
+		})
+		if x == 0 || (x > 0 && f[x] != dot) {
+			continue
+		}
+		return f
+	}
+	return ""
+}
@@ -0,0 +1,666 @@
+package ldap
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+const (
+	attrStructureRules = "dITStructureRules"
+	attrNameForms      = "nameForms"
+	attrContentRules   = "dITContentRules"
+	attrObjectClass    = "objectClass"
+	classExtensible    = "extensibleObject"
+	attrAttributeTypes = "attributeTypes"
+	attrObjectClasses  = "objectClasses"
+	attrSyntaxes       = "ldapSyntaxes"
+	attrMatchingRules  = "matchingRules"
+)
+
+type Schema struct {
+	AttributeTypes []AttributeType
+	ObjectClasses  []ObjectClass
+	Syntaxes       []Syntax
+	MatchingRules  []MatchingRuleDef
+	NameForms      []NameForm
+	StructureRules []StructureRule
+	ContentRules   []ContentRule
+}
+
+func ParseSchema(e Entry) (*Schema, error) {
+	var s Schema
+	for _, a := range e.Attrs {
+		for _, v := range a.Values {
+			var err error
+			switch {
+			case strings.EqualFold(a.Name, attrAttributeTypes):
+				var at AttributeType
+				if at, err = ParseAttributeType(v); err == nil {
+					s.AttributeTypes = append(s.AttributeTypes, at)
+				}
+			case strings.EqualFold(a.Name, attrObjectClasses):
+				var oc ObjectClass
+				if oc, err = ParseObjectClass(v); err == nil {
+					s.ObjectClasses = append(s.ObjectClasses, oc)
+				}
+			case strings.EqualFold(a.Name, attrSyntaxes):
+				var sx Syntax
+				if sx, err = ParseSyntax(v); err == nil {
+					s.Syntaxes = append(s.Syntaxes, sx)
+				}
+			case strings.EqualFold(a.Name, attrMatchingRules):
+				var mr MatchingRuleDef
+				if mr, err = ParseMatchingRule(v); err == nil {
+					s.MatchingRules = append(s.MatchingRules, mr)
+				}
+			case strings.EqualFold(a.Name, attrNameForms):
+				var nf NameForm
+				if nf, err = ParseNameForm(v); err == nil {
+					s.NameForms = append(s.NameForms, nf)
+				}
+			case strings.EqualFold(a.Name, attrStructureRules):
+				var sr StructureRule
+				if sr, err = ParseStructureRule(v); err == nil {
+					s.StructureRules = append(s.StructureRules, sr)
+				}
+			case strings.EqualFold(a.Name, attrContentRules):
+				var cr ContentRule
+				if cr, err = ParseContentRule(v); err == nil {
+					s.ContentRules = append(s.ContentRules, cr)
+				}
+			}
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", a.Name, err)
+			}
+		}
+	}
+	return &s, nil
+}
+
+func (s *Schema) AttributeType(name string) (AttributeType, bool) {
+	for _, at := range s.AttributeTypes {
+		if isNamed(at.OID, at.Names, name) {
+			return at, true
+		}
+	}
+	return AttributeType{}, false
+}
+
+func (s *Schema) EqualityFor(attr string) (MatchingRule, bool) {
+	for _, at := range s.Supertypes(ParseAttributeDescription(attr).Type) {
+		if at.Equality != "" {
+			return MatchingRuleNamed(at.Equality)
+		}
+	}
+	return 0, false
+}
+
+func (s *Schema) RegisterMatchingRules() {
+	for _, at := range s.AttributeTypes {
+		rule, ok := s.EqualityFor(at.OID)
+		if !ok {
+			continue
+		}
+		for _, n := range at.Names {
+			RegisterMatchingRule(n, rule)
+		}
+		RegisterMatchingRule(at.OID, rule)
+	}
+}
+
+func (s *Schema) ObjectClass(name string) (ObjectClass, bool) {
+	for _, oc := range s.ObjectClasses {
+		if isNamed(oc.OID, oc.Names, name) {
+			return oc, true
+		}
+	}
+	return ObjectClass{}, false
+}
+
+func (s *Schema) Syntax(oid string) (Syntax, bool) {
+	for _, sx := range s.Syntaxes {
+		if sx.OID == oid {
+			return sx, true
+		}
+	}
+	return Syntax{}, false
+}
+
+func (s *Schema) MatchingRule(name string) (MatchingRuleDef, bool) {
+	for _, mr := range s.MatchingRules {
+		if isNamed(mr.OID, mr.Names, name) {
+			return mr, true
+		}
+	}
+	return MatchingRuleDef{}, false
+}
+
+func (s *Schema) NameForm(name string) (NameForm, bool) {
+	for _, nf := range s.NameForms {
+		if isNamed(nf.OID, nf.Names, name) {
+			return nf, true
+		}
+	}
+	return NameForm{}, false
+}
+
+func (s *Schema) StructureRule(id int) (StructureRule, bool) {
+	for _, sr := range s.StructureRules {
+		if sr.ID == id {
+			return sr, true
+		}
+	}
+	return StructureRule{}, false
+}
+
+func (s *Schema) ContentRule(name string) (ContentRule, bool) {
+	for _, cr := range s.ContentRules {
+		if isNamed(cr.OID, cr.Names, name) {
+			return cr, true
+		}
+	}
+	return ContentRule{}, false
+}
+
+func (s *Schema) ValidateAdd(dn string, attrs []Attribute, parent []string) error {
+	var classes []string
+	for _, a := range attrs {
+		if strings.EqualFold(a.Name, attrObjectClass) {
+			classes = append(classes, a.Values...)
+		}
+	}
+	if err := s.CheckName(dn, classes, parent); err != nil {
+		return err
+	}
+	if err := s.CheckAttributes(classes, attrs); err != nil {
+		return err
+	}
+	return s.CheckContent(classes, attrs)
+}
+
+func (s *Schema) ValidateEntry(e Entry) error {
+	return s.ValidateAdd(e.Name, e.Attrs, nil)
+}
+
+func (s *Schema) ValidateModDN(dn, rdn, superior string, classes, parent []string) error {
+	name, err := Explode(dn)
+	if err != nil {
+		return namingViolation(err.Error())
+	}
+	if superior == "" {
+		superior = name.Parent(1).String()
+	}
+	next := rdn
+	if superior != "" {
+		next = rdn + string(comma) + superior
+	}
+	return s.CheckName(next, classes, parent)
+}
+
+func (s *Schema) CheckName(dn string, classes, parent []string) error {
+	name, err := Explode(dn)
+	if err != nil {
+		return namingViolation(err.Error())
+	}
+	forms := s.formsFor(classes)
+	if len(forms) == 0 {
+		return nil
+	}
+	var (
+		rdn   = name.RDN()
+		valid []NameForm
+	)
+	for _, nf := range forms {
+		if nf.accept(rdn) {
+			valid = append(valid, nf)
+		}
+	}
+	if len(valid) == 0 {
+		return namingViolation(fmt.Sprintf("%s: rdn does not match any name form", rdn))
+	}
+	if len(s.StructureRules) == 0 || parent == nil {
+		return nil
+	}
+	var (
+		rules    = s.rulesFor(valid)
+		superior = s.rulesFor(s.formsFor(parent))
+	)
+	if len(rules) == 0 {
+		return namingViolation(fmt.Sprintf("%s: no structure rule governs entry", dn))
+	}
+	for _, r := range rules {
+		for _, p := range superior {
+			if r.allow(p.ID) {
+				return nil
+			}
+		}
+	}
+	return namingViolation(fmt.Sprintf("%s: entry not allowed under its superior", dn))
+}
+
+func (s *Schema) CheckContent(classes []string, attrs []Attribute) error {
+	for _, c := range classes {
+		cr, ok := s.ContentRule(c)
+		if !ok || cr.Obsolete {
+			continue
+		}
+		for _, m := range cr.Must {
+			if !hasAttribute(attrs, m) {
+				return objectClassViolation(fmt.Sprintf("%s: attribute required by content rule %s", m, c))
+			}
+		}
+		for _, n := range cr.Not {
+			if hasAttribute(attrs, n) {
+				return objectClassViolation(fmt.Sprintf("%s: attribute precluded by content rule %s", n, c))
+			}
+		}
+		for _, x := range classes {
+			if strings.EqualFold(x, c) || isNamed("", cr.Names, x) || !s.isAuxiliary(x) {
+				continue
+			}
+			if !containsName(cr.Aux, x) {
+				return objectClassViolation(fmt.Sprintf("%s: auxiliary class not allowed by content rule %s", x, c))
+			}
+		}
+	}
+	return nil
+}
+
+func (s *Schema) CheckAttributes(classes []string, attrs []Attribute) error {
+	if len(s.ObjectClasses) == 0 {
+		return nil
+	}
+	if len(classes) == 0 {
+		return objectClassViolation("no object class provided")
+	}
+	var (
+		must       []string
+		may        []string
+		structural bool
+		extensible bool
+	)
+	for _, c := range s.expandClasses(classes) {
+		oc, ok := s.ObjectClass(c)
+		if !ok {
+			return objectClassViolation(fmt.Sprintf("%s: undefined object class", c))
+		}
+		structural = structural || oc.Kind == ClassStructural
+		extensible = extensible || isNamed(oc.OID, oc.Names, classExtensible)
+		must = append(must, oc.Must...)
+		may = append(may, oc.May...)
+	}
+	if !structural {
+		return objectClassViolation("no structural object class provided")
+	}
+	for _, m := range must {
+		if !s.hasAttribute(attrs, m) {
+			return objectClassViolation(fmt.Sprintf("%s: attribute required by object classes", m))
+		}
+	}
+	for _, a := range attrs {
+		name := a.Description().Type
+		at, ok := s.AttributeType(name)
+		if !ok {
+			return Result{
+				Code:       UndefinedAttributeType,
+				Diagnostic: fmt.Sprintf("%s: undefined attribute type", name),
+			}
+		}
+		if at.SingleValue && len(a.Values) > 1 {
+			return Result{
+				Code:       ConstraintViolation,
+				Diagnostic: fmt.Sprintf("%s: single-value attribute has %d values", name, len(a.Values)),
+			}
+		}
+		if extensible || at.Usage != UsageUser {
+			continue
+		}
+		if !s.allowAttribute(must, at) && !s.allowAttribute(may, at) {
+			return objectClassViolation(fmt.Sprintf("%s: attribute not allowed by object classes", name))
+		}
+	}
+	return nil
+}
+
+func (s *Schema) expandClasses(classes []string) []string {
+	var (
+		list []string
+		seen = make(map[string]struct{})
+	)
+	for len(classes) > 0 {
+		c := classes[0]
+		classes = classes[1:]
+		key := strings.ToLower(c)
+		oc, ok := s.ObjectClass(c)
+		if ok {
+			key = oc.OID
+		}
+		if _, dup := seen[key]; dup {
+			continue
+		}
+		seen[key] = struct{}{}
+		list = append(list, c)
+		if ok {
+			classes = append(classes, oc.Sup...)
+		}
+	}
+	return list
+}
+
+func (s *Schema) hasAttribute(attrs []Attribute, name string) bool {
+	at, ok := s.AttributeType(name)
+	if !ok {
+		return hasAttribute(attrs, name)
+	}
+	for _, a := range attrs {
+		if isNamed(at.OID, at.Names, a.Description().Type) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Schema) allowAttribute(list []string, at AttributeType) bool {
+	for _, n := range list {
+		if isNamed(at.OID, at.Names, n) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *Schema) isAuxiliary(class string) bool {
+	oc, ok := s.ObjectClass(class)
+	return ok && oc.Kind == ClassAuxiliary
+}
+
+func (s *Schema) formsFor(classes []string) []NameForm {
+	var forms []NameForm
+	for _, nf := range s.NameForms {
+		if nf.Obsolete {
+			continue
+		}
+		if containsName(classes, nf.Class) {
+			forms = append(forms, nf)
+		}
+	}
+	return forms
+}
+
+func (s *Schema) rulesFor(forms []NameForm) []StructureRule {
+	var rules []StructureRule
+	for _, sr := range s.StructureRules {
+		if sr.Obsolete {
+			continue
+		}
+		for _, nf := range forms {
+			if isNamed(nf.OID, nf.Names, sr.Form) {
+				rules = append(rules, sr)
+				break
+			}
+		}
+	}
+	return rules
+}
+
+type NameForm struct {
+	OID      string
+	Names    []string
+	Desc     string
+	Obsolete bool
+	Class    string
+	Must     []string
+	May      []string
+}
+
+func ParseNameForm(str string) (NameForm, error) {
+	var nf NameForm
+	def, err := parseDefinition(str)
+	if err != nil {
+		return nf, err
+	}
+	nf.OID = def.id
+	nf.Names = def.get("NAME")
+	nf.Desc = def.first("DESC")
+	nf.Obsolete = def.has("OBSOLETE")
+	nf.Class = def.first("OC")
+	nf.Must = def.get("MUST")
+	nf.May = def.get("MAY")
+	if nf.Class == "" || len(nf.Must) == 0 {
+		return nf, fmt.Errorf("%s: name form requires OC and MUST", nf.OID)
+	}
+	return nf, nil
+}
+
+func (nf NameForm) accept(rdn RDN) bool {
+	var found int
+	for _, a := range rdn.attrs {
+		switch {
+		case containsName(nf.Must, a.Name):
+			found++
+		case containsName(nf.May, a.Name):
+		default:
+			return false
+		}
+	}
+	return found >= len(nf.Must)
+}
+
+type StructureRule struct {
+	ID       int
+	Names    []string
+	Desc     string
+	Obsolete bool
+	Form     string
+	Sup      []int
+}
+
+func ParseStructureRule(str string) (StructureRule, error) {
+	var sr StructureRule
+	def, err := parseDefinition(str)
+	if err != nil {
+		return sr, err
+	}
+	if sr.ID, err = strconv.Atoi(def.id); err != nil {
+		return sr, fmt.Errorf("%s: invalid rule id", def.id)
+	}
+	sr.Names = def.get("NAME")
+	sr.Desc = def.first("DESC")
+	sr.Obsolete = def.has("OBSOLETE")
+	sr.Form = def.first("FORM")
+	for _, s := range def.get("SUP") {
+		id, err := strconv.Atoi(s)
+		if err != nil {
+			return sr, fmt.Errorf("%s: invalid superior rule id", s)
+		}
+		sr.Sup = append(sr.Sup, id)
+	}
+	if sr.Form == "" {
+		return sr, fmt.Errorf("%d: structure rule requires FORM", sr.ID)
+	}
+	return sr, nil
+}
+
+func (sr StructureRule) allow(id int) bool {
+	for _, s := range sr.Sup {
+		if s == id {
+			return true
+		}
+	}
+	return false
+}
+
+type ContentRule struct {
+	OID      string
+	Names    []string
+	Desc     string
+	Obsolete bool
+	Aux      []string
+	Must     []string
+	May      []string
+	Not      []string
+}
+
+func ParseContentRule(str string) (ContentRule, error) {
+	var cr ContentRule
+	def, err := parseDefinition(str)
+	if err != nil {
+		return cr, err
+	}
+	cr.OID = def.id
+	cr.Names = def.get("NAME")
+	cr.Desc = def.first("DESC")
+	cr.Obsolete = def.has("OBSOLETE")
+	cr.Aux = def.get("AUX")
+	cr.Must = def.get("MUST")
+	cr.May = def.get("MAY")
+	cr.Not = def.get("NOT")
+	return cr, nil
+}
+
+var definitionFlags = map[string]struct{}{
+	"OBSOLETE":             {},
+	"SINGLE-VALUE":         {},
+	"COLLECTIVE":           {},
+	"NO-USER-MODIFICATION": {},
+	"ABSTRACT":             {},
+	"STRUCTURAL":           {},
+	"AUXILIARY":            {},
+}
+
+type definition struct {
+	id     string
+	fields map[string][]string
+}
+
+func (d definition) has(key string) bool {
+	_, ok := d.fields[key]
+	return ok
+}
+
+func (d definition) get(key string) []string {
+	return d.fields[key]
+}
+
+func (d definition) first(key string) string {
+	if vs := d.fields[key]; len(vs) > 0 {
+		return vs[0]
+	}
+	return ""
+}
+
+func parseDefinition(str string) (definition, error) {
+	def := definition{
+		fields: make(map[string][]string),
+	}
+	toks, err := tokenizeDefinition(str)
+	if err != nil {
+		return def, err
+	}
+	if len(toks) < 3 || toks[0] != "(" || toks[len(toks)-1] != ")" {
+		return def, fmt.Errorf("%s: definition should be enclosed in parentheses", str)
+	}
+	toks = toks[1 : len(toks)-1]
+	def.id, toks = toks[0], toks[1:]
+	for len(toks) > 0 {
+		key := toks[0]
+		toks = toks[1:]
+		if _, ok := definitionFlags[key]; ok {
+			def.fields[key] = nil
+			continue
+		}
+		if len(toks) == 0 {
+			return def, fmt.Errorf("%s: missing value", key)
+		}
+		if toks[0] != "(" {
+			def.fields[key] = append(def.fields[key], toks[0])
+			toks = toks[1:]
+			continue
+		}
+		toks = toks[1:]
+		for len(toks) > 0 && toks[0] != ")" {
+			if toks[0] != "$" {
+				def.fields[key] = append(def.fields[key], toks[0])
+			}
+			toks = toks[1:]
+		}
+		if len(toks) == 0 {
+			return def, fmt.Errorf("%s: unterminated list", key)
+		}
+		toks = toks[1:]
+	}
+	return def, nil
+}
+
+func tokenizeDefinition(str string) ([]string, error) {
+	var toks []string
+	for i := 0; i < len(str); {
+		switch c := str[i]; c {
+		case space, '\t', newline, carriage:
+			i++
+		case lparen, rparen, '$':
+			toks = append(toks, string(c))
+			i++
+		case '\'':
+			j := strings.IndexByte(str[i+1:], '\'')
+			if j < 0 {
+				return nil, fmt.Errorf("%s: unterminated quoted string", str[i:])
+			}
+			toks = append(toks, unescapeDefinition(str[i+1:i+1+j]))
+			i += j + 2
+		default:
+			j := i
+			for j < len(str) && !strings.ContainsRune(" \t\r\n()$'", rune(str[j])) {
+				j++
+			}
+			toks = append(toks, str[i:j])
+			i = j
+		}
+	}
+	return toks, nil
+}
+
+func unescapeDefinition(str string) string {
+	str = strings.ReplaceAll(str, `\27`, "'")
+	return strings.ReplaceAll(str, `\5c`, `\`)
+}
+
+func isNamed(oid string, names []string, name string) bool {
+	if oid != "" && oid == name {
+		return true
+	}
+	return containsName(names, name)
+}
+
+func containsName(list []string, name string) bool {
+	for _, n := range list {
+		if strings.EqualFold(n, name) {
+			return true
+		}
+	}
+	return false
+}
+
+func hasAttribute(attrs []Attribute, name string) bool {
+	for _, a := range attrs {
+		if matchAttribute(a, name) {
+			return true
+		}
+	}
+	return false
+}
+
+func namingViolation(msg string) error {
+	return Result{
+		Code:       NamingViolation,
+		Diagnostic: msg,
+	}
+}
+
+func objectClassViolation(msg string) error {
+	return Result{
+		Code:       ObjectClassViolation,
+		Diagnostic: msg,
+	}
+}
@@ -0,0 +1,68 @@
+package ldap
+
+import (
+	"testing"
+)
+
+func testSchema(t *testing.T) *Schema {
+	t.Helper()
+	var s Schema
+	for _, str := range []string{
+		"( 2.5.6.0 NAME 'top' ABSTRACT MUST objectClass )",
+		"( 2.5.6.6 NAME 'person' SUP top STRUCTURAL MUST ( sn $ cn ) MAY description )",
+		"( 1.3.6.1.4.1.5322.13.1.1 NAME 'namedObject' SUP top AUXILIARY MAY cn )",
+		"( 1.3.6.1.1.3.1 NAME 'uidObject' SUP top AUXILIARY MUST uid )",
+	} {
+		oc, err := ParseObjectClass(str)
+		if err != nil {
+			t.Fatalf("%s: %s", str, err)
+		}
+		s.ObjectClasses = append(s.ObjectClasses, oc)
+	}
+	cr, err := ParseContentRule("( 2.5.6.6 NAME 'person' AUX namedObject )")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.ContentRules = append(s.ContentRules, cr)
+	return &s
+}
+
+func TestSchemaCheckContent(t *testing.T) {
+	s := testSchema(t)
+	data := []struct {
+		Classes []string
+		Valid   bool
+	}{
+		{Classes: []string{"top", "person"}, Valid: true},
+		{Classes: []string{"top", "person", "namedObject"}, Valid: true},
+		{Classes: []string{"top", "person", "uidObject"}, Valid: false},
+		{Classes: []string{"top", "person", "namedObject", "uidObject"}, Valid: false},
+	}
+	for _, d := range data {
+		attrs := []Attribute{
+			{Name: "objectClass", Values: d.Classes},
+			{Name: "cn", Values: []string{"john"}},
+			{Name: "sn", Values: []string{"doe"}},
+		}
+		err := s.CheckContent(d.Classes, attrs)
+		if got := err == nil; got != d.Valid {
+			t.Errorf("%v: want valid %t, got %t (%v)", d.Classes, d.Valid, got, err)
+		}
+	}
+}
+
+func TestSchemaIsAuxiliary(t *testing.T) {
+	s := testSchema(t)
+	data := map[string]bool{
+		"namedObject": true,
+		"uidObject":   true,
+		"person":      false,
+		"top":         false,
+		"unknown":     false,
+	}
+	for class, want := range data {
+		if got := s.isAuxiliary(class); got != want {
+			t.Errorf("%s: want auxiliary %t, got %t", class, want, got)
+		}
+	}
+}
@@ -0,0 +1,130 @@
+package ldap
+
+import (
+	"sort"
+	"strings"
+)
+
+const (
+	kindAttributeType = "attributeType"
+	kindObjectClass   = "objectClass"
+)
+
+type SchemaChange struct {
+	Kind  string
+	OID   string
+	Name  string
+	Left  string
+	Right string
+}
+
+func (c SchemaChange) Added() bool {
+	return c.Left == "" && c.Right != ""
+}
+
+func (c SchemaChange) Removed() bool {
+	return c.Left != "" && c.Right == ""
+}
+
+func DiffSchema(left, right *Schema) []SchemaChange {
+	var changes []SchemaChange
+
+	attrs := make(map[string]AttributeType)
+	for _, at := range right.AttributeTypes {
+		attrs[strings.ToLower(at.OID)] = at
+	}
+	for _, at := range left.AttributeTypes {
+		key := strings.ToLower(at.OID)
+		other, ok := attrs[key]
+		delete(attrs, key)
+		if ok && sameAttributeType(at, other) {
+			continue
+		}
+		c := SchemaChange{
+			Kind: kindAttributeType,
+			OID:  at.OID,
+			Name: at.Name(),
+			Left: at.String(),
+		}
+		if ok {
+			c.Right = other.String()
+		}
+		changes = append(changes, c)
+	}
+	for _, at := range attrs {
+		changes = append(changes, SchemaChange{
+			Kind:  kindAttributeType,
+			OID:   at.OID,
+			Name:  at.Name(),
+			Right: at.String(),
+		})
+	}
+
+	classes := make(map[string]ObjectClass)
+	for _, oc := range right.ObjectClasses {
+		classes[strings.ToLower(oc.OID)] = oc
+	}
+	for _, oc := range left.ObjectClasses {
+		key := strings.ToLower(oc.OID)
+		other, ok := classes[key]
+		delete(classes, key)
+		if ok && sameObjectClass(oc, other) {
+			continue
+		}
+		c := SchemaChange{
+			Kind: kindObjectClass,
+			OID:  oc.OID,
+			Name: oc.Name(),
+			Left: oc.String(),
+		}
+		if ok {
+			c.Right = other.String()
+		}
+		changes = append(changes, c)
+	}
+	for _, oc := range classes {
+		changes = append(changes, SchemaChange{
+			Kind:  kindObjectClass,
+			OID:   oc.OID,
+			Name:  oc.Name(),
+			Right: oc.String(),
+		})
+	}
+
+	sort.Slice(changes, func(i, j int) bool {
+		if changes[i].Kind != changes[j].Kind {
+			return changes[i].Kind < changes[j].Kind
+		}
+		return strings.ToLower(changes[i].Name) < strings.ToLower(changes[j].Name)
+	})
+	return changes
+}
+
+func sameAttributeType(left, right AttributeType) bool {
+	if !sameNames(left.Names, right.Names) {
+		return false
+	}
+	left.Desc, right.Desc = "", ""
+	left.Names, right.Names = nil, nil
+	return strings.EqualFold(left.String(), right.String())
+}
+
+func sameObjectClass(left, right ObjectClass) bool {
+	ok := sameNames(left.Names, right.Names) &&
+		sameNames(left.Sup, right.Sup) &&
+		sameNames(left.Must, right.Must) &&
+		sameNames(left.May, right.May)
+	return ok && left.Kind == right.Kind && left.Obsolete == right.Obsolete
+}
+
+func sameNames(left, right []string) bool {
+	if len(left) != len(right) {
+		return false
+	}
+	for _, n := range left {
+		if !containsName(right, n) {
+			return false
+		}
+	}
+	return true
+}
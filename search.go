@@ -1,6 +1,9 @@
 package ldap
 
 import (
+	"context"
+	"fmt"
+	"strings"
 	"time"
 )
 
@@ -21,6 +24,40 @@ const (
 	ScopeWhole
 )
 
+func (s Scope) String() string {
+	switch s {
+	case ScopeBase:
+		return "base"
+	case ScopeSingle:
+		return "one"
+	case ScopeWhole:
+		return "sub"
+	default:
+		return fmt.Sprintf("scope(%d)", uint8(s))
+	}
+}
+
+func (s Scope) MarshalText() ([]byte, error) {
+	if !s.isValid() {
+		return nil, fmt.Errorf("%d: invalid scope", s)
+	}
+	return []byte(s.String()), nil
+}
+
+func (s *Scope) UnmarshalText(b []byte) error {
+	switch str := strings.ToLower(string(b)); str {
+	case "base":
+		*s = ScopeBase
+	case "one", "single", "onelevel":
+		*s = ScopeSingle
+	case "sub", "subtree", "whole":
+		*s = ScopeWhole
+	default:
+		return fmt.Errorf("%s: invalid scope", str)
+	}
+	return nil
+}
+
 type Deref uint8
 
 func (d Deref) isValid() bool {
@@ -39,6 +76,44 @@ const (
 	DerefAlways
 )
 
+func (d Deref) String() string {
+	switch d {
+	case DerefNever:
+		return "never"
+	case DerefSearching:
+		return "searching"
+	case DerefFinding:
+		return "finding"
+	case DerefAlways:
+		return "always"
+	default:
+		return fmt.Sprintf("deref(%d)", uint8(d))
+	}
+}
+
+func (d Deref) MarshalText() ([]byte, error) {
+	if !d.isValid() {
+		return nil, fmt.Errorf("%d: invalid deref", d)
+	}
+	return []byte(d.String()), nil
+}
+
+func (d *Deref) UnmarshalText(b []byte) error {
+	switch str := strings.ToLower(string(b)); str {
+	case "never":
+		*d = DerefNever
+	case "searching", "search":
+		*d = DerefSearching
+	case "finding", "find":
+		*d = DerefFinding
+	case "always":
+		*d = DerefAlways
+	default:
+		return fmt.Errorf("%s: invalid deref", str)
+	}
+	return nil
+}
+
 type searchRequest struct {
 	Base     string `ber:"tag:0x4"`
 	Scope    Scope  `ber:"tag:0xa"`
@@ -48,7 +123,28 @@ type searchRequest struct {
 	Types    bool
 	Filter   Filter
 	Attrs    [][]byte
-	controls []Control `ber:"-"`
+	controls []Control       `ber:"-"`
+	fallback bool            `ber:"-"`
+	maxbytes int             `ber:"-"`
+	ctx      context.Context `ber:"-"`
+}
+
+func (sr *searchRequest) deadline() error {
+	if sr.ctx == nil {
+		return nil
+	}
+	if err := sr.ctx.Err(); err != nil {
+		return err
+	}
+	when, ok := sr.ctx.Deadline()
+	if !ok || sr.Delay > 0 {
+		return nil
+	}
+	sr.Delay = int(time.Until(when) / time.Second)
+	if sr.Delay < 1 {
+		sr.Delay = 1
+	}
+	return nil
 }
 
 type SearchOption func(*searchRequest) error
@@ -60,6 +156,26 @@ func WithControl(ctrl Control) SearchOption {
 	}
 }
 
+func WithBaseFallback() SearchOption {
+	return func(sr *searchRequest) error {
+		sr.fallback = true
+		return nil
+	}
+}
+
+// WithValueLimit truncates every attribute value longer than limit bytes
+// and marks its attribute as Truncated. Values are cut once the entry has
+// been received and decoded: the limit bounds what is handed to the caller,
+// not what the server sends.
+func WithValueLimit(limit int) SearchOption {
+	return func(sr *searchRequest) error {
+		if limit > 0 {
+			sr.maxbytes = limit
+		}
+		return nil
+	}
+}
+
 func WithFilter(filter Filter) SearchOption {
 	return func(sr *searchRequest) error {
 		sr.Filter = filter
@@ -105,6 +221,13 @@ func WithTime(limit time.Duration) SearchOption {
 	}
 }
 
+func WithContext(ctx context.Context) SearchOption {
+	return func(sr *searchRequest) error {
+		sr.ctx = ctx
+		return nil
+	}
+}
+
 func WithTypes(only bool) SearchOption {
 	return func(sr *searchRequest) error {
 		sr.Types = only
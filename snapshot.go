@@ -0,0 +1,239 @@
+package ldap
+
+import (
+	"fmt"
+	"io"
+	"os"
+)
+
+type SnapshotClient struct {
+	entries []Entry
+	names   []DN
+	index   map[string]int
+}
+
+func NewSnapshotClient(file string) (*SnapshotClient, error) {
+	r, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer r.Close()
+	return NewSnapshot(r)
+}
+
+func NewSnapshot(r io.Reader) (*SnapshotClient, error) {
+	s := SnapshotClient{
+		index: make(map[string]int),
+	}
+	err := ReadLDIF(r, func(ct ChangeType, c Change) error {
+		if ct != ModAdd {
+			return fmt.Errorf("%s: snapshot only accepts entries", c.Name)
+		}
+		dn, err := Explode(c.Name)
+		if err != nil {
+			return err
+		}
+		e := Entry{Name: c.Name}
+		for _, a := range c.Attrs {
+			e.Attrs = append(e.Attrs, a.Attribute)
+		}
+		key := dn.Normalize().String()
+		if x, ok := s.index[key]; ok {
+			s.entries[x] = e
+			return nil
+		}
+		s.index[key] = len(s.entries)
+		s.entries = append(s.entries, e)
+		s.names = append(s.names, dn)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &s, nil
+}
+
+func (s *SnapshotClient) Len() int {
+	return len(s.entries)
+}
+
+func (s *SnapshotClient) GetEntry(dn string, attrs ...string) (Entry, error) {
+	es, _, err := s.Search(dn, WithAttributes(attrs))
+	if err != nil {
+		return Entry{}, err
+	}
+	if len(es) == 0 {
+		return Entry{}, Result{Code: NoSuchObject, Name: dn}
+	}
+	return es[0], nil
+}
+
+func (s *SnapshotClient) Search(base string, options ...SearchOption) ([]Entry, []ControlValue, error) {
+	var es []Entry
+	values, err := s.Stream(base, func(e Entry) error {
+		es = append(es, e)
+		return nil
+	}, options...)
+	return es, values, err
+}
+
+func (s *SnapshotClient) Stream(base string, fn func(Entry) error, options ...SearchOption) ([]ControlValue, error) {
+	search := searchRequest{
+		Base:   base,
+		Scope:  ScopeBase,
+		Deref:  DerefNever,
+		Filter: Present("objectClass"),
+	}
+	for _, opt := range options {
+		if err := opt(&search); err != nil {
+			return nil, err
+		}
+	}
+	if err := search.deadline(); err != nil {
+		return nil, err
+	}
+	root, err := Explode(search.Base)
+	if err != nil {
+		return nil, Result{Code: InvalidDNSyntax, Diagnostic: err.Error()}
+	}
+	if _, ok := s.index[root.Normalize().String()]; !ok && root.Len() > 0 {
+		return nil, Result{Code: NoSuchObject, Name: s.matchedDN(root)}
+	}
+	var count int
+	for i, e := range s.entries {
+		if !inScope(s.names[i], root, search.Scope) {
+			continue
+		}
+		ok, err := Match(search.Filter, e)
+		if err != nil {
+			return nil, err
+		}
+		if !ok {
+			continue
+		}
+		if search.Size > 0 && count >= search.Size {
+			return nil, Result{Code: SizeExeeded}
+		}
+		count++
+		if err := fn(project(e, search)); err != nil {
+			return nil, err
+		}
+	}
+	return nil, nil
+}
+
+func (s *SnapshotClient) matchedDN(dn DN) string {
+	for i := 1; i < dn.Len(); i++ {
+		parent := dn.Parent(i)
+		if x, ok := s.index[parent.Normalize().String()]; ok {
+			return s.entries[x].Name
+		}
+	}
+	return ""
+}
+
+func inScope(dn, base DN, scope Scope) bool {
+	switch scope {
+	case ScopeBase:
+		return dn.Equal(base)
+	case ScopeSingle:
+		return dn.IsChildOf(base)
+	default:
+		return base.Len() == 0 || dn.Equal(base) || dn.IsDescendantOf(base)
+	}
+}
+
+func project(e Entry, search searchRequest) Entry {
+	var (
+		res = Entry{Name: e.Name}
+		all = len(search.Attrs) == 0
+	)
+	for _, a := range search.Attrs {
+		if string(a) == "*" {
+			all = true
+		}
+	}
+	for _, a := range e.Attrs {
+		if !all && !selected(a, search.Attrs) {
+			continue
+		}
+		a.Values = append([]string{}, a.Values...)
+		if search.Types {
+			a.Values = nil
+		}
+		if search.maxbytes > 0 {
+			a.truncate(search.maxbytes)
+		}
+		res.Attrs = append(res.Attrs, a)
+	}
+	return res
+}
+
+func selected(a Attribute, names [][]byte) bool {
+	for _, n := range names {
+		if matchAttribute(a, string(n)) {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *SnapshotClient) Bind(user, passwd string, controls ...Control) ([]ControlValue, error) {
+	return nil, nil
+}
+
+func (s *SnapshotClient) Unbind(controls ...Control) error {
+	return nil
+}
+
+func (s *SnapshotClient) Compare(dn string, ava AttributeAssertion, controls ...Control) (bool, []ControlValue, error) {
+	e, err := s.GetEntry(dn, ava.Desc)
+	if err != nil {
+		return false, nil, err
+	}
+	if !hasAttribute(e.Attrs, ava.Desc) {
+		return false, nil, Result{Code: NoSuchAttribute, Name: dn}
+	}
+	for _, v := range e.GetValues(ava.Desc) {
+		if MatchValues(ava.Desc, v, ava.Attr) {
+			return true, nil, nil
+		}
+	}
+	return false, nil, nil
+}
+
+func (s *SnapshotClient) Add(dn string, attrs []Attribute, controls ...Control) ([]ControlValue, error) {
+	return nil, readOnly(dn)
+}
+
+func (s *SnapshotClient) Modify(dn string, attrs []PartialAttribute, controls ...Control) ([]ControlValue, error) {
+	return nil, readOnly(dn)
+}
+
+func (s *SnapshotClient) Delete(dn string, controls ...Control) ([]ControlValue, error) {
+	return nil, readOnly(dn)
+}
+
+func (s *SnapshotClient) ModDN(dn, rdn, parent string, keep bool, controls ...Control) ([]ControlValue, error) {
+	return nil, readOnly(dn)
+}
+
+func (s *SnapshotClient) Rename(dn, rdn string, keep bool, controls ...Control) ([]ControlValue, error) {
+	return nil, readOnly(dn)
+}
+
+func (s *SnapshotClient) Move(dn, parent string, controls ...Control) ([]ControlValue, error) {
+	return nil, readOnly(dn)
+}
+
+func (s *SnapshotClient) Extended(oid string, value []byte, controls ...Control) (ExtendedResult, error) {
+	return ExtendedResult{}, readOnly(oid)
+}
+
+func readOnly(name string) error {
+	return Result{
+		Code:       UnwillingToPerform,
+		Name:       name,
+		Diagnostic: "snapshot is read-only",
+	}
+}
@@ -0,0 +1,110 @@
+package ldap
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+type Preparation uint8
+
+const (
+	PrepExact Preparation = iota
+	PrepCaseIgnore
+	PrepNumeric
+	PrepTelephone
+)
+
+func Prepare(str string, prep Preparation) (string, error) {
+	return prepareString(str, prep, tagFilterEquality)
+}
+
+func prepareString(str string, prep Preparation, pos uint64) (string, error) {
+	mapped := mapCharacters(str)
+	mapped = norm.NFKC.String(mapped)
+	if prep == PrepCaseIgnore {
+		mapped = norm.NFKC.String(strings.ToLower(mapped))
+	}
+	for _, r := range mapped {
+		if isProhibited(r) {
+			return "", fmt.Errorf("%s: prohibited character %U", str, r)
+		}
+	}
+	switch prep {
+	case PrepNumeric:
+		return strings.ReplaceAll(mapped, " ", ""), nil
+	case PrepTelephone:
+		mapped = strings.ReplaceAll(mapped, " ", "")
+		return strings.Map(func(r rune) rune {
+			if r == minus || r == '\u2010' || r == '\u2011' || r == '\u2212' || r == '\uFE63' || r == '\uFF0D' {
+				return -1
+			}
+			return r
+		}, mapped), nil
+	default:
+		return insignificantSpace(mapped, pos), nil
+	}
+}
+
+func mapCharacters(str string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r == '\u00AD' || r == '\u1806' || r == '\u034F' || r == '\uFFFC' || r == '\u200B':
+			return -1
+		case r >= '\u180B' && r <= '\u180D', r >= '\uFE00' && r <= '\uFE0F':
+			return -1
+		case r == '\t' || r == '\n' || r == '\v' || r == '\f' || r == '\r' || r == '\u0085':
+			return space
+		case unicode.Is(unicode.Cc, r), unicode.Is(unicode.Cf, r):
+			return -1
+		case unicode.Is(unicode.Zs, r), r == '\u2028', r == '\u2029':
+			return space
+		default:
+			return r
+		}
+	}, str)
+}
+
+func isProhibited(r rune) bool {
+	switch {
+	case r == unicode.ReplacementChar:
+		return true
+	case unicode.Is(unicode.Co, r), unicode.Is(unicode.Cs, r):
+		return true
+	case r >= '\uFDD0' && r <= '\uFDEF', r&0xFFFE == 0xFFFE:
+		return true
+	default:
+		return false
+	}
+}
+
+func insignificantSpace(str string, pos uint64) string {
+	trimmed := strings.Trim(str, " ")
+	if trimmed == "" {
+		if pos == tagFilterEquality {
+			return "  "
+		}
+		return " "
+	}
+	var buf strings.Builder
+	switch pos {
+	case tagFilterEquality, subInitial:
+		buf.WriteRune(space)
+	default:
+		if strings.HasPrefix(str, " ") {
+			buf.WriteRune(space)
+		}
+	}
+	buf.WriteString(strings.Join(strings.Fields(trimmed), "  "))
+	switch pos {
+	case tagFilterEquality, subFinal:
+		buf.WriteRune(space)
+	default:
+		if strings.HasSuffix(str, " ") {
+			buf.WriteRune(space)
+		}
+	}
+	return buf.String()
+}
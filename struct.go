@@ -0,0 +1,242 @@
+package ldap
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	structTag = "ldap"
+	structDN  = "dn"
+)
+
+var ErrUnsupportedType = errors.New("unsupported type")
+
+var (
+	timeType  = reflect.TypeOf(time.Time{})
+	bytesType = reflect.TypeOf([]byte(nil))
+)
+
+func MarshalEntry(v interface{}) (Entry, error) {
+	var e Entry
+	rv := reflect.Indirect(reflect.ValueOf(v))
+	if rv.Kind() != reflect.Struct {
+		return e, fmt.Errorf("%s: %w (struct expected)", rv.Type(), ErrUnsupportedType)
+	}
+	for _, f := range structFields(rv.Type()) {
+		fv := rv.Field(f.index)
+		if f.name == structDN {
+			e.Name = fv.String()
+			continue
+		}
+		values, err := marshalValues(fv)
+		if err != nil {
+			return e, fmt.Errorf("%s: %w", f.name, err)
+		}
+		if len(values) == 0 {
+			continue
+		}
+		e.Attrs = append(e.Attrs, Attribute{
+			Name:   f.name,
+			Values: values,
+		})
+	}
+	return e, nil
+}
+
+func (e Entry) Unmarshal(v interface{}) error {
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("%T: %w (pointer to struct expected)", v, ErrUnsupportedType)
+	}
+	rv = rv.Elem()
+	for _, f := range structFields(rv.Type()) {
+		fv := rv.Field(f.index)
+		if f.name == structDN {
+			fv.SetString(e.Name)
+			continue
+		}
+		values := e.GetValues(f.name)
+		if len(values) == 0 {
+			continue
+		}
+		if err := unmarshalValues(fv, values); err != nil {
+			return fmt.Errorf("%s: %w", f.name, err)
+		}
+	}
+	return nil
+}
+
+type structField struct {
+	name  string
+	index int
+}
+
+func structFields(typ reflect.Type) []structField {
+	var list []structField
+	for i := 0; i < typ.NumField(); i++ {
+		f := typ.Field(i)
+		if f.PkgPath != "" {
+			continue
+		}
+		name := f.Tag.Get(structTag)
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		list = append(list, structField{
+			name:  name,
+			index: i,
+		})
+	}
+	return list
+}
+
+func marshalValues(v reflect.Value) ([]string, error) {
+	if v.Type() == bytesType {
+		if v.Len() == 0 {
+			return nil, nil
+		}
+		return []string{string(v.Bytes())}, nil
+	}
+	switch v.Kind() {
+	case reflect.Slice, reflect.Array:
+		var list []string
+		for i := 0; i < v.Len(); i++ {
+			str, ok, err := marshalValue(v.Index(i))
+			if err != nil {
+				return nil, err
+			}
+			if ok {
+				list = append(list, str)
+			}
+		}
+		return list, nil
+	case reflect.Ptr:
+		if v.IsNil() {
+			return nil, nil
+		}
+		return marshalValues(v.Elem())
+	default:
+		str, ok, err := marshalValue(v)
+		if err != nil || !ok {
+			return nil, err
+		}
+		return []string{str}, nil
+	}
+}
+
+func marshalValue(v reflect.Value) (string, bool, error) {
+	if v.Type() == timeType {
+		t := v.Interface().(time.Time)
+		if t.IsZero() {
+			return "", false, nil
+		}
+		return FormatGeneralizedTime(t), true, nil
+	}
+	if v.Type() == bytesType {
+		return string(v.Bytes()), v.Len() > 0, nil
+	}
+	switch v.Kind() {
+	case reflect.String:
+		return v.String(), v.Len() > 0, nil
+	case reflect.Bool:
+		if v.Bool() {
+			return "TRUE", true, nil
+		}
+		return "FALSE", true, nil
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(v.Int(), 10), true, nil
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return strconv.FormatUint(v.Uint(), 10), true, nil
+	case reflect.Ptr:
+		if v.IsNil() {
+			return "", false, nil
+		}
+		return marshalValue(v.Elem())
+	default:
+		return "", false, fmt.Errorf("%s: %w", v.Type(), ErrUnsupportedType)
+	}
+}
+
+func unmarshalValues(v reflect.Value, values []string) error {
+	if v.Type() == bytesType {
+		v.SetBytes([]byte(values[0]))
+		return nil
+	}
+	switch v.Kind() {
+	case reflect.Slice:
+		list := reflect.MakeSlice(v.Type(), len(values), len(values))
+		for i, str := range values {
+			if err := unmarshalValue(list.Index(i), str); err != nil {
+				return err
+			}
+		}
+		v.Set(list)
+		return nil
+	case reflect.Ptr:
+		ptr := reflect.New(v.Type().Elem())
+		if err := unmarshalValues(ptr.Elem(), values); err != nil {
+			return err
+		}
+		v.Set(ptr)
+		return nil
+	default:
+		return unmarshalValue(v, values[0])
+	}
+}
+
+func unmarshalValue(v reflect.Value, str string) error {
+	if v.Type() == timeType {
+		t, err := ParseGeneralizedTime(str)
+		if err != nil {
+			return err
+		}
+		v.Set(reflect.ValueOf(t))
+		return nil
+	}
+	if v.Type() == bytesType {
+		v.SetBytes([]byte(str))
+		return nil
+	}
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(str)
+	case reflect.Bool:
+		switch strings.ToUpper(strings.TrimSpace(str)) {
+		case "TRUE":
+			v.SetBool(true)
+		case "FALSE":
+			v.SetBool(false)
+		default:
+			return fmt.Errorf("invalid boolean value %q", str)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(strings.TrimSpace(str), 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(strings.TrimSpace(str), 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(n)
+	case reflect.Ptr:
+		ptr := reflect.New(v.Type().Elem())
+		if err := unmarshalValue(ptr.Elem(), str); err != nil {
+			return err
+		}
+		v.Set(ptr)
+	default:
+		return fmt.Errorf("%s: %w", v.Type(), ErrUnsupportedType)
+	}
+	return nil
+}
@@ -0,0 +1,387 @@
+package ldap
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	subschemaDefault = "cn=subschema"
+	subschemaClass   = "subschema"
+)
+
+type cachedSchema struct {
+	schema *Schema
+	loaded time.Time
+}
+
+var schemaCache = struct {
+	mu  sync.Mutex
+	set map[string]cachedSchema
+}{
+	set: make(map[string]cachedSchema),
+}
+
+func (c *Client) SetSchemaTTL(ttl time.Duration) {
+	c.schemaTTL = ttl
+}
+
+func (c *Client) InvalidateSchema() {
+	schemaCache.mu.Lock()
+	defer schemaCache.mu.Unlock()
+	delete(schemaCache.set, c.addr)
+}
+
+func (c *Client) Schema() (*Schema, error) {
+	if c.addr == "" {
+		return c.fetchSchema()
+	}
+	schemaCache.mu.Lock()
+	cs, ok := schemaCache.set[c.addr]
+	schemaCache.mu.Unlock()
+	if ok && (c.schemaTTL <= 0 || time.Since(cs.loaded) < c.schemaTTL) {
+		return cs.schema, nil
+	}
+	s, err := c.fetchSchema()
+	if err != nil {
+		return nil, err
+	}
+	schemaCache.mu.Lock()
+	defer schemaCache.mu.Unlock()
+	schemaCache.set[c.addr] = cachedSchema{
+		schema: s,
+		loaded: time.Now(),
+	}
+	return s, nil
+}
+
+func (c *Client) fetchSchema() (*Schema, error) {
+	dse, err := c.RootDSE()
+	if err != nil {
+		return nil, err
+	}
+	base := dse.Subschema
+	if base == "" {
+		base = subschemaDefault
+	}
+	attrs := []string{
+		attrAttributeTypes,
+		attrObjectClasses,
+		attrSyntaxes,
+		attrMatchingRules,
+		attrNameForms,
+		attrStructureRules,
+		attrContentRules,
+	}
+	es, _, err := c.Search(base, WithScope(ScopeBase), WithFilter(Equal(attrObjectClass, subschemaClass)), WithAttributes(attrs))
+	if err != nil {
+		return nil, err
+	}
+	if len(es) == 0 {
+		return nil, fmt.Errorf("%s: subschema entry not found", base)
+	}
+	return ParseSchema(es[0])
+}
+
+func (s *Schema) Superclasses(name string) []ObjectClass {
+	var list []ObjectClass
+	for _, c := range s.expandClasses([]string{name}) {
+		if oc, ok := s.ObjectClass(c); ok {
+			list = append(list, oc)
+		}
+	}
+	return list
+}
+
+func (s *Schema) Supertypes(name string) []AttributeType {
+	var list []AttributeType
+	for i := 0; i <= len(s.AttributeTypes); i++ {
+		at, ok := s.AttributeType(name)
+		if !ok {
+			break
+		}
+		list = append(list, at)
+		if at.Sup == "" {
+			break
+		}
+		name = at.Sup
+	}
+	return list
+}
+
+type AttributeUsage string
+
+const (
+	UsageUser        AttributeUsage = "userApplications"
+	UsageDirectory   AttributeUsage = "directoryOperation"
+	UsageDistributed AttributeUsage = "distributedOperation"
+	UsageDSA         AttributeUsage = "dSAOperation"
+)
+
+type AttributeType struct {
+	OID                string
+	Names              []string
+	Desc               string
+	Obsolete           bool
+	Sup                string
+	Equality           string
+	Ordering           string
+	Substr             string
+	Syntax             string
+	Length             int
+	SingleValue        bool
+	Collective         bool
+	NoUserModification bool
+	Usage              AttributeUsage
+}
+
+func ParseAttributeType(str string) (AttributeType, error) {
+	var at AttributeType
+	def, err := parseDefinition(str)
+	if err != nil {
+		return at, err
+	}
+	at.OID = def.id
+	at.Names = def.get("NAME")
+	at.Desc = def.first("DESC")
+	at.Obsolete = def.has("OBSOLETE")
+	at.Sup = def.first("SUP")
+	at.Equality = def.first("EQUALITY")
+	at.Ordering = def.first("ORDERING")
+	at.Substr = def.first("SUBSTR")
+	at.SingleValue = def.has("SINGLE-VALUE")
+	at.Collective = def.has("COLLECTIVE")
+	at.NoUserModification = def.has("NO-USER-MODIFICATION")
+	at.Usage = UsageUser
+	if u := def.first("USAGE"); u != "" {
+		switch u := AttributeUsage(u); u {
+		case UsageUser, UsageDirectory, UsageDistributed, UsageDSA:
+			at.Usage = u
+		default:
+			return at, fmt.Errorf("%s: unknown usage %s", at.OID, u)
+		}
+	}
+	if syntax := def.first("SYNTAX"); syntax != "" {
+		at.Syntax = syntax
+		if x := strings.IndexByte(syntax, '{'); x > 0 && strings.HasSuffix(syntax, "}") {
+			at.Syntax = syntax[:x]
+			if at.Length, err = strconv.Atoi(syntax[x+1 : len(syntax)-1]); err != nil {
+				return at, fmt.Errorf("%s: invalid syntax length", syntax)
+			}
+		}
+	}
+	if at.Sup == "" && at.Syntax == "" {
+		return at, fmt.Errorf("%s: attribute type requires SUP or SYNTAX", at.OID)
+	}
+	return at, nil
+}
+
+func (at AttributeType) Name() string {
+	if len(at.Names) > 0 {
+		return at.Names[0]
+	}
+	return at.OID
+}
+
+type ClassKind uint8
+
+const (
+	ClassStructural ClassKind = iota
+	ClassAbstract
+	ClassAuxiliary
+)
+
+func (k ClassKind) String() string {
+	switch k {
+	case ClassStructural:
+		return "STRUCTURAL"
+	case ClassAbstract:
+		return "ABSTRACT"
+	case ClassAuxiliary:
+		return "AUXILIARY"
+	default:
+		return "unknown"
+	}
+}
+
+type ObjectClass struct {
+	OID      string
+	Names    []string
+	Desc     string
+	Obsolete bool
+	Sup      []string
+	Kind     ClassKind
+	Must     []string
+	May      []string
+}
+
+func ParseObjectClass(str string) (ObjectClass, error) {
+	var oc ObjectClass
+	def, err := parseDefinition(str)
+	if err != nil {
+		return oc, err
+	}
+	oc.OID = def.id
+	oc.Names = def.get("NAME")
+	oc.Desc = def.first("DESC")
+	oc.Obsolete = def.has("OBSOLETE")
+	oc.Sup = def.get("SUP")
+	oc.Must = def.get("MUST")
+	oc.May = def.get("MAY")
+	switch {
+	case def.has("ABSTRACT"):
+		oc.Kind = ClassAbstract
+	case def.has("AUXILIARY"):
+		oc.Kind = ClassAuxiliary
+	default:
+		oc.Kind = ClassStructural
+	}
+	return oc, nil
+}
+
+func (oc ObjectClass) Name() string {
+	if len(oc.Names) > 0 {
+		return oc.Names[0]
+	}
+	return oc.OID
+}
+
+type Syntax struct {
+	OID  string
+	Desc string
+}
+
+func ParseSyntax(str string) (Syntax, error) {
+	var sx Syntax
+	def, err := parseDefinition(str)
+	if err != nil {
+		return sx, err
+	}
+	sx.OID = def.id
+	sx.Desc = def.first("DESC")
+	return sx, nil
+}
+
+type MatchingRuleDef struct {
+	OID      string
+	Names    []string
+	Desc     string
+	Obsolete bool
+	Syntax   string
+}
+
+func ParseMatchingRule(str string) (MatchingRuleDef, error) {
+	var mr MatchingRuleDef
+	def, err := parseDefinition(str)
+	if err != nil {
+		return mr, err
+	}
+	mr.OID = def.id
+	mr.Names = def.get("NAME")
+	mr.Desc = def.first("DESC")
+	mr.Obsolete = def.has("OBSOLETE")
+	mr.Syntax = def.first("SYNTAX")
+	if mr.Syntax == "" {
+		return mr, fmt.Errorf("%s: matching rule requires SYNTAX", mr.OID)
+	}
+	return mr, nil
+}
+
+func (at AttributeType) String() string {
+	var str strings.Builder
+	str.WriteString("( ")
+	str.WriteString(at.OID)
+	writeDefinitionNames(&str, "NAME", at.Names)
+	writeDefinitionQuoted(&str, "DESC", at.Desc)
+	writeDefinitionFlag(&str, "OBSOLETE", at.Obsolete)
+	writeDefinitionValue(&str, "SUP", at.Sup)
+	writeDefinitionValue(&str, "EQUALITY", at.Equality)
+	writeDefinitionValue(&str, "ORDERING", at.Ordering)
+	writeDefinitionValue(&str, "SUBSTR", at.Substr)
+	if at.Length > 0 {
+		writeDefinitionValue(&str, "SYNTAX", fmt.Sprintf("%s{%d}", at.Syntax, at.Length))
+	} else {
+		writeDefinitionValue(&str, "SYNTAX", at.Syntax)
+	}
+	writeDefinitionFlag(&str, "SINGLE-VALUE", at.SingleValue)
+	writeDefinitionFlag(&str, "COLLECTIVE", at.Collective)
+	writeDefinitionFlag(&str, "NO-USER-MODIFICATION", at.NoUserModification)
+	if at.Usage != "" && at.Usage != UsageUser {
+		writeDefinitionValue(&str, "USAGE", string(at.Usage))
+	}
+	str.WriteString(" )")
+	return str.String()
+}
+
+func (oc ObjectClass) String() string {
+	var str strings.Builder
+	str.WriteString("( ")
+	str.WriteString(oc.OID)
+	writeDefinitionNames(&str, "NAME", oc.Names)
+	writeDefinitionQuoted(&str, "DESC", oc.Desc)
+	writeDefinitionFlag(&str, "OBSOLETE", oc.Obsolete)
+	writeDefinitionList(&str, "SUP", oc.Sup)
+	str.WriteRune(space)
+	str.WriteString(oc.Kind.String())
+	writeDefinitionList(&str, "MUST", oc.Must)
+	writeDefinitionList(&str, "MAY", oc.May)
+	str.WriteString(" )")
+	return str.String()
+}
+
+func writeDefinitionFlag(str *strings.Builder, key string, set bool) {
+	if !set {
+		return
+	}
+	str.WriteRune(space)
+	str.WriteString(key)
+}
+
+func writeDefinitionValue(str *strings.Builder, key, value string) {
+	if value == "" {
+		return
+	}
+	str.WriteRune(space)
+	str.WriteString(key)
+	str.WriteRune(space)
+	str.WriteString(value)
+}
+
+func writeDefinitionQuoted(str *strings.Builder, key, value string) {
+	if value == "" {
+		return
+	}
+	writeDefinitionValue(str, key, quoteDefinition(value))
+}
+
+func writeDefinitionNames(str *strings.Builder, key string, names []string) {
+	switch len(names) {
+	case 0:
+	case 1:
+		writeDefinitionQuoted(str, key, names[0])
+	default:
+		list := make([]string, len(names))
+		for i := range names {
+			list[i] = quoteDefinition(names[i])
+		}
+		writeDefinitionValue(str, key, "( "+strings.Join(list, " ")+" )")
+	}
+}
+
+func writeDefinitionList(str *strings.Builder, key string, list []string) {
+	switch len(list) {
+	case 0:
+	case 1:
+		writeDefinitionValue(str, key, list[0])
+	default:
+		writeDefinitionValue(str, key, "( "+strings.Join(list, " $ ")+" )")
+	}
+}
+
+func quoteDefinition(str string) string {
+	str = strings.ReplaceAll(str, `\`, `\5c`)
+	return "'" + strings.ReplaceAll(str, "'", `\27`) + "'"
+}
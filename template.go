@@ -0,0 +1,84 @@
+package ldap
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+type FilterTemplate struct {
+	source string
+	parts  []string
+	slots  []int
+	arity  int
+}
+
+func ParseFilterTemplate(str string) (*FilterTemplate, error) {
+	t := FilterTemplate{source: str}
+	var (
+		buf   strings.Builder
+		value bool
+	)
+	for i := 0; i < len(str); i++ {
+		switch str[i] {
+		case '=':
+			value = true
+		case lparen, rparen:
+			value = false
+		}
+		if str[i] != '{' {
+			buf.WriteByte(str[i])
+			continue
+		}
+		if !value {
+			return nil, fmt.Errorf("%s: placeholder outside of assertion value at %d", str, i)
+		}
+		x := strings.IndexByte(str[i:], '}')
+		if x < 0 {
+			return nil, fmt.Errorf("%s: unterminated placeholder at %d", str, i)
+		}
+		n, err := strconv.Atoi(str[i+1 : i+x])
+		if err != nil || n < 0 {
+			return nil, fmt.Errorf("%s: invalid placeholder %q", str, str[i:i+x+1])
+		}
+		t.parts = append(t.parts, buf.String())
+		t.slots = append(t.slots, n)
+		if n >= t.arity {
+			t.arity = n + 1
+		}
+		buf.Reset()
+		i += x
+	}
+	t.parts = append(t.parts, buf.String())
+
+	values := make([]string, t.arity)
+	for i := range values {
+		values[i] = "x"
+	}
+	if _, err := t.Bind(values...); err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+func (t *FilterTemplate) Bind(values ...string) (Filter, error) {
+	if len(values) < t.arity {
+		return nil, fmt.Errorf("%s: expected %d values, got %d", t.source, t.arity, len(values))
+	}
+	var str strings.Builder
+	for i, p := range t.parts {
+		str.WriteString(p)
+		if i < len(t.slots) {
+			str.WriteString(EscapeFilterValue(values[t.slots[i]]))
+		}
+	}
+	return ParseFilter(str.String())
+}
+
+func (t *FilterTemplate) Arity() int {
+	return t.arity
+}
+
+func (t *FilterTemplate) String() string {
+	return t.source
+}
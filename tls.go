@@ -0,0 +1,171 @@
+package ldap
+
+import (
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"strings"
+)
+
+var (
+	ErrPinMismatch = errors.New("no certificate matches pinned public keys")
+	ErrTLSRequired = errors.New("tls required but not available")
+)
+
+type TLSPolicy uint8
+
+const (
+	Plaintext TLSPolicy = iota
+	OpportunisticTLS
+	RequireTLS
+)
+
+func (p TLSPolicy) String() string {
+	switch p {
+	case Plaintext:
+		return "plaintext"
+	case OpportunisticTLS:
+		return "opportunistic"
+	case RequireTLS:
+		return "require"
+	default:
+		return "unknown"
+	}
+}
+
+func (p TLSPolicy) MarshalText() ([]byte, error) {
+	return []byte(p.String()), nil
+}
+
+func (p *TLSPolicy) UnmarshalText(b []byte) error {
+	switch str := strings.ToLower(string(b)); str {
+	case "", "plaintext", "none":
+		*p = Plaintext
+	case "opportunistic", "try":
+		*p = OpportunisticTLS
+	case "require", "required":
+		*p = RequireTLS
+	default:
+		return fmt.Errorf("%s: unknown tls policy", str)
+	}
+	return nil
+}
+
+func BindPolicy(addr, user, passwd string, policy TLSPolicy, cfg *tls.Config) (*Client, error) {
+	c, err := Open(addr)
+	if err != nil {
+		return nil, err
+	}
+	c.SetTLSPolicy(policy, cfg)
+	if _, err := c.Bind(user, passwd); err != nil {
+		c.conn.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+func (c *Client) SetTLSPolicy(policy TLSPolicy, cfg *tls.Config) {
+	c.policy = policy
+	c.tlsConfig = cfg
+}
+
+func (c *Client) secure() error {
+	if _, ok := c.conn.(*tls.Conn); ok {
+		return nil
+	}
+	switch c.policy {
+	case RequireTLS:
+		if err := c.StartTLS(c.tlsConfig); err != nil {
+			return fmt.Errorf("%w: %s", ErrTLSRequired, err)
+		}
+	case OpportunisticTLS:
+		dse, err := c.RootDSE()
+		if err != nil || !dse.SupportsExtension(oidStartTLS) {
+			return nil
+		}
+		return c.StartTLS(c.tlsConfig)
+	}
+	return nil
+}
+
+type TLSOption func(*tls.Config) error
+
+func NewTLSConfig(options ...TLSOption) (*tls.Config, error) {
+	cfg := tls.Config{
+		MinVersion: tls.VersionTLS12,
+	}
+	for _, opt := range options {
+		if err := opt(&cfg); err != nil {
+			return nil, err
+		}
+	}
+	return &cfg, nil
+}
+
+func WithServerName(name string) TLSOption {
+	return func(cfg *tls.Config) error {
+		cfg.ServerName = name
+		return nil
+	}
+}
+
+func WithRootCAs(pool *x509.CertPool) TLSOption {
+	return func(cfg *tls.Config) error {
+		cfg.RootCAs = pool
+		return nil
+	}
+}
+
+func WithRootCAFile(file string) TLSOption {
+	return func(cfg *tls.Config) error {
+		pem, err := ioutil.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		if cfg.RootCAs == nil {
+			cfg.RootCAs = x509.NewCertPool()
+		}
+		if !cfg.RootCAs.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("%s: no certificate found", file)
+		}
+		return nil
+	}
+}
+
+func WithInsecureSkipVerify() TLSOption {
+	return func(cfg *tls.Config) error {
+		cfg.InsecureSkipVerify = true
+		return nil
+	}
+}
+
+func WithPinnedKeys(pins ...string) TLSOption {
+	return func(cfg *tls.Config) error {
+		set := make(map[string]struct{})
+		for _, p := range pins {
+			raw, err := base64.StdEncoding.DecodeString(p)
+			if err != nil || len(raw) != sha256.Size {
+				return fmt.Errorf("%s: invalid pin (expected base64 sha256 digest)", p)
+			}
+			set[p] = struct{}{}
+		}
+		cfg.VerifyConnection = func(cs tls.ConnectionState) error {
+			for _, c := range cs.PeerCertificates {
+				if _, ok := set[PinPublicKey(c)]; ok {
+					return nil
+				}
+			}
+			return ErrPinMismatch
+		}
+		return nil
+	}
+}
+
+func PinPublicKey(cert *x509.Certificate) string {
+	sum := sha256.Sum256(cert.RawSubjectPublicKeyInfo)
+	return base64.StdEncoding.EncodeToString(sum[:])
+}
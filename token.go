@@ -0,0 +1,139 @@
+package ldap
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var ErrInvalidToken = errors.New("invalid token")
+
+type TokenAuthenticator interface {
+	Authenticate(token string) (string, error)
+}
+
+type StaticTokens map[string]string
+
+func (s StaticTokens) Authenticate(token string) (string, error) {
+	for t, id := range s {
+		if hmac.Equal([]byte(t), []byte(token)) {
+			return authzID(id), nil
+		}
+	}
+	return "", ErrInvalidToken
+}
+
+type JWTValidator struct {
+	Secret   []byte
+	Issuer   string
+	Audience string
+	Claim    string
+}
+
+func (j JWTValidator) Authenticate(token string) (string, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return "", fmt.Errorf("%w: malformed jwt", ErrInvalidToken)
+	}
+	var header struct {
+		Alg string `json:"alg"`
+	}
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return "", err
+	}
+	if header.Alg != "HS256" {
+		return "", fmt.Errorf("%w: unsupported algorithm %s", ErrInvalidToken, header.Alg)
+	}
+	mac := hmac.New(sha256.New, j.Secret)
+	mac.Write([]byte(parts[0] + "." + parts[1]))
+	sig, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil || !hmac.Equal(sig, mac.Sum(nil)) {
+		return "", fmt.Errorf("%w: bad signature", ErrInvalidToken)
+	}
+	claims := make(map[string]interface{})
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return "", err
+	}
+	now := float64(time.Now().Unix())
+	if exp, ok := claims["exp"].(float64); ok && now >= exp {
+		return "", fmt.Errorf("%w: token expired", ErrInvalidToken)
+	}
+	if nbf, ok := claims["nbf"].(float64); ok && now < nbf {
+		return "", fmt.Errorf("%w: token not yet valid", ErrInvalidToken)
+	}
+	if j.Issuer != "" && claims["iss"] != j.Issuer {
+		return "", fmt.Errorf("%w: unexpected issuer", ErrInvalidToken)
+	}
+	if j.Audience != "" && !hasAudience(claims["aud"], j.Audience) {
+		return "", fmt.Errorf("%w: unexpected audience", ErrInvalidToken)
+	}
+	claim := j.Claim
+	if claim == "" {
+		claim = "sub"
+	}
+	id, ok := claims[claim].(string)
+	if !ok || id == "" {
+		return "", fmt.Errorf("%w: missing %s claim", ErrInvalidToken, claim)
+	}
+	return authzID(id), nil
+}
+
+func BearerToken(header string) (string, bool) {
+	const prefix = "bearer "
+	if len(header) <= len(prefix) || !strings.EqualFold(header[:len(prefix)], prefix) {
+		return "", false
+	}
+	return strings.TrimSpace(header[len(prefix):]), true
+}
+
+func ProxyHook(authzid string) BeforeHook {
+	return func(info *OperationInfo) error {
+		switch info.Type {
+		case OpBind, OpUnbind, OpAbandon:
+			return nil
+		}
+		info.Controls = append(info.Controls, ProxyAuthorization(authzid))
+		return nil
+	}
+}
+
+func authzID(id string) string {
+	switch {
+	case strings.HasPrefix(id, "dn:"), strings.HasPrefix(id, "u:"):
+		return id
+	case strings.ContainsRune(id, equal):
+		return "dn:" + id
+	default:
+		return "u:" + id
+	}
+}
+
+func decodeSegment(str string, v interface{}) error {
+	b, err := base64.RawURLEncoding.DecodeString(str)
+	if err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidToken, err)
+	}
+	if err := json.Unmarshal(b, v); err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidToken, err)
+	}
+	return nil
+}
+
+func hasAudience(aud interface{}, want string) bool {
+	switch aud := aud.(type) {
+	case string:
+		return aud == want
+	case []interface{}:
+		for _, a := range aud {
+			if a == want {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,215 @@
+package ldap
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+type LDIFError struct {
+	Line int
+	DN   string
+	Err  error
+}
+
+func (e LDIFError) Error() string {
+	if e.DN == "" {
+		return fmt.Sprintf("line %d: %s", e.Line, e.Err)
+	}
+	return fmt.Sprintf("line %d: %s: %s", e.Line, e.DN, e.Err)
+}
+
+func (e LDIFError) Unwrap() error {
+	return e.Err
+}
+
+type LDIFErrors []LDIFError
+
+func (es LDIFErrors) Error() string {
+	list := make([]string, len(es))
+	for i, e := range es {
+		list[i] = e.Error()
+	}
+	return strings.Join(list, "\n")
+}
+
+type ldifValidator struct {
+	schema *Schema
+	errs   LDIFErrors
+	dn     string
+}
+
+func ValidateLDIF(r io.Reader, schema *Schema) error {
+	v := ldifValidator{
+		schema: schema,
+	}
+	if err := v.validate(r); err != nil {
+		return err
+	}
+	if len(v.errs) == 0 {
+		return nil
+	}
+	return v.errs
+}
+
+func (v *ldifValidator) validate(r io.Reader) error {
+	var (
+		scan  = bufio.NewScanner(r)
+		block bytes.Buffer
+		start int
+		num   int
+		data  bool
+		first = true
+	)
+	scan.Buffer(make([]byte, 0, 4096), 1<<24)
+
+	flush := func() {
+		if data {
+			v.checkBlock(start, block.Bytes(), first)
+			first = false
+		}
+		block.Reset()
+		start, data = 0, false
+	}
+	for scan.Scan() {
+		num++
+		line := trimEOL(scan.Text())
+		if line == "" {
+			flush()
+			continue
+		}
+		if start == 0 {
+			start = num
+		}
+		if line[0] != sharp && line[0] != space {
+			data = true
+		}
+		block.WriteString(line)
+		block.WriteByte(newline)
+	}
+	if err := scan.Err(); err != nil {
+		return err
+	}
+	flush()
+	return nil
+}
+
+func (v *ldifValidator) checkBlock(start int, buf []byte, first bool) {
+	rs := NewReader(bytes.NewReader(buf), withoutFetch())
+	rs.version = !first
+	for {
+		rec, err := rs.Read()
+		if errors.Is(err, io.EOF) {
+			return
+		}
+		if err != nil {
+			v.dn = rec.Name
+			if errors.Is(err, io.ErrUnexpectedEOF) {
+				err = fmt.Errorf("incomplete record")
+			}
+			num := rs.rs.line() - 1
+			if n := bytes.Count(buf, []byte{newline}); num > n {
+				num = n
+			}
+			if num < 1 {
+				num = 1
+			}
+			v.report(start+num-1, err)
+			return
+		}
+		v.checkRecord(start+rec.Line-1, rec)
+	}
+}
+
+func (v *ldifValidator) checkRecord(num int, rec ChangeRecord) {
+	v.dn = rec.Name
+	if rec.Name != "" {
+		if _, err := Explode(rec.Name); err != nil {
+			v.report(num, fmt.Errorf("%s: invalid dn (%w)", rec.Name, err))
+		}
+	}
+	switch rec.Type {
+	case ModAdd:
+		v.checkAdd(num, rec.Attrs)
+	case ModReplace:
+		v.checkModify(num, rec.Attrs)
+	case ModRDN:
+		v.checkModDN(num, rec.Change)
+	}
+}
+
+func (v *ldifValidator) checkAdd(num int, list []PartialAttribute) {
+	if len(list) == 0 {
+		v.report(num, fmt.Errorf("entry has no attributes"))
+		return
+	}
+	attrs := make([]Attribute, 0, len(list))
+	for _, a := range list {
+		if !isDescriptor(a.Name) {
+			v.report(num, fmt.Errorf("%q: invalid attribute description", a.Name))
+		}
+		attrs = append(attrs, a.Attribute)
+	}
+	if v.schema == nil {
+		return
+	}
+	if err := v.schema.ValidateAdd(v.dn, attrs, nil); err != nil {
+		v.report(num, err)
+	}
+}
+
+func (v *ldifValidator) checkModify(num int, list []PartialAttribute) {
+	if len(list) == 0 {
+		v.report(num, fmt.Errorf("modify record without modifications"))
+		return
+	}
+	for _, a := range list {
+		if !isDescriptor(a.Name) {
+			v.report(num, fmt.Errorf("%q: invalid attribute description", a.Name))
+		}
+		switch {
+		case a.Mod == ModAdd && len(a.Values) == 0:
+			v.report(num, fmt.Errorf("%s: no values to add", a.Name))
+		case a.Mod == ModIncrement && len(a.Values) != 1:
+			v.report(num, fmt.Errorf("%s: increment expects exactly one value", a.Name))
+		}
+	}
+}
+
+func (v *ldifValidator) checkModDN(num int, cg Change) {
+	if _, err := ParseRDN(cg.NewRDN); err != nil {
+		v.report(num, fmt.Errorf("%s: invalid rdn (%w)", cg.NewRDN, err))
+	}
+	if cg.NewSuperior == "" {
+		return
+	}
+	if _, err := Explode(cg.NewSuperior); err != nil {
+		v.report(num, fmt.Errorf("%s: invalid dn (%w)", cg.NewSuperior, err))
+	}
+}
+
+func (v *ldifValidator) report(num int, err error) {
+	v.errs = append(v.errs, LDIFError{
+		Line: num,
+		DN:   v.dn,
+		Err:  err,
+	})
+}
+
+func isDescriptor(str string) bool {
+	if str == "" {
+		return false
+	}
+	for i, r := range str {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+		case i > 0 && (r == minus || r == semicolon || r == dot):
+		default:
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,89 @@
+package ldap
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestValidateLDIF(t *testing.T) {
+	const ldif = `version: 1
+
+# valid entry
+dn: cn=john,ou=people,dc=example,dc=com
+objectClass: person
+cn: john
+sn: doe
+photo:< file:///tmp/john.jpg
+
+dn: cn=jane,ou=people,dc=example,dc=com
+objectClass: person
+cn jane
+
+dn: cn=bob,,dc=example,dc=com
+cn: bob
+
+dn: cn=alice,ou=people,dc=example,dc=com
+changetype: modify
+add: mail
+-
+increment: uidNumber
+-
+
+dn: cn=carol,ou=people,dc=example,dc=com
+changetype: modrdn
+newrdn: carol
+deleteoldrdn: 1
+
+dn: cn=dave,ou=people,dc=example,dc=com
+changetype: delete
+
+dn: cn=eve,ou=people,dc=example,dc=com
+photo:< ftp://example.com/eve.jpg
+`
+	want := []struct {
+		Line int
+		Err  string
+	}{
+		{Line: 12, Err: "cn jane: colon not found"},
+		{Line: 14, Err: "invalid dn"},
+		{Line: 17, Err: "mail: no values to add"},
+		{Line: 17, Err: "uidNumber: increment expects exactly one value"},
+		{Line: 24, Err: "carol: invalid rdn"},
+		{Line: 33, Err: "ftp: unsupported scheme"},
+	}
+	err := ValidateLDIF(strings.NewReader(ldif), nil)
+	var errs LDIFErrors
+	if !errors.As(err, &errs) {
+		t.Fatalf("expected LDIFErrors, got %v", err)
+	}
+	if len(errs) != len(want) {
+		t.Fatalf("errors mismatch: want %d, got %d\n%s", len(want), len(errs), errs)
+	}
+	for i, w := range want {
+		e := errs[i]
+		if e.Line != w.Line || !strings.Contains(e.Err.Error(), w.Err) {
+			t.Errorf("%d: error mismatch: want line %d (%s), got %s", i, w.Line, w.Err, e)
+		}
+	}
+}
+
+func TestValidateLDIFValid(t *testing.T) {
+	const ldif = `dn: cn=john,ou=people,dc=example,dc=com
+changetype: modify
+replace: mail
+mail: john@example.com
+-
+delete: description
+-
+
+dn: cn=john,ou=people,dc=example,dc=com
+changetype: moddn
+newrdn: cn=jack
+deleteoldrdn: 0
+newsuperior: ou=staff,dc=example,dc=com
+`
+	if err := ValidateLDIF(strings.NewReader(ldif), nil); err != nil {
+		t.Errorf("unexpected error: %s", err)
+	}
+}